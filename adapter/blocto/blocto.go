@@ -0,0 +1,101 @@
+// Package blocto converts the account types of github.com/blocto/solana-go-sdk to the inputs of the activation calculation.
+package blocto
+
+import (
+	"encoding/json"
+
+	sdkClient "github.com/blocto/solana-go-sdk/client"
+	sdkRpc "github.com/blocto/solana-go-sdk/rpc"
+	"github.com/skport/solana-rpc-client-extensions-go/types"
+
+	"golang.org/x/xerrors"
+)
+
+// StakeAccount converts the value of getAccountInfo, with jsonParsed or base64 encoding.
+// The zero value, which the SDK returns for null, is ErrAccountNotFound.
+func StakeAccount(info sdkRpc.AccountInfo) (*types.StakeAccount, error) {
+	if isNull(info) {
+		return nil, types.ErrAccountNotFound
+	}
+	if data, encoding, ok := encodedData(info.Data); ok {
+		stakeAccount := &types.StakeAccount{
+			Executable: info.Executable,
+			Lamports:   info.Lamports,
+			Owner:      info.Owner,
+			RentEpoch:  info.RentEpoch,
+		}
+		if err := types.DecodeEncodedData(stakeAccount, data, encoding); err != nil {
+			return nil, err
+		}
+		return stakeAccount, nil
+	}
+
+	// jsonParsed data is a map, which can only be converted through JSON.
+	b, err := json.Marshal(info)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to marshal: %w", err)
+	}
+	return types.DecodeStakeAccount(b)
+}
+
+// StakeHistoryAccount converts the value of getAccountInfo for the StakeHistory sysvar, with jsonParsed or base64 encoding.
+func StakeHistoryAccount(info sdkRpc.AccountInfo) (*types.StakeHistoryAccount, error) {
+	if isNull(info) {
+		return nil, types.ErrAccountNotFound
+	}
+	b, err := json.Marshal(info)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to marshal: %w", err)
+	}
+	return types.DecodeStakeHistoryAccount(b)
+}
+
+// StakeAccountFromClient converts the AccountInfo of the SDK client, whose data is binary.
+func StakeAccountFromClient(info sdkClient.AccountInfo) (*types.StakeAccount, error) {
+	if info.Data == nil && info.Lamports == 0 {
+		return nil, types.ErrAccountNotFound
+	}
+	stakeAccount := &types.StakeAccount{
+		Executable: info.Executable,
+		Lamports:   info.Lamports,
+		Owner:      info.Owner.ToBase58(),
+		RentEpoch:  info.RentEpoch,
+	}
+	if err := stakeAccount.DecodeData(info.Data); err != nil {
+		return nil, xerrors.Errorf("%w: %v", types.ErrNotStakeAccount, err)
+	}
+	return stakeAccount, nil
+}
+
+// StakeHistoryAccountFromClient converts the AccountInfo of the SDK client for the StakeHistory sysvar.
+func StakeHistoryAccountFromClient(info sdkClient.AccountInfo) (*types.StakeHistoryAccount, error) {
+	if info.Data == nil && info.Lamports == 0 {
+		return nil, types.ErrAccountNotFound
+	}
+	stakeHistoryAccount := &types.StakeHistoryAccount{
+		Executable: info.Executable,
+		Lamports:   info.Lamports,
+		Owner:      info.Owner.ToBase58(),
+		RentEpoch:  info.RentEpoch,
+		Space:      uint64(len(info.Data)),
+	}
+	if err := stakeHistoryAccount.DecodeData(info.Data); err != nil {
+		return nil, xerrors.Errorf("%w: %v", types.ErrNotStakeHistoryAccount, err)
+	}
+	return stakeHistoryAccount, nil
+}
+
+func isNull(info sdkRpc.AccountInfo) bool {
+	return info.Data == nil && info.Owner == ""
+}
+
+// encodedData returns the data of base64 encoding, which the SDK decodes to []any{data, encoding}.
+func encodedData(data any) (string, string, bool) {
+	d, ok := data.([]any)
+	if !ok || len(d) != 2 {
+		return "", "", false
+	}
+	s, ok1 := d[0].(string)
+	encoding, ok2 := d[1].(string)
+	return s, encoding, ok1 && ok2
+}
@@ -0,0 +1,190 @@
+package blocto
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+
+	sdkClient "github.com/blocto/solana-go-sdk/client"
+	"github.com/blocto/solana-go-sdk/common"
+	sdkRpc "github.com/blocto/solana-go-sdk/rpc"
+	"github.com/skport/solana-rpc-client-extensions-go/types"
+)
+
+const (
+	testStakeProgramAddress = "Stake11111111111111111111111111111111111111"
+	testSysvarAddress       = "Sysvar1111111111111111111111111111111111111"
+)
+
+// newTestStakeData returns the binary data of a delegated stake account whose authorities are 32 bytes of 1 and the voter 32 bytes of 2.
+func newTestStakeData() []byte {
+	data := make([]byte, types.StakeStateV2Size)
+	binary.LittleEndian.PutUint32(data[0:], 2)
+	binary.LittleEndian.PutUint64(data[4:], 2282880)
+	copy(data[12:], bytes.Repeat([]byte{1}, 64))
+	copy(data[92:], bytes.Repeat([]byte{1}, 32))
+	copy(data[124:], bytes.Repeat([]byte{2}, 32))
+	binary.LittleEndian.PutUint64(data[156:], 1000000000)
+	binary.LittleEndian.PutUint64(data[164:], 816)
+	binary.LittleEndian.PutUint64(data[172:], math.MaxUint64)
+	binary.LittleEndian.PutUint64(data[180:], math.Float64bits(0.25))
+	binary.LittleEndian.PutUint64(data[188:], 612480517)
+	return data
+}
+
+const testStakeAccountJSON = `{
+	"data": {
+		"parsed": {
+			"info": {
+				"meta": {
+					"authorized": {"staker": "4vJ9JU1bJJE96FWSJKvHsmmFADCg4gpZQff4P3bkLKi", "withdrawer": "4vJ9JU1bJJE96FWSJKvHsmmFADCg4gpZQff4P3bkLKi"},
+					"lockup": {"custodian": "4vJ9JU1bJJE96FWSJKvHsmmFADCg4gpZQff4P3bkLKi", "epoch": 0, "unixTimestamp": 0},
+					"rentExemptReserve": "2282880"
+				},
+				"stake": {
+					"creditsObserved": 612480517,
+					"delegation": {
+						"activationEpoch": "816",
+						"deactivationEpoch": "18446744073709551615",
+						"stake": "1000000000",
+						"voter": "8qbHbw2BbbTHBW1sbeqakYXVKRQM8Ne7pLK7m6CVfeR",
+						"warmupCooldownRate": 0.25
+					}
+				}
+			},
+			"type": "delegated"
+		},
+		"program": "stake",
+		"space": 200
+	},
+	"executable": false,
+	"lamports": 1002282880,
+	"owner": "Stake11111111111111111111111111111111111111",
+	"rentEpoch": 18446744073709551615
+}`
+
+func TestStakeAccount(t *testing.T) {
+	var jsonParsed sdkRpc.AccountInfo
+	if err := json.Unmarshal([]byte(testStakeAccountJSON), &jsonParsed); err != nil {
+		t.Fatal(err)
+	}
+	want, err := types.DecodeStakeAccount([]byte(testStakeAccountJSON))
+	if err != nil {
+		t.Fatalf("DecodeStakeAccount error = %v", err)
+	}
+
+	base64Info := jsonParsed
+	base64Info.Data = []any{base64.StdEncoding.EncodeToString(newTestStakeData()), "base64"}
+	clientInfo := sdkClient.AccountInfo{
+		Lamports:  jsonParsed.Lamports,
+		Owner:     common.PublicKeyFromString(testStakeProgramAddress),
+		RentEpoch: jsonParsed.RentEpoch,
+		Data:      newTestStakeData(),
+	}
+
+	tests := []struct {
+		name    string
+		convert func() (*types.StakeAccount, error)
+		wantErr error
+	}{
+		{name: "jsonParsed", convert: func() (*types.StakeAccount, error) { return StakeAccount(jsonParsed) }},
+		{name: "base64", convert: func() (*types.StakeAccount, error) { return StakeAccount(base64Info) }},
+		{name: "client", convert: func() (*types.StakeAccount, error) { return StakeAccountFromClient(clientInfo) }},
+		{name: "null", convert: func() (*types.StakeAccount, error) { return StakeAccount(sdkRpc.AccountInfo{}) }, wantErr: types.ErrAccountNotFound},
+		{name: "null client", convert: func() (*types.StakeAccount, error) { return StakeAccountFromClient(sdkClient.AccountInfo{}) }, wantErr: types.ErrAccountNotFound},
+		{
+			name: "not a stake account",
+			convert: func() (*types.StakeAccount, error) {
+				return StakeAccountFromClient(sdkClient.AccountInfo{Lamports: 1, Data: []byte{9, 0, 0, 0}})
+			},
+			wantErr: types.ErrNotStakeAccount,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.convert()
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error = %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestStakeHistoryAccount(t *testing.T) {
+	const jsonParsedValue = `{
+		"data": {
+			"parsed": {
+				"info": [
+					{"epoch": 815, "stakeHistory": {"activating": 3, "deactivating": 4, "effective": 2}},
+					{"epoch": 814, "stakeHistory": {"activating": 6, "deactivating": 7, "effective": 5}}
+				],
+				"type": "stakeHistory"
+			},
+			"program": "sysvar",
+			"space": 16392
+		},
+		"executable": false,
+		"lamports": 114979200,
+		"owner": "Sysvar1111111111111111111111111111111111111",
+		"rentEpoch": 0
+	}`
+	var jsonParsed sdkRpc.AccountInfo
+	if err := json.Unmarshal([]byte(jsonParsedValue), &jsonParsed); err != nil {
+		t.Fatal(err)
+	}
+	want, err := StakeHistoryAccount(jsonParsed)
+	if err != nil {
+		t.Fatalf("StakeHistoryAccount error = %v", err)
+	}
+
+	// Vec<(Epoch, StakeHistoryEntry)> padded to the size of the sysvar.
+	data := make([]byte, 16392)
+	binary.LittleEndian.PutUint64(data, 2)
+	for i, entry := range [][4]uint64{{815, 2, 3, 4}, {814, 5, 6, 7}} {
+		for j, v := range entry {
+			binary.LittleEndian.PutUint64(data[8+i*32+j*8:], v)
+		}
+	}
+
+	base64Info := jsonParsed
+	base64Info.Data = []any{base64.StdEncoding.EncodeToString(data), "base64"}
+	got, err := StakeHistoryAccount(base64Info)
+	if err != nil {
+		t.Fatalf("StakeHistoryAccount(base64) error = %v", err)
+	}
+	if !reflect.DeepEqual(got.Data, want.Data) {
+		t.Errorf("StakeHistoryAccount(base64) = %+v, want %+v", got.Data, want.Data)
+	}
+
+	got, err = StakeHistoryAccountFromClient(sdkClient.AccountInfo{
+		Lamports: jsonParsed.Lamports,
+		Owner:    common.PublicKeyFromString(testSysvarAddress),
+		Data:     data,
+	})
+	if err != nil {
+		t.Fatalf("StakeHistoryAccountFromClient error = %v", err)
+	}
+	if !reflect.DeepEqual(got.Data, want.Data) || got.Owner != testSysvarAddress {
+		t.Errorf("StakeHistoryAccountFromClient = %+v, want %+v", got, want)
+	}
+
+	// The length exceeds the data.
+	binary.LittleEndian.PutUint64(data, 1000)
+	if _, err := StakeHistoryAccountFromClient(sdkClient.AccountInfo{Lamports: 1, Data: data}); !errors.Is(err, types.ErrNotStakeHistoryAccount) {
+		t.Errorf("StakeHistoryAccountFromClient error = %v, want %v", err, types.ErrNotStakeHistoryAccount)
+	}
+}
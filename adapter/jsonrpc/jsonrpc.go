@@ -0,0 +1,101 @@
+// Package jsonrpc decodes raw JSON-RPC response bodies to the inputs of the activation calculation, together with their context slot,
+// without an SDK.
+package jsonrpc
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/skport/solana-rpc-client-extensions-go/types"
+
+	"golang.org/x/xerrors"
+)
+
+// Error is the error of a JSON-RPC response.
+type Error struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("JSON-RPC error %d: %s", e.Code, e.Message)
+}
+
+type rpcContext struct {
+	Slot uint64 `json:"slot"`
+}
+
+type response[T any] struct {
+	Result struct {
+		Context rpcContext `json:"context"`
+		Value   T          `json:"value"`
+	} `json:"result"`
+	Error *Error `json:"error"`
+}
+
+func decodeResponse[T any](body []byte) (*response[T], error) {
+	var res response[T]
+	if err := json.Unmarshal(body, &res); err != nil {
+		return nil, xerrors.Errorf("failed to unmarshal response: %w", err)
+	}
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	return &res, nil
+}
+
+// DecodeStakeAccountResponse decodes the body of getAccountInfo for a stake account, with jsonParsed or base64 encoding,
+// and returns the context slot. It returns ErrAccountNotFound for a null value.
+func DecodeStakeAccountResponse(body []byte) (*types.StakeAccount, uint64, error) {
+	res, err := decodeResponse[json.RawMessage](body)
+	if err != nil {
+		return nil, 0, err
+	}
+	stakeAccount, err := types.DecodeStakeAccount(res.Result.Value)
+	if err != nil {
+		return nil, res.Result.Context.Slot, err
+	}
+	return stakeAccount, res.Result.Context.Slot, nil
+}
+
+// DecodeStakeHistoryAccountResponse decodes the body of getAccountInfo for the StakeHistory sysvar, with jsonParsed or base64 encoding,
+// and returns the context slot.
+func DecodeStakeHistoryAccountResponse(body []byte) (*types.StakeHistoryAccount, uint64, error) {
+	res, err := decodeResponse[json.RawMessage](body)
+	if err != nil {
+		return nil, 0, err
+	}
+	if isNull(res.Result.Value) {
+		return nil, res.Result.Context.Slot, types.ErrAccountNotFound
+	}
+	stakeHistoryAccount, err := types.DecodeStakeHistoryAccount(res.Result.Value)
+	if err != nil {
+		return nil, res.Result.Context.Slot, err
+	}
+	return stakeHistoryAccount, res.Result.Context.Slot, nil
+}
+
+// DecodeMultipleStakeAccountsResponse decodes the body of getMultipleAccounts for stake accounts, and returns the context slot.
+// Accounts which don't exist are nil. Unlike ProgramAccountsDecoder, which continues after an account that can't be decoded,
+// such an account fails the whole response with its index, since a nil account already means that it doesn't exist.
+func DecodeMultipleStakeAccountsResponse(body []byte) ([]*types.StakeAccount, uint64, error) {
+	res, err := decodeResponse[[]json.RawMessage](body)
+	if err != nil {
+		return nil, 0, err
+	}
+	stakeAccounts := make([]*types.StakeAccount, len(res.Result.Value))
+	for i, raw := range res.Result.Value {
+		if isNull(raw) {
+			continue
+		}
+		if stakeAccounts[i], err = types.DecodeStakeAccount(raw); err != nil {
+			return nil, res.Result.Context.Slot, xerrors.Errorf("account %d: %w", i, err)
+		}
+	}
+	return stakeAccounts, res.Result.Context.Slot, nil
+}
+
+func isNull(raw json.RawMessage) bool {
+	return len(raw) == 0 || string(raw) == "null"
+}
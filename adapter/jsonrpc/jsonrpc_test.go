@@ -0,0 +1,174 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/skport/solana-rpc-client-extensions-go/types"
+)
+
+// newTestStakeData returns the binary data of a delegated stake account whose authorities are 32 bytes of 1 and the voter 32 bytes of 2.
+func newTestStakeData() []byte {
+	data := make([]byte, types.StakeStateV2Size)
+	binary.LittleEndian.PutUint32(data[0:], 2)
+	binary.LittleEndian.PutUint64(data[4:], 2282880)
+	copy(data[12:], bytes.Repeat([]byte{1}, 64))
+	copy(data[92:], bytes.Repeat([]byte{1}, 32))
+	copy(data[124:], bytes.Repeat([]byte{2}, 32))
+	binary.LittleEndian.PutUint64(data[156:], 1000000000)
+	binary.LittleEndian.PutUint64(data[164:], 816)
+	binary.LittleEndian.PutUint64(data[172:], math.MaxUint64)
+	binary.LittleEndian.PutUint64(data[180:], math.Float64bits(0.25))
+	binary.LittleEndian.PutUint64(data[188:], 612480517)
+	return data
+}
+
+const testStakeAccountJSON = `{
+	"data": {
+		"parsed": {
+			"info": {
+				"meta": {
+					"authorized": {"staker": "4vJ9JU1bJJE96FWSJKvHsmmFADCg4gpZQff4P3bkLKi", "withdrawer": "4vJ9JU1bJJE96FWSJKvHsmmFADCg4gpZQff4P3bkLKi"},
+					"lockup": {"custodian": "4vJ9JU1bJJE96FWSJKvHsmmFADCg4gpZQff4P3bkLKi", "epoch": 0, "unixTimestamp": 0},
+					"rentExemptReserve": "2282880"
+				},
+				"stake": {
+					"creditsObserved": 612480517,
+					"delegation": {
+						"activationEpoch": "816",
+						"deactivationEpoch": "18446744073709551615",
+						"stake": "1000000000",
+						"voter": "8qbHbw2BbbTHBW1sbeqakYXVKRQM8Ne7pLK7m6CVfeR",
+						"warmupCooldownRate": 0.25
+					}
+				}
+			},
+			"type": "delegated"
+		},
+		"program": "stake",
+		"space": 200
+	},
+	"executable": false,
+	"lamports": 1002282880,
+	"owner": "Stake11111111111111111111111111111111111111",
+	"rentEpoch": 18446744073709551615
+}`
+
+func testStakeAccountBase64JSON() string {
+	return fmt.Sprintf(`{"data":["%s","base64"],"executable":false,"lamports":1002282880,"owner":"Stake11111111111111111111111111111111111111","rentEpoch":18446744073709551615,"space":200}`,
+		base64.StdEncoding.EncodeToString(newTestStakeData()))
+}
+
+const testErrorBody = `{"jsonrpc":"2.0","id":1,"error":{"code":-32016,"message":"Minimum context slot has not been reached","data":{"contextSlot":99}}}`
+
+func testStakeAccount(t *testing.T) *types.StakeAccount {
+	t.Helper()
+	want, err := types.DecodeStakeAccount([]byte(testStakeAccountJSON))
+	if err != nil {
+		t.Fatalf("DecodeStakeAccount error = %v", err)
+	}
+	return want
+}
+
+func TestDecodeStakeAccountResponse(t *testing.T) {
+	want := testStakeAccount(t)
+
+	tests := []struct {
+		name     string
+		body     string
+		wantSlot uint64
+		wantErr  error
+		wantCode int
+	}{
+		{name: "jsonParsed", body: `{"jsonrpc":"2.0","id":1,"result":{"context":{"apiVersion":"2.0.15","slot":100},"value":` + testStakeAccountJSON + `}}`, wantSlot: 100},
+		{name: "base64", body: `{"jsonrpc":"2.0","id":1,"result":{"context":{"slot":101},"value":` + testStakeAccountBase64JSON() + `}}`, wantSlot: 101},
+		{name: "null", body: `{"jsonrpc":"2.0","id":1,"result":{"context":{"slot":102},"value":null}}`, wantSlot: 102, wantErr: types.ErrAccountNotFound},
+		{name: "error", body: testErrorBody, wantCode: -32016},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, slot, err := DecodeStakeAccountResponse([]byte(tt.body))
+			if slot != tt.wantSlot {
+				t.Errorf("slot = %d, want %d", slot, tt.wantSlot)
+			}
+			if tt.wantCode != 0 {
+				var rpcErr *Error
+				if !errors.As(err, &rpcErr) || rpcErr.Code != tt.wantCode {
+					t.Errorf("error = %v, want code %d", err, tt.wantCode)
+				}
+				return
+			}
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error = %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestDecodeStakeHistoryAccountResponse(t *testing.T) {
+	body := `{"jsonrpc":"2.0","id":1,"result":{"context":{"slot":100},"value":{
+		"data": {
+			"parsed": {
+				"info": [{"epoch": 815, "stakeHistory": {"activating": 3, "deactivating": 4, "effective": 2}}],
+				"type": "stakeHistory"
+			},
+			"program": "sysvar",
+			"space": 16392
+		},
+		"executable": false,
+		"lamports": 114979200,
+		"owner": "Sysvar1111111111111111111111111111111111111",
+		"rentEpoch": 0
+	}}}`
+	got, slot, err := DecodeStakeHistoryAccountResponse([]byte(body))
+	if err != nil {
+		t.Fatalf("DecodeStakeHistoryAccountResponse error = %v", err)
+	}
+	if slot != 100 || len(got.Data.Parsed.Info) != 1 || got.Data.Parsed.Info[0].Epoch != 815 {
+		t.Errorf("DecodeStakeHistoryAccountResponse = %+v, %d", got, slot)
+	}
+
+	_, _, err = DecodeStakeHistoryAccountResponse([]byte(`{"jsonrpc":"2.0","id":1,"result":{"context":{"slot":100},"value":null}}`))
+	if !errors.Is(err, types.ErrAccountNotFound) {
+		t.Errorf("DecodeStakeHistoryAccountResponse error = %v, want %v", err, types.ErrAccountNotFound)
+	}
+}
+
+func TestDecodeMultipleStakeAccountsResponse(t *testing.T) {
+	want := testStakeAccount(t)
+
+	body := `{"jsonrpc":"2.0","id":1,"result":{"context":{"slot":100},"value":[` + testStakeAccountJSON + `,null,` + testStakeAccountBase64JSON() + `]}}`
+	got, slot, err := DecodeMultipleStakeAccountsResponse([]byte(body))
+	if err != nil {
+		t.Fatalf("DecodeMultipleStakeAccountsResponse error = %v", err)
+	}
+	if !reflect.DeepEqual(got, []*types.StakeAccount{want, nil, want}) || slot != 100 {
+		t.Errorf("DecodeMultipleStakeAccountsResponse = %+v, %d", got, slot)
+	}
+
+	// An account which can't be decoded fails the whole response, unlike with ProgramAccountsDecoder.
+	body = `{"jsonrpc":"2.0","id":1,"result":{"context":{"slot":100},"value":[` + testStakeAccountJSON + `,{"data":["CQAAAA==","base64"],"lamports":1,"owner":"Stake11111111111111111111111111111111111111"}]}}`
+	got, _, err = DecodeMultipleStakeAccountsResponse([]byte(body))
+	if !errors.Is(err, types.ErrNotStakeAccount) || !strings.HasPrefix(err.Error(), "account 1: ") {
+		t.Errorf("DecodeMultipleStakeAccountsResponse error = %v, want %v of account 1", err, types.ErrNotStakeAccount)
+	}
+	if got != nil {
+		t.Errorf("DecodeMultipleStakeAccountsResponse = %+v, want nil", got)
+	}
+}
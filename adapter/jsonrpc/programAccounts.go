@@ -0,0 +1,244 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/skport/solana-rpc-client-extensions-go/types"
+
+	"golang.org/x/xerrors"
+)
+
+// ProgramAccount is an account of the result of getProgramAccounts.
+type ProgramAccount struct {
+	Pubkey  string
+	Account *types.StakeAccount
+}
+
+// ProgramAccountsDecoder decodes the body of getProgramAccounts for the stake program one account at a time,
+// so that a large result, e.g. all the stake accounts of a cluster, is never held in memory.
+// Both the plain result and the result of `withContext: true` are supported.
+type ProgramAccountsDecoder struct {
+	dec     *json.Decoder
+	started bool
+	// withContext is whether the accounts are the value of a result with context.
+	withContext bool
+	slot        uint64
+	err         error
+}
+
+func NewProgramAccountsDecoder(r io.Reader) *ProgramAccountsDecoder {
+	return &ProgramAccountsDecoder{dec: json.NewDecoder(r)}
+}
+
+// Slot returns the context slot of a result with context, or 0 before it has been decoded.
+// Validators send the context before the accounts, so it's available with the first account.
+func (d *ProgramAccountsDecoder) Slot() uint64 {
+	return d.slot
+}
+
+// Next returns the next account, or io.EOF after the last one.
+// An account which can't be decoded is returned as an error with its pubkey, and the decoder continues with the next one.
+// Other errors, e.g. a JSON-RPC error or a malformed body, are returned by all later calls.
+func (d *ProgramAccountsDecoder) Next() (*ProgramAccount, error) {
+	if d.err != nil {
+		return nil, d.err
+	}
+	if !d.started {
+		d.started = true
+		if err := d.start(); err != nil {
+			d.err = err
+			return nil, err
+		}
+	}
+
+	if !d.dec.More() {
+		d.err = d.finish()
+		return nil, d.err
+	}
+
+	var raw struct {
+		Pubkey  string          `json:"pubkey"`
+		Account json.RawMessage `json:"account"`
+	}
+	if err := d.dec.Decode(&raw); err != nil {
+		d.err = xerrors.Errorf("failed to decode program account: %w", err)
+		return nil, d.err
+	}
+	account, err := types.DecodeStakeAccount(raw.Account)
+	if err != nil {
+		return nil, xerrors.Errorf("account: %s: %w", raw.Pubkey, err)
+	}
+	return &ProgramAccount{Pubkey: raw.Pubkey, Account: account}, nil
+}
+
+// start reads the response up to the first account.
+func (d *ProgramAccountsDecoder) start() error {
+	if err := d.expectDelim('{'); err != nil {
+		return err
+	}
+	for d.dec.More() {
+		key, err := d.key()
+		if err != nil {
+			return err
+		}
+		switch key {
+		case "result":
+			tok, err := d.dec.Token()
+			if err != nil {
+				return xerrors.Errorf("failed to decode result: %w", err)
+			}
+			switch tok {
+			case json.Delim('['):
+				return nil
+			case json.Delim('{'):
+				found, err := d.startResult()
+				if err != nil || found {
+					return err
+				}
+			case nil:
+			default:
+				return fmt.Errorf("unexpected result: %v", tok)
+			}
+		case "error":
+			if err := d.decodeError(); err != nil {
+				return err
+			}
+		default:
+			if err := d.skip(); err != nil {
+				return err
+			}
+		}
+	}
+	return fmt.Errorf("response has no result")
+}
+
+// startResult reads a result with context up to the first account, and reports whether the accounts were found.
+func (d *ProgramAccountsDecoder) startResult() (bool, error) {
+	for d.dec.More() {
+		key, err := d.key()
+		if err != nil {
+			return false, err
+		}
+		switch key {
+		case "context":
+			if err := d.decodeContext(); err != nil {
+				return false, err
+			}
+		case "value":
+			tok, err := d.dec.Token()
+			if err != nil {
+				return false, xerrors.Errorf("failed to decode value: %w", err)
+			}
+			if tok == json.Delim('[') {
+				d.withContext = true
+				return true, nil
+			}
+			if tok != nil {
+				return false, fmt.Errorf("unexpected value: %v", tok)
+			}
+		default:
+			if err := d.skip(); err != nil {
+				return false, err
+			}
+		}
+	}
+	return false, d.expectDelim('}')
+}
+
+// finish reads the response after the last account, and returns io.EOF or the error of the response.
+func (d *ProgramAccountsDecoder) finish() error {
+	if err := d.expectDelim(']'); err != nil {
+		return err
+	}
+	if d.withContext {
+		for d.dec.More() {
+			key, err := d.key()
+			if err != nil {
+				return err
+			}
+			if key == "context" {
+				err = d.decodeContext()
+			} else {
+				err = d.skip()
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if err := d.expectDelim('}'); err != nil {
+			return err
+		}
+	}
+	for d.dec.More() {
+		key, err := d.key()
+		if err != nil {
+			return err
+		}
+		if key == "error" {
+			err = d.decodeError()
+		} else {
+			err = d.skip()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if err := d.expectDelim('}'); err != nil {
+		return err
+	}
+	return io.EOF
+}
+
+func (d *ProgramAccountsDecoder) key() (string, error) {
+	tok, err := d.dec.Token()
+	if err != nil {
+		return "", xerrors.Errorf("failed to decode key: %w", err)
+	}
+	key, ok := tok.(string)
+	if !ok {
+		return "", fmt.Errorf("unexpected key: %v", tok)
+	}
+	return key, nil
+}
+
+func (d *ProgramAccountsDecoder) expectDelim(delim json.Delim) error {
+	tok, err := d.dec.Token()
+	if err != nil {
+		return xerrors.Errorf("failed to decode response: %w", err)
+	}
+	if tok != delim {
+		return fmt.Errorf("unexpected token %v, want %v", tok, delim)
+	}
+	return nil
+}
+
+func (d *ProgramAccountsDecoder) decodeContext() error {
+	var ctx rpcContext
+	if err := d.dec.Decode(&ctx); err != nil {
+		return xerrors.Errorf("failed to decode context: %w", err)
+	}
+	d.slot = ctx.Slot
+	return nil
+}
+
+// decodeError decodes the error of the response, which is nil for `"error": null`.
+func (d *ProgramAccountsDecoder) decodeError() error {
+	var rpcErr *Error
+	if err := d.dec.Decode(&rpcErr); err != nil {
+		return xerrors.Errorf("failed to decode error: %w", err)
+	}
+	if rpcErr == nil {
+		return nil
+	}
+	return rpcErr
+}
+
+func (d *ProgramAccountsDecoder) skip() error {
+	var v json.RawMessage
+	if err := d.dec.Decode(&v); err != nil {
+		return xerrors.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package jsonrpc
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/skport/solana-rpc-client-extensions-go/types"
+)
+
+func testProgramAccountJSON(pubkey, account string) string {
+	return fmt.Sprintf(`{"pubkey":"%s","account":%s}`, pubkey, account)
+}
+
+func TestProgramAccountsDecoder(t *testing.T) {
+	want := testStakeAccount(t)
+	accounts := testProgramAccountJSON("A", testStakeAccountJSON) + "," + testProgramAccountJSON("B", testStakeAccountBase64JSON())
+	invalid := testProgramAccountJSON("C", `{"data":["CQAAAA==","base64"],"lamports":1,"owner":"Stake11111111111111111111111111111111111111"}`)
+
+	tests := []struct {
+		name        string
+		body        string
+		wantPubkeys []string
+		// wantInvalid are the pubkeys of the accounts which fail to decode.
+		wantInvalid []string
+		wantSlot    uint64
+		wantCode    int
+	}{
+		{
+			name:        "plain",
+			body:        `{"jsonrpc":"2.0","result":[` + accounts + `],"id":1}`,
+			wantPubkeys: []string{"A", "B"},
+		},
+		{
+			name:        "withContext",
+			body:        `{"jsonrpc":"2.0","result":{"context":{"apiVersion":"2.0.15","slot":100},"value":[` + accounts + `]},"id":1}`,
+			wantPubkeys: []string{"A", "B"},
+			wantSlot:    100,
+		},
+		{
+			name:        "context after value",
+			body:        `{"jsonrpc":"2.0","id":1,"result":{"value":[` + accounts + `],"context":{"slot":101}}}`,
+			wantPubkeys: []string{"A", "B"},
+			wantSlot:    101,
+		},
+		{
+			name:     "empty",
+			body:     `{"jsonrpc":"2.0","result":{"context":{"slot":102},"value":[]},"id":1}`,
+			wantSlot: 102,
+		},
+		{
+			name:        "invalid account",
+			body:        `{"jsonrpc":"2.0","result":[` + invalid + `,` + accounts + `],"id":1}`,
+			wantPubkeys: []string{"A", "B"},
+			wantInvalid: []string{"C"},
+		},
+		{
+			name:     "error",
+			body:     testErrorBody,
+			wantCode: -32016,
+		},
+		{
+			name:        "null error",
+			body:        `{"jsonrpc":"2.0","error":null,"result":[` + accounts + `],"id":1}`,
+			wantPubkeys: []string{"A", "B"},
+		},
+		{
+			name:        "null error after result",
+			body:        `{"jsonrpc":"2.0","result":{"context":{"slot":100},"value":[` + accounts + `]},"error":null,"id":1}`,
+			wantPubkeys: []string{"A", "B"},
+			wantSlot:    100,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewProgramAccountsDecoder(strings.NewReader(tt.body))
+			var pubkeys, invalid []string
+			var err error
+			for {
+				var account *ProgramAccount
+				account, err = d.Next()
+				if err == io.EOF {
+					break
+				}
+				if errors.Is(err, types.ErrNotStakeAccount) {
+					invalid = append(invalid, strings.SplitN(strings.TrimPrefix(err.Error(), "account: "), ":", 2)[0])
+					continue
+				}
+				if err != nil {
+					break
+				}
+				if !reflect.DeepEqual(account.Account, want) {
+					t.Errorf("Next = %+v, want %+v", account.Account, want)
+				}
+				pubkeys = append(pubkeys, account.Pubkey)
+			}
+
+			if tt.wantCode != 0 {
+				var rpcErr *Error
+				if !errors.As(err, &rpcErr) || rpcErr.Code != tt.wantCode {
+					t.Errorf("Next error = %v, want code %d", err, tt.wantCode)
+				}
+				return
+			}
+			if err != io.EOF {
+				t.Fatalf("Next error = %v", err)
+			}
+			if !reflect.DeepEqual(pubkeys, tt.wantPubkeys) || !reflect.DeepEqual(invalid, tt.wantInvalid) {
+				t.Errorf("pubkeys = %v, invalid = %v, want %v, %v", pubkeys, invalid, tt.wantPubkeys, tt.wantInvalid)
+			}
+			if d.Slot() != tt.wantSlot {
+				t.Errorf("Slot = %d, want %d", d.Slot(), tt.wantSlot)
+			}
+			if _, err := d.Next(); err != io.EOF {
+				t.Errorf("Next after EOF error = %v", err)
+			}
+		})
+	}
+}
+
+type errReader struct{ err error }
+
+func (r errReader) Read([]byte) (int, error) { return 0, r.err }
+
+// The accounts are returned before the rest of the body is read.
+func TestProgramAccountsDecoder_Streaming(t *testing.T) {
+	errBroken := errors.New("broken")
+	head := `{"jsonrpc":"2.0","result":{"context":{"slot":100},"value":[` + testProgramAccountJSON("A", testStakeAccountJSON) + `,`
+	d := NewProgramAccountsDecoder(io.MultiReader(strings.NewReader(head), errReader{errBroken}))
+
+	account, err := d.Next()
+	if err != nil {
+		t.Fatalf("Next error = %v", err)
+	}
+	if account.Pubkey != "A" || d.Slot() != 100 {
+		t.Errorf("Next = %+v, Slot = %d", account, d.Slot())
+	}
+	if _, err := d.Next(); !errors.Is(err, errBroken) {
+		t.Errorf("Next error = %v, want %v", err, errBroken)
+	}
+	if _, err := d.Next(); !errors.Is(err, errBroken) {
+		t.Errorf("Next after error = %v, want %v", err, errBroken)
+	}
+}
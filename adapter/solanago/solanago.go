@@ -0,0 +1,102 @@
+// Package solanago converts the account types of github.com/gagliardetto/solana-go to the inputs of the activation calculation.
+//
+// It's a separate module, so that the main module doesn't depend on solana-go.
+package solanago
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/skport/solana-rpc-client-extensions-go/types"
+
+	"golang.org/x/xerrors"
+)
+
+// StakeAccount converts an account fetched with base64 or jsonParsed encoding.
+// Only base64 encoding includes the stake flags. A nil account is ErrAccountNotFound.
+func StakeAccount(account *rpc.Account) (*types.StakeAccount, error) {
+	if account == nil || account.Data == nil {
+		return nil, types.ErrAccountNotFound
+	}
+	stakeAccount := &types.StakeAccount{
+		Executable: account.Executable,
+		Lamports:   account.Lamports,
+		Owner:      account.Owner.String(),
+		RentEpoch:  rentEpoch(account),
+	}
+
+	if raw := account.Data.GetRawJSON(); len(raw) > 0 {
+		if err := json.Unmarshal(raw, &stakeAccount.Data); err != nil {
+			// A malformed delegation of a stake account is reported as is.
+			var fieldErr *types.FieldError
+			if errors.As(err, &fieldErr) {
+				return nil, xerrors.Errorf("failed to unmarshal stake account: %w", err)
+			}
+			return nil, xerrors.Errorf("%w: failed to unmarshal stake account: %v", types.ErrNotStakeAccount, err)
+		}
+		return stakeAccount, nil
+	}
+
+	if err := stakeAccount.DecodeData(account.Data.GetBinary()); err != nil {
+		return nil, xerrors.Errorf("%w: %v", types.ErrNotStakeAccount, err)
+	}
+	return stakeAccount, nil
+}
+
+// StakeHistoryAccount converts the StakeHistory sysvar fetched with base64 or jsonParsed encoding.
+func StakeHistoryAccount(account *rpc.Account) (*types.StakeHistoryAccount, error) {
+	if account == nil || account.Data == nil {
+		return nil, types.ErrAccountNotFound
+	}
+	stakeHistoryAccount := &types.StakeHistoryAccount{
+		Executable: account.Executable,
+		Lamports:   account.Lamports,
+		Owner:      account.Owner.String(),
+		RentEpoch:  rentEpoch(account),
+	}
+
+	if raw := account.Data.GetRawJSON(); len(raw) > 0 {
+		if err := json.Unmarshal(raw, &stakeHistoryAccount.Data); err != nil {
+			return nil, xerrors.Errorf("%w: failed to unmarshal stake history: %v", types.ErrNotStakeHistoryAccount, err)
+		}
+		if stakeHistoryAccount.Data.Parsed.Info == nil {
+			return nil, xerrors.Errorf("%w: no entries", types.ErrNotStakeHistoryAccount)
+		}
+		// rpc.Account has no space, but jsonParsed data has.
+		stakeHistoryAccount.Space = uint64(stakeHistoryAccount.Data.Space)
+		return stakeHistoryAccount, nil
+	}
+
+	data := account.Data.GetBinary()
+	if err := stakeHistoryAccount.DecodeData(data); err != nil {
+		return nil, xerrors.Errorf("%w: %v", types.ErrNotStakeHistoryAccount, err)
+	}
+	stakeHistoryAccount.Space = uint64(len(data))
+	return stakeHistoryAccount, nil
+}
+
+// StakeAccountFromResult converts the result of GetAccountInfo, and returns its context slot.
+func StakeAccountFromResult(result *rpc.GetAccountInfoResult) (*types.StakeAccount, uint64, error) {
+	if result == nil {
+		return nil, 0, types.ErrAccountNotFound
+	}
+	stakeAccount, err := StakeAccount(result.Value)
+	return stakeAccount, result.Context.Slot, err
+}
+
+// StakeHistoryAccountFromResult converts the result of GetAccountInfo for the StakeHistory sysvar, and returns its context slot.
+func StakeHistoryAccountFromResult(result *rpc.GetAccountInfoResult) (*types.StakeHistoryAccount, uint64, error) {
+	if result == nil {
+		return nil, 0, types.ErrAccountNotFound
+	}
+	stakeHistoryAccount, err := StakeHistoryAccount(result.Value)
+	return stakeHistoryAccount, result.Context.Slot, err
+}
+
+func rentEpoch(account *rpc.Account) uint64 {
+	if account.RentEpoch == nil {
+		return 0
+	}
+	return account.RentEpoch.Uint64()
+}
@@ -0,0 +1,181 @@
+package solanago
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/skport/solana-rpc-client-extensions-go/types"
+)
+
+// newTestStakeData returns the binary data of a delegated stake account whose authorities are 32 bytes of 1 and the voter 32 bytes of 2.
+func newTestStakeData() []byte {
+	data := make([]byte, types.StakeStateV2Size)
+	binary.LittleEndian.PutUint32(data[0:], 2)
+	binary.LittleEndian.PutUint64(data[4:], 2282880)
+	copy(data[12:], bytes.Repeat([]byte{1}, 64))
+	copy(data[92:], bytes.Repeat([]byte{1}, 32))
+	copy(data[124:], bytes.Repeat([]byte{2}, 32))
+	binary.LittleEndian.PutUint64(data[156:], 1000000000)
+	binary.LittleEndian.PutUint64(data[164:], 816)
+	binary.LittleEndian.PutUint64(data[172:], math.MaxUint64)
+	binary.LittleEndian.PutUint64(data[180:], math.Float64bits(0.25))
+	binary.LittleEndian.PutUint64(data[188:], 612480517)
+	return data
+}
+
+const testStakeAccountJSON = `{
+	"data": {
+		"parsed": {
+			"info": {
+				"meta": {
+					"authorized": {"staker": "4vJ9JU1bJJE96FWSJKvHsmmFADCg4gpZQff4P3bkLKi", "withdrawer": "4vJ9JU1bJJE96FWSJKvHsmmFADCg4gpZQff4P3bkLKi"},
+					"lockup": {"custodian": "4vJ9JU1bJJE96FWSJKvHsmmFADCg4gpZQff4P3bkLKi", "epoch": 0, "unixTimestamp": 0},
+					"rentExemptReserve": "2282880"
+				},
+				"stake": {
+					"creditsObserved": 612480517,
+					"delegation": {
+						"activationEpoch": "816",
+						"deactivationEpoch": "18446744073709551615",
+						"stake": "1000000000",
+						"voter": "8qbHbw2BbbTHBW1sbeqakYXVKRQM8Ne7pLK7m6CVfeR",
+						"warmupCooldownRate": 0.25
+					}
+				}
+			},
+			"type": "delegated"
+		},
+		"program": "stake",
+		"space": 200
+	},
+	"executable": false,
+	"lamports": 1002282880,
+	"owner": "Stake11111111111111111111111111111111111111",
+	"rentEpoch": 18446744073709551615
+}`
+
+// withBase64Data replaces the data of the JSON account with the base64 encoding of data.
+func withBase64Data(t *testing.T, account string, data []byte) *rpc.Account {
+	t.Helper()
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(account), &fields); err != nil {
+		t.Fatal(err)
+	}
+	fields["data"] = json.RawMessage(fmt.Sprintf(`["%s","base64"]`, base64.StdEncoding.EncodeToString(data)))
+	b, err := json.Marshal(fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result rpc.Account
+	if err := json.Unmarshal(b, &result); err != nil {
+		t.Fatal(err)
+	}
+	return &result
+}
+
+func TestStakeAccount(t *testing.T) {
+	var jsonParsed rpc.Account
+	if err := json.Unmarshal([]byte(testStakeAccountJSON), &jsonParsed); err != nil {
+		t.Fatal(err)
+	}
+	want, err := types.DecodeStakeAccount([]byte(testStakeAccountJSON))
+	if err != nil {
+		t.Fatalf("DecodeStakeAccount error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		account *rpc.Account
+		wantErr error
+	}{
+		{name: "jsonParsed", account: &jsonParsed},
+		{name: "base64", account: withBase64Data(t, testStakeAccountJSON, newTestStakeData())},
+		{name: "null", account: nil, wantErr: types.ErrAccountNotFound},
+		{name: "not a stake account", account: withBase64Data(t, testStakeAccountJSON, []byte{9, 0, 0, 0}), wantErr: types.ErrNotStakeAccount},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := StakeAccount(tt.account)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error = %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestStakeHistoryAccount(t *testing.T) {
+	const jsonParsedValue = `{
+		"data": {
+			"parsed": {
+				"info": [
+					{"epoch": 815, "stakeHistory": {"activating": 3, "deactivating": 4, "effective": 2}},
+					{"epoch": 814, "stakeHistory": {"activating": 6, "deactivating": 7, "effective": 5}}
+				],
+				"type": "stakeHistory"
+			},
+			"program": "sysvar",
+			"space": 16392
+		},
+		"executable": false,
+		"lamports": 114979200,
+		"owner": "Sysvar1111111111111111111111111111111111111",
+		"rentEpoch": 0
+	}`
+	var jsonParsed rpc.Account
+	if err := json.Unmarshal([]byte(jsonParsedValue), &jsonParsed); err != nil {
+		t.Fatal(err)
+	}
+	want, err := StakeHistoryAccount(&jsonParsed)
+	if err != nil {
+		t.Fatalf("StakeHistoryAccount error = %v", err)
+	}
+	// rpc.Account has no space, so it's taken from the data.
+	if want.Space != 16392 {
+		t.Errorf("StakeHistoryAccount Space = %d, want 16392", want.Space)
+	}
+
+	// Vec<(Epoch, StakeHistoryEntry)> padded to the size of the sysvar.
+	data := make([]byte, 16392)
+	binary.LittleEndian.PutUint64(data, 2)
+	for i, entry := range [][4]uint64{{815, 2, 3, 4}, {814, 5, 6, 7}} {
+		for j, v := range entry {
+			binary.LittleEndian.PutUint64(data[8+i*32+j*8:], v)
+		}
+	}
+	got, err := StakeHistoryAccount(withBase64Data(t, jsonParsedValue, data))
+	if err != nil {
+		t.Fatalf("StakeHistoryAccount(base64) error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("StakeHistoryAccount(base64) = %+v, want %+v", got, want)
+	}
+
+	result := &rpc.GetAccountInfoResult{Value: &jsonParsed}
+	result.Context.Slot = 100
+	if _, slot, err := StakeHistoryAccountFromResult(result); err != nil || slot != 100 {
+		t.Errorf("StakeHistoryAccountFromResult slot = %d, %v, want 100", slot, err)
+	}
+
+	// The length exceeds the data.
+	binary.LittleEndian.PutUint64(data, 1000)
+	if _, err := StakeHistoryAccount(withBase64Data(t, jsonParsedValue, data)); !errors.Is(err, types.ErrNotStakeHistoryAccount) {
+		t.Errorf("StakeHistoryAccount error = %v, want %v", err, types.ErrNotStakeHistoryAccount)
+	}
+}
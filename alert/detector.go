@@ -0,0 +1,182 @@
+package alert
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/skport/solana-rpc-client-extensions-go/client"
+	"github.com/skport/solana-rpc-client-extensions-go/types"
+
+	"golang.org/x/xerrors"
+)
+
+// StateClosed is the state of a stake account which doesn't exist anymore, e.g. after it was withdrawn or merged.
+const StateClosed = "closed"
+
+// Events classify a Transition.
+const (
+	EventActivationStarted   = "activation_started"
+	EventActivationFinished  = "activation_finished"
+	EventDeactivationStarted = "deactivation_started"
+	// EventCooldownFinished is delivered when the stake becomes withdrawable.
+	EventCooldownFinished = "cooldown_finished"
+	EventClosed           = "closed"
+	// EventStateChanged is delivered for other transitions, e.g. of a closed account created again without a delegation.
+	EventStateChanged = "state_changed"
+)
+
+// Transition is a change of the state of a stake account between two runs of a Detector.
+type Transition struct {
+	Event   string `json:"event"`
+	Address string `json:"address"`
+	// Epoch is the epoch in which the transition was detected.
+	Epoch uint64 `json:"epoch"`
+	// From and To are the states of GetStakeActivation, or StateClosed.
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Activation is the activation after the transition, or nil if the account was closed.
+	Activation *client.GetStakeActivationResponse `json:"activation,omitempty"`
+	Time       time.Time                          `json:"time"`
+}
+
+// Detector detects the transitions of the state of stake accounts and dispatches them to sinks.
+// The first run of an account records its state without an event.
+type Detector struct {
+	source client.AccountSource
+	store  StateStore
+	sinks  []Sink
+	now    func() time.Time
+}
+
+func NewDetector(source client.AccountSource, store StateStore, sinks ...Sink) *Detector {
+	return &Detector{
+		source: source,
+		store:  store,
+		sinks:  sinks,
+		now:    time.Now,
+	}
+}
+
+// Run compares the state of the stake accounts at addresses with the stored ones, and dispatches the transitions.
+// The state of an account is only stored after all sinks accepted its transition, so that a failed transition is dispatched again
+// in the next run, also to the sinks which accepted it.
+func (d *Detector) Run(ctx context.Context, addresses []string) ([]*Transition, error) {
+	states, err := d.store.Load(ctx)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to load states: %w", err)
+	}
+	epoch, err := d.source.GetEpoch(ctx)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to get epoch: %w", err)
+	}
+	stakeHistoryAccount, err := client.GetStakeHistoryAccount(ctx, d.source)
+	if err != nil {
+		return nil, xerrors.Errorf("wrap: %w", err)
+	}
+
+	var transitions []*Transition
+	var firstErr error
+	for _, address := range addresses {
+		state, activation, err := d.getState(ctx, address, epoch, stakeHistoryAccount)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+
+		last, ok := states[address]
+		if ok && last.State != state {
+			transition := &Transition{
+				Event:      classifyTransition(last.State, state),
+				Address:    address,
+				Epoch:      epoch,
+				From:       last.State,
+				To:         state,
+				Activation: activation,
+				Time:       d.now(),
+			}
+			if err := d.dispatch(ctx, transition); err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue
+			}
+			transitions = append(transitions, transition)
+		}
+		states[address] = AccountState{State: state, Epoch: epoch}
+	}
+
+	if err := d.store.Save(ctx, states); err != nil {
+		return transitions, xerrors.Errorf("failed to save states: %w", err)
+	}
+	return transitions, firstErr
+}
+
+// RunOnEpochBoundaries runs d once now and then at each epoch boundary, until ctx is canceled.
+// Errors of a run are passed to onError, and the next run retries.
+func (d *Detector) RunOnEpochBoundaries(ctx context.Context, addresses []string, onError func(error), opts ...client.WatchOption) error {
+	events, err := client.WatchEpochBoundaries(ctx, d.source, opts...)
+	if err != nil {
+		return xerrors.Errorf("wrap: %w", err)
+	}
+	if _, err := d.Run(ctx, addresses); err != nil {
+		onError(err)
+	}
+	for event := range events {
+		if event.Err != nil {
+			onError(event.Err)
+			continue
+		}
+		if _, err := d.Run(ctx, addresses); err != nil {
+			onError(err)
+		}
+	}
+	return ctx.Err()
+}
+
+func (d *Detector) getState(ctx context.Context, address string, epoch uint64, stakeHistoryAccount *types.StakeHistoryAccount) (string, *client.GetStakeActivationResponse, error) {
+	stakeAccount, err := client.GetStakeAccount(ctx, d.source, address)
+	if errors.Is(err, client.ErrAccountNotFound) {
+		return StateClosed, nil, nil
+	}
+	if err != nil {
+		return "", nil, xerrors.Errorf("wrap: %w", err)
+	}
+	activation, err := client.GetStakeActivation(address, epoch, stakeAccount, stakeHistoryAccount, client.WithCurrentEpoch())
+	if err != nil {
+		return "", nil, xerrors.Errorf("wrap: %w", err)
+	}
+	return activation.State, activation, nil
+}
+
+func (d *Detector) dispatch(ctx context.Context, transition *Transition) error {
+	var firstErr error
+	for _, sink := range d.sinks {
+		if err := sink.Send(ctx, transition); err != nil && firstErr == nil {
+			firstErr = xerrors.Errorf("failed to send %s of %s to %T: %w", transition.Event, transition.Address, sink, err)
+		}
+	}
+	return firstErr
+}
+
+// classifyTransition classifies a transition by the state it ends in, and for inactive by whether stake was delegated before,
+// so that a transition which skipped a state between two runs, e.g. from active to inactive, is still classified.
+func classifyTransition(from string, to string) string {
+	switch to {
+	case StateClosed:
+		return EventClosed
+	case "deactivating":
+		return EventDeactivationStarted
+	case "inactive":
+		if from == "active" || from == "activating" || from == "deactivating" {
+			return EventCooldownFinished
+		}
+	case "activating":
+		return EventActivationStarted
+	case "active":
+		return EventActivationFinished
+	}
+	return EventStateChanged
+}
@@ -0,0 +1,320 @@
+package alert
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/skport/solana-rpc-client-extensions-go/client"
+	"github.com/skport/solana-rpc-client-extensions-go/types"
+)
+
+const (
+	testActiveAddress       = "4vJ9JU1bJJE96FWSJKvHsmmFADCg4gpZQff4P3bkLKi"
+	testActivatingAddress   = "8qbHbw2BbbTHBW1sbeqakYXVKRQM8Ne7pLK7m6CVfeR"
+	testDeactivatingAddress = "CktRuQ2mttgRGkXJtyksdKHjUdc2C4TgDzyB98oEzy8"
+	testClosingAddress      = "GgBaCs3NCBuZN12kCJgAW63ydqohFkHEdfdEXBPzLHq"
+
+	testStake = 10000000000
+)
+
+var testAddresses = []string{testActiveAddress, testActivatingAddress, testDeactivatingAddress, testClosingAddress}
+
+func newTestStakeAccount(activationEpoch uint64, deactivationEpoch uint64) *types.StakeAccount {
+	a := &types.StakeAccount{
+		Lamports: testStake + 2282880,
+		Owner:    client.StakeProgramAddress,
+	}
+	a.Data.Program = "stake"
+	a.Data.Space = 200
+	a.Data.Parsed.Type = "delegated"
+	a.Data.Parsed.Info.Meta.RentExemptReserve = strconv.FormatUint(2282880, 10)
+
+	s := &types.StakeAccountInfoStake{}
+	s.Delegation.ActivationEpoch = activationEpoch
+	s.Delegation.DeactivationEpoch = deactivationEpoch
+	s.Delegation.Stake = testStake
+	s.Delegation.Voter = "LbUiWL3xVV8hTFYBVdbTNrpDo41NKS6o3LHHuDzjfcY"
+	a.Data.Parsed.Info.Stake = s
+	return a
+}
+
+// newTestStakeHistory returns the StakeHistory sysvar of epoch, in which warmup and cooldown of the test accounts complete in an epoch.
+func newTestStakeHistory(epoch uint64) *types.StakeHistoryAccount {
+	h := &types.StakeHistoryAccount{Owner: client.SysvarProgramAddress}
+	h.Data.Program = "sysvar"
+	h.Data.Parsed.Type = "stakeHistory"
+	for _, e := range []uint64{epoch - 1, epoch - 2} {
+		entry := types.StakeHistoryAccountInfo{Epoch: int(e)}
+		entry.StakeHistory.Effective = 1000000000000000
+		entry.StakeHistory.Activating = testStake
+		entry.StakeHistory.Deactivating = testStake
+		h.Data.Parsed.Info = append(h.Data.Parsed.Info, entry)
+	}
+	return h
+}
+
+func newTestSource() *client.MemoryAccountSource {
+	source := client.NewMemoryAccountSource(10)
+	source.SetAccount(client.StakeHistoryAccountAddress, newTestStakeHistory(10))
+	source.SetAccount(testActiveAddress, newTestStakeAccount(0, types.MaxEpoch))
+	source.SetAccount(testActivatingAddress, newTestStakeAccount(10, types.MaxEpoch))
+	source.SetAccount(testDeactivatingAddress, newTestStakeAccount(0, 10))
+	source.SetAccount(testClosingAddress, newTestStakeAccount(0, types.MaxEpoch))
+	return source
+}
+
+// advanceTestSource deactivates the active account, closes an account and advances to epoch 11.
+func advanceTestSource(source *client.MemoryAccountSource) {
+	source.SetAccount(testActiveAddress, newTestStakeAccount(0, 11))
+	source.DeleteAccount(testClosingAddress)
+	source.SetEpoch(11)
+	source.SetAccount(client.StakeHistoryAccountAddress, newTestStakeHistory(11))
+}
+
+type testTransition struct {
+	Event, Address, From, To string
+	Epoch                    uint64
+}
+
+func toTestTransitions(transitions []*Transition) []testTransition {
+	var got []testTransition
+	for _, transition := range transitions {
+		got = append(got, testTransition{transition.Event, transition.Address, transition.From, transition.To, transition.Epoch})
+	}
+	return got
+}
+
+func TestDetector_Run(t *testing.T) {
+	ctx := context.Background()
+
+	source := newTestSource()
+	var sent []*Transition
+	detector := NewDetector(source, NewMemoryStateStore(), SinkFunc(func(ctx context.Context, transition *Transition) error {
+		sent = append(sent, transition)
+		return nil
+	}))
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	detector.now = func() time.Time { return now }
+
+	// The first run records the states.
+	transitions, err := detector.Run(ctx, testAddresses)
+	if err != nil {
+		t.Fatalf("Run error = %v", err)
+	}
+	if len(transitions) != 0 {
+		t.Fatalf("transitions of the first run = %+v", toTestTransitions(transitions))
+	}
+
+	advanceTestSource(source)
+	transitions, err = detector.Run(ctx, testAddresses)
+	if err != nil {
+		t.Fatalf("Run error = %v", err)
+	}
+	want := []testTransition{
+		{EventDeactivationStarted, testActiveAddress, "active", "deactivating", 11},
+		{EventActivationFinished, testActivatingAddress, "activating", "active", 11},
+		{EventCooldownFinished, testDeactivatingAddress, "deactivating", "inactive", 11},
+		{EventClosed, testClosingAddress, "active", StateClosed, 11},
+	}
+	if got := toTestTransitions(transitions); !reflect.DeepEqual(got, want) {
+		t.Errorf("transitions = %+v, want %+v", got, want)
+	}
+	if !reflect.DeepEqual(sent, transitions) {
+		t.Errorf("sent = %+v, want %+v", toTestTransitions(sent), want)
+	}
+	if transitions[0].Activation == nil || transitions[0].Activation.Active != testStake || !transitions[0].Time.Equal(now) {
+		t.Errorf("transition = %+v", transitions[0])
+	}
+	if transitions[3].Activation != nil {
+		t.Errorf("activation of a closed account = %+v", transitions[3].Activation)
+	}
+
+	// Nothing changed.
+	transitions, err = detector.Run(ctx, testAddresses)
+	if err != nil || len(transitions) != 0 {
+		t.Errorf("Run = %+v, %v, want no transitions", toTestTransitions(transitions), err)
+	}
+}
+
+func TestDetector_Run_SkippedStates(t *testing.T) {
+	ctx := context.Background()
+
+	initialized := newTestStakeAccount(0, 0)
+	initialized.Data.Parsed.Type = "initialized"
+	initialized.Data.Parsed.Info.Stake = nil
+
+	source := newTestSource()
+	source.SetAccount(testActivatingAddress, initialized)
+	detector := NewDetector(source, NewMemoryStateStore())
+	addresses := []string{testActiveAddress, testActivatingAddress}
+	if _, err := detector.Run(ctx, addresses); err != nil {
+		t.Fatalf("Run error = %v", err)
+	}
+
+	// The next run is two epochs later, after the cooldown and the warmup completed.
+	source.SetAccount(testActiveAddress, newTestStakeAccount(0, 11))
+	source.SetAccount(testActivatingAddress, newTestStakeAccount(11, types.MaxEpoch))
+	source.SetEpoch(13)
+	source.SetAccount(client.StakeHistoryAccountAddress, newTestStakeHistory(13))
+	transitions, err := detector.Run(ctx, addresses)
+	if err != nil {
+		t.Fatalf("Run error = %v", err)
+	}
+	want := []testTransition{
+		{EventCooldownFinished, testActiveAddress, "active", "inactive", 13},
+		{EventActivationFinished, testActivatingAddress, "inactive", "active", 13},
+	}
+	if got := toTestTransitions(transitions); !reflect.DeepEqual(got, want) {
+		t.Errorf("transitions = %+v, want %+v", got, want)
+	}
+}
+
+func TestClassifyTransition(t *testing.T) {
+	tests := []struct {
+		from string
+		to   string
+		want string
+	}{
+		{from: "inactive", to: "activating", want: EventActivationStarted},
+		{from: "activating", to: "active", want: EventActivationFinished},
+		{from: "active", to: "deactivating", want: EventDeactivationStarted},
+		{from: "deactivating", to: "inactive", want: EventCooldownFinished},
+		{from: "active", to: StateClosed, want: EventClosed},
+		// States skipped between two runs.
+		{from: "inactive", to: "active", want: EventActivationFinished},
+		{from: "active", to: "inactive", want: EventCooldownFinished},
+		{from: "activating", to: "inactive", want: EventCooldownFinished},
+		{from: "activating", to: "deactivating", want: EventDeactivationStarted},
+		{from: "inactive", to: "deactivating", want: EventDeactivationStarted},
+		// The deactivation was rescinded by delegating to the same vote account again.
+		{from: "deactivating", to: "active", want: EventActivationFinished},
+		{from: StateClosed, to: "active", want: EventActivationFinished},
+		{from: StateClosed, to: "inactive", want: EventStateChanged},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.from+" to "+tt.to, func(t *testing.T) {
+			if got := classifyTransition(tt.from, tt.to); got != tt.want {
+				t.Errorf("classifyTransition(%q, %q) = %s, want %s", tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetector_Run_SinkError(t *testing.T) {
+	ctx := context.Background()
+	errSink := errors.New("sink error")
+
+	source := newTestSource()
+	fail := true
+	var sent []testTransition
+	detector := NewDetector(source, NewMemoryStateStore(), SinkFunc(func(ctx context.Context, transition *Transition) error {
+		if fail && transition.Address == testActivatingAddress {
+			return errSink
+		}
+		sent = append(sent, toTestTransitions([]*Transition{transition})...)
+		return nil
+	}))
+	if _, err := detector.Run(ctx, testAddresses); err != nil {
+		t.Fatalf("Run error = %v", err)
+	}
+
+	advanceTestSource(source)
+	transitions, err := detector.Run(ctx, testAddresses)
+	if !errors.Is(err, errSink) {
+		t.Fatalf("Run error = %v, want %v", err, errSink)
+	}
+	if len(transitions) != 3 {
+		t.Errorf("transitions = %+v, want 3", toTestTransitions(transitions))
+	}
+
+	// The failed transition is dispatched again.
+	fail = false
+	sent = nil
+	transitions, err = detector.Run(ctx, testAddresses)
+	if err != nil {
+		t.Fatalf("Run error = %v", err)
+	}
+	want := []testTransition{{EventActivationFinished, testActivatingAddress, "activating", "active", 11}}
+	if got := toTestTransitions(transitions); !reflect.DeepEqual(got, want) || !reflect.DeepEqual(sent, want) {
+		t.Errorf("transitions = %+v, sent = %+v, want %+v", got, sent, want)
+	}
+}
+
+func TestDetector_Run_FileStateStore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "states", "states.json")
+
+	source := newTestSource()
+	if _, err := NewDetector(source, NewFileStateStore(path)).Run(ctx, testAddresses); err != nil {
+		t.Fatalf("Run error = %v", err)
+	}
+
+	// A restarted process detects the transitions since the previous one.
+	advanceTestSource(source)
+	transitions, err := NewDetector(source, NewFileStateStore(path)).Run(ctx, testAddresses)
+	if err != nil {
+		t.Fatalf("Run error = %v", err)
+	}
+	if len(transitions) != 4 {
+		t.Errorf("transitions = %+v, want 4", toTestTransitions(transitions))
+	}
+
+	states, err := NewFileStateStore(path).Load(ctx)
+	if err != nil {
+		t.Fatalf("Load error = %v", err)
+	}
+	want := map[string]AccountState{
+		testActiveAddress:       {State: "deactivating", Epoch: 11},
+		testActivatingAddress:   {State: "active", Epoch: 11},
+		testDeactivatingAddress: {State: "inactive", Epoch: 11},
+		testClosingAddress:      {State: StateClosed, Epoch: 11},
+	}
+	if !reflect.DeepEqual(states, want) {
+		t.Errorf("states = %+v, want %+v", states, want)
+	}
+}
+
+func TestDetector_RunOnEpochBoundaries(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	source := newTestSource()
+	sent := make(chan *Transition, len(testAddresses))
+	detector := NewDetector(source, NewMemoryStateStore(), SinkFunc(func(ctx context.Context, transition *Transition) error {
+		sent <- transition
+		return nil
+	}))
+
+	done := make(chan error)
+	go func() {
+		done <- detector.RunOnEpochBoundaries(ctx, testAddresses, func(err error) { t.Errorf("run error = %v", err) }, client.WithPollInterval(time.Millisecond))
+	}()
+
+	// Wait for the first run before changing the accounts.
+	for {
+		states, _ := detector.store.Load(ctx)
+		if len(states) == len(testAddresses) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	advanceTestSource(source)
+	for i := 0; i < len(testAddresses); i++ {
+		select {
+		case <-sent:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for transition %d", i)
+		}
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("RunOnEpochBoundaries error = %v, want %v", err, context.Canceled)
+	}
+}
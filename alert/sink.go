@@ -0,0 +1,118 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+
+	"golang.org/x/xerrors"
+)
+
+// Sink delivers transitions, e.g. to a log or a chat webhook.
+type Sink interface {
+	Send(ctx context.Context, transition *Transition) error
+}
+
+// SinkFunc adapts a function to a Sink.
+type SinkFunc func(ctx context.Context, transition *Transition) error
+
+func (f SinkFunc) Send(ctx context.Context, transition *Transition) error {
+	return f(ctx, transition)
+}
+
+// JSONSink writes each transition as a line of JSON, e.g. to os.Stdout.
+type JSONSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewJSONSink(w io.Writer) *JSONSink {
+	return &JSONSink{w: w}
+}
+
+func (s *JSONSink) Send(ctx context.Context, transition *Transition) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := json.NewEncoder(s.w).Encode(transition); err != nil {
+		return xerrors.Errorf("failed to write transition: %w", err)
+	}
+	return nil
+}
+
+// FileSink appends each transition to a file as a line of JSON.
+type FileSink struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewFileSink(path string) *FileSink {
+	return &FileSink{path: path}
+}
+
+func (s *FileSink) Send(ctx context.Context, transition *Transition) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return xerrors.Errorf("failed to open %s: %w", s.path, err)
+	}
+	if err := json.NewEncoder(f).Encode(transition); err != nil {
+		f.Close()
+		return xerrors.Errorf("failed to write transition: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return xerrors.Errorf("failed to write transition: %w", err)
+	}
+	return nil
+}
+
+// WebhookSink posts each transition as JSON to a URL.
+// Responses other than 2xx are errors.
+type WebhookSink struct {
+	url    string
+	client *http.Client
+	header http.Header
+}
+
+// NewWebhookSink posts to url with httpClient, or http.DefaultClient if it's nil.
+// header is added to each request, e.g. for an Authorization header.
+func NewWebhookSink(url string, httpClient *http.Client, header http.Header) *WebhookSink {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &WebhookSink{url: url, client: httpClient, header: header}
+}
+
+func (s *WebhookSink) Send(ctx context.Context, transition *Transition) error {
+	b, err := json.Marshal(transition)
+	if err != nil {
+		return xerrors.Errorf("failed to marshal transition: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(b))
+	if err != nil {
+		return xerrors.Errorf("failed to create request: %w", err)
+	}
+	for key, values := range s.header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return xerrors.Errorf("failed to post transition: %w", err)
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, res.Body)
+	if res.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook responded with status %d", res.StatusCode)
+	}
+	return nil
+}
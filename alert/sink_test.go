@@ -0,0 +1,111 @@
+package alert
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/skport/solana-rpc-client-extensions-go/client"
+)
+
+func newTestTransition(address string) *Transition {
+	return &Transition{
+		Event:      EventCooldownFinished,
+		Address:    address,
+		Epoch:      11,
+		From:       "deactivating",
+		To:         "inactive",
+		Activation: &client.GetStakeActivationResponse{Active: 0, Inactive: testStake, State: "inactive"},
+		Time:       time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+func decodeTestTransitions(t *testing.T, r io.Reader) []*Transition {
+	t.Helper()
+	var transitions []*Transition
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		var transition Transition
+		if err := json.Unmarshal(scanner.Bytes(), &transition); err != nil {
+			t.Fatalf("failed to unmarshal %s: %v", scanner.Bytes(), err)
+		}
+		transitions = append(transitions, &transition)
+	}
+	return transitions
+}
+
+func TestJSONSink(t *testing.T) {
+	ctx := context.Background()
+
+	var buf bytes.Buffer
+	sink := NewJSONSink(&buf)
+	want := []*Transition{newTestTransition(testActiveAddress), newTestTransition(testActivatingAddress)}
+	for _, transition := range want {
+		if err := sink.Send(ctx, transition); err != nil {
+			t.Fatalf("Send error = %v", err)
+		}
+	}
+	if got := decodeTestTransitions(t, &buf); !reflect.DeepEqual(got, want) {
+		t.Errorf("transitions = %+v, want %+v", got, want)
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "transitions.jsonl")
+
+	want := []*Transition{newTestTransition(testActiveAddress), newTestTransition(testActivatingAddress)}
+	for _, transition := range want {
+		// Each send appends to the file.
+		if err := NewFileSink(path).Send(ctx, transition); err != nil {
+			t.Fatalf("Send error = %v", err)
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if got := decodeTestTransitions(t, f); !reflect.DeepEqual(got, want) {
+		t.Errorf("transitions = %+v, want %+v", got, want)
+	}
+}
+
+func TestWebhookSink(t *testing.T) {
+	ctx := context.Background()
+
+	var got []*Transition
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" || r.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("request = %s, headers: %v", r.Method, r.Header)
+		}
+		got = append(got, decodeTestTransitions(t, r.Body)...)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	sink := NewWebhookSink(server.URL, server.Client(), http.Header{"Authorization": {"Bearer token"}})
+	transition := newTestTransition(testActiveAddress)
+	if err := sink.Send(ctx, transition); err != nil {
+		t.Fatalf("Send error = %v", err)
+	}
+	if want := []*Transition{transition}; !reflect.DeepEqual(got, want) {
+		t.Errorf("transitions = %+v, want %+v", got, want)
+	}
+
+	status = http.StatusInternalServerError
+	if err := sink.Send(ctx, transition); err == nil {
+		t.Error("Send with status 500 succeeded")
+	}
+}
@@ -0,0 +1,110 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/xerrors"
+)
+
+// AccountState is the last known state of a stake account.
+type AccountState struct {
+	State string `json:"state"`
+	// Epoch is the epoch in which State was observed.
+	Epoch uint64 `json:"epoch"`
+}
+
+// StateStore persists the last known state of each stake account between runs of a Detector.
+type StateStore interface {
+	// Load returns the stored states by address. The returned map is owned by the caller.
+	Load(ctx context.Context) (map[string]AccountState, error)
+	Save(ctx context.Context, states map[string]AccountState) error
+}
+
+// MemoryStateStore keeps the states in memory, e.g. for a long-running process or tests.
+type MemoryStateStore struct {
+	mu     sync.Mutex
+	states map[string]AccountState
+}
+
+func NewMemoryStateStore() *MemoryStateStore {
+	return &MemoryStateStore{states: map[string]AccountState{}}
+}
+
+func (s *MemoryStateStore) Load(ctx context.Context) (map[string]AccountState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return copyStates(s.states), nil
+}
+
+func (s *MemoryStateStore) Save(ctx context.Context, states map[string]AccountState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.states = copyStates(states)
+	return nil
+}
+
+// FileStateStore keeps the states in a JSON file, which is replaced atomically on Save.
+// A missing file is loaded as no states.
+type FileStateStore struct {
+	path string
+}
+
+func NewFileStateStore(path string) *FileStateStore {
+	return &FileStateStore{path: path}
+}
+
+func (s *FileStateStore) Load(ctx context.Context) (map[string]AccountState, error) {
+	b, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]AccountState{}, nil
+	}
+	if err != nil {
+		return nil, xerrors.Errorf("failed to read states: %w", err)
+	}
+
+	states := map[string]AccountState{}
+	if err := json.Unmarshal(b, &states); err != nil {
+		return nil, xerrors.Errorf("failed to unmarshal %s: %w", s.path, err)
+	}
+	return states, nil
+}
+
+func (s *FileStateStore) Save(ctx context.Context, states map[string]AccountState) error {
+	b, err := json.MarshalIndent(states, "", "  ")
+	if err != nil {
+		return xerrors.Errorf("failed to marshal states: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return xerrors.Errorf("failed to create directory: %w", err)
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp*")
+	if err != nil {
+		return xerrors.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return xerrors.Errorf("failed to write states: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return xerrors.Errorf("failed to write states: %w", err)
+	}
+	if err := os.Rename(f.Name(), s.path); err != nil {
+		return xerrors.Errorf("failed to replace states: %w", err)
+	}
+	return nil
+}
+
+func copyStates(states map[string]AccountState) map[string]AccountState {
+	c := make(map[string]AccountState, len(states))
+	for address, state := range states {
+		c[address] = state
+	}
+	return c
+}
@@ -0,0 +1,411 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	sdkRpc "github.com/blocto/solana-go-sdk/rpc"
+	"github.com/skport/solana-rpc-client-extensions-go/types"
+
+	"golang.org/x/xerrors"
+)
+
+// AccountSource provides the inputs of GetStakeActivation.
+// Accounts are returned as the jsonParsed `value` of getAccountInfo, and nil is returned for accounts that do not exist.
+type AccountSource interface {
+	GetAccount(ctx context.Context, address string) (json.RawMessage, error)
+	GetMultipleAccounts(ctx context.Context, addresses []string) ([]json.RawMessage, error)
+	GetEpoch(ctx context.Context) (uint64, error)
+}
+
+// SlotAccountSource is implemented by an AccountSource which also returns the context slot of its responses,
+// so that callers can detect inputs from different slots. Each request requires at least minContextSlot, unless it's 0.
+type SlotAccountSource interface {
+	GetAccountWithSlot(ctx context.Context, address string, minContextSlot uint64) (json.RawMessage, uint64, error)
+	// GetMultipleAccountsWithSlot returns accounts which are all from the returned slot, so it's limited to the addresses of one request.
+	GetMultipleAccountsWithSlot(ctx context.Context, addresses []string, minContextSlot uint64) ([]json.RawMessage, uint64, error)
+	// GetEpochWithSlot returns the epoch and the absolute slot of getEpochInfo.
+	GetEpochWithSlot(ctx context.Context, minContextSlot uint64) (uint64, uint64, error)
+}
+
+// VoteAccountsSource is implemented by an AccountSource which can also serve getVoteAccounts.
+type VoteAccountsSource interface {
+	GetVoteAccounts(ctx context.Context) (*sdkRpc.GetVoteAccounts, error)
+}
+
+func GetStakeAccount(ctx context.Context, source AccountSource, address string) (*types.StakeAccount, error) {
+	raw, err := source.GetAccount(ctx, address)
+	if err != nil {
+		return nil, xerrors.Errorf("stakeAccount: %s, wrap: %w", address, err)
+	}
+	if raw == nil {
+		return nil, &AccountError{Address: address, Err: ErrAccountNotFound}
+	}
+	stakeAccount, err := types.DecodeStakeAccount(raw)
+	if err != nil {
+		return nil, &AccountError{Address: address, Err: err}
+	}
+	return stakeAccount, nil
+}
+
+func GetStakeHistoryAccount(ctx context.Context, source AccountSource) (*types.StakeHistoryAccount, error) {
+	raw, err := source.GetAccount(ctx, StakeHistoryAccountAddress)
+	if err != nil {
+		return nil, xerrors.Errorf("stakeHistoryAccount: %s, wrap: %w", StakeHistoryAccountAddress, err)
+	}
+	if raw == nil {
+		return nil, &AccountError{Address: StakeHistoryAccountAddress, Err: ErrAccountNotFound}
+	}
+	return types.DecodeStakeHistoryAccount(raw)
+}
+
+// GetStakeActivationFromSource fetches the current epoch, the StakeHistory sysvar and the stake account from source,
+// and calculates the activation of the stake account.
+func GetStakeActivationFromSource(ctx context.Context, source AccountSource, stakeAccountAddress string) (*GetStakeActivationResponse, error) {
+	epoch, err := source.GetEpoch(ctx)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to get epoch: %w", err)
+	}
+	stakeHistoryAccount, err := GetStakeHistoryAccount(ctx, source)
+	if err != nil {
+		return nil, xerrors.Errorf("wrap: %w", err)
+	}
+	stakeAccount, err := GetStakeAccount(ctx, source, stakeAccountAddress)
+	if err != nil {
+		return nil, xerrors.Errorf("wrap: %w", err)
+	}
+
+	return GetStakeActivation(stakeAccountAddress, epoch, stakeAccount, stakeHistoryAccount, WithCurrentEpoch())
+}
+
+const (
+	// https://solana.com/docs/rpc/http/getmultipleaccounts
+	getMultipleAccountsLimit = 100
+)
+
+// RpcAccountSource fetches accounts from a live JSON-RPC endpoint with jsonParsed encoding.
+type RpcAccountSource struct {
+	rpc        sdkRpc.RpcClient
+	commitment sdkRpc.Commitment
+}
+
+func NewRpcAccountSource(rpc sdkRpc.RpcClient) *RpcAccountSource {
+	return &RpcAccountSource{
+		rpc:        rpc,
+		commitment: sdkRpc.CommitmentFinalized,
+	}
+}
+
+// rpcConfig is the config of the requests, with minContextSlot which the config types of the SDK lack.
+type rpcConfig struct {
+	Commitment     sdkRpc.Commitment      `json:"commitment,omitempty"`
+	Encoding       sdkRpc.AccountEncoding `json:"encoding,omitempty"`
+	MinContextSlot *uint64                `json:"minContextSlot,omitempty"`
+}
+
+func (s *RpcAccountSource) config(encoding sdkRpc.AccountEncoding, minContextSlot uint64) rpcConfig {
+	c := rpcConfig{Commitment: s.commitment, Encoding: encoding}
+	if minContextSlot > 0 {
+		c.MinContextSlot = &minContextSlot
+	}
+	return c
+}
+
+func (s *RpcAccountSource) GetAccount(ctx context.Context, address string) (json.RawMessage, error) {
+	account, _, err := s.GetAccountWithSlot(ctx, address, 0)
+	return account, err
+}
+
+// GetAccountWithSlot returns the account and the slot of the response, which is at least minContextSlot.
+func (s *RpcAccountSource) GetAccountWithSlot(ctx context.Context, address string, minContextSlot uint64) (json.RawMessage, uint64, error) {
+	var res sdkRpc.JsonRpcResponse[sdkRpc.ValueWithContext[json.RawMessage]]
+	err := s.call(ctx, &res, "getAccountInfo", address, s.config(sdkRpc.AccountEncodingJsonParsed, minContextSlot))
+	if err != nil {
+		return nil, 0, xerrors.Errorf("failed to getAccountInfo: %w", err)
+	}
+
+	return nullToNil(res.Result.Value), res.Result.Context.Slot, nil
+}
+
+// GetMultipleAccounts fetches the accounts in requests of up to getMultipleAccountsLimit addresses.
+// Each request requires at least the slot of the previous one, but the requests may be served at different slots.
+func (s *RpcAccountSource) GetMultipleAccounts(ctx context.Context, addresses []string) ([]json.RawMessage, error) {
+	accounts := make([]json.RawMessage, 0, len(addresses))
+	var slot uint64
+	for start := 0; start < len(addresses); start += getMultipleAccountsLimit {
+		end := start + getMultipleAccountsLimit
+		if end > len(addresses) {
+			end = len(addresses)
+		}
+		chunk, chunkSlot, err := s.GetMultipleAccountsWithSlot(ctx, addresses[start:end], slot)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, chunk...)
+		slot = chunkSlot
+	}
+	return accounts, nil
+}
+
+// GetMultipleAccountsWithSlot fetches the accounts in one request, so that they are all from the returned slot.
+// It fails for more than getMultipleAccountsLimit addresses.
+func (s *RpcAccountSource) GetMultipleAccountsWithSlot(ctx context.Context, addresses []string, minContextSlot uint64) ([]json.RawMessage, uint64, error) {
+	if len(addresses) > getMultipleAccountsLimit {
+		return nil, 0, fmt.Errorf("%d addresses exceed the getMultipleAccounts limit of %d", len(addresses), getMultipleAccountsLimit)
+	}
+
+	var res sdkRpc.JsonRpcResponse[sdkRpc.ValueWithContext[[]json.RawMessage]]
+	err := s.call(ctx, &res, "getMultipleAccounts", addresses, s.config(sdkRpc.AccountEncodingJsonParsed, minContextSlot))
+	if err != nil {
+		return nil, 0, xerrors.Errorf("failed to getMultipleAccounts: %w", err)
+	}
+	if len(res.Result.Value) != len(addresses) {
+		return nil, 0, fmt.Errorf("getMultipleAccounts returned %d accounts, want %d", len(res.Result.Value), len(addresses))
+	}
+
+	accounts := make([]json.RawMessage, 0, len(addresses))
+	for _, v := range res.Result.Value {
+		accounts = append(accounts, nullToNil(v))
+	}
+	return accounts, res.Result.Context.Slot, nil
+}
+
+func (s *RpcAccountSource) GetEpoch(ctx context.Context) (uint64, error) {
+	epoch, _, err := s.GetEpochWithSlot(ctx, 0)
+	return epoch, err
+}
+
+// GetEpochWithSlot returns the epoch and the absolute slot of getEpochInfo.
+func (s *RpcAccountSource) GetEpochWithSlot(ctx context.Context, minContextSlot uint64) (uint64, uint64, error) {
+	var res sdkRpc.JsonRpcResponse[sdkRpc.GetEpochInfo]
+	err := s.call(ctx, &res, "getEpochInfo", s.config("", minContextSlot))
+	if err != nil {
+		return 0, 0, xerrors.Errorf("failed to getEpochInfo: %w", err)
+	}
+	return res.Result.Epoch, res.Result.AbsoluteSlot, nil
+}
+
+func (s *RpcAccountSource) GetStakeMinimumDelegation(ctx context.Context) (uint64, error) {
+	var res sdkRpc.JsonRpcResponse[sdkRpc.ValueWithContext[uint64]]
+	err := s.call(ctx, &res, "getStakeMinimumDelegation", s.config("", 0))
+	if err != nil {
+		return 0, xerrors.Errorf("failed to getStakeMinimumDelegation: %w", err)
+	}
+	return res.Result.Value, nil
+}
+
+func (s *RpcAccountSource) GetVoteAccounts(ctx context.Context) (*sdkRpc.GetVoteAccounts, error) {
+	var res sdkRpc.JsonRpcResponse[sdkRpc.GetVoteAccounts]
+	err := s.call(ctx, &res, "getVoteAccounts", sdkRpc.GetVoteAccountsConfig{Commitment: s.commitment})
+	if err != nil {
+		return nil, xerrors.Errorf("failed to getVoteAccounts: %w", err)
+	}
+	return &res.Result, nil
+}
+
+func (s *RpcAccountSource) call(ctx context.Context, res interface{ GetError() error }, params ...any) error {
+	body, err := s.rpc.Call(ctx, params...)
+	if err != nil {
+		return xerrors.Errorf("wrap: %w", err)
+	}
+	if err := json.Unmarshal(body, res); err != nil {
+		return xerrors.Errorf("failed to unmarshal response: %w", err)
+	}
+	if err := res.GetError(); err != nil {
+		return xerrors.Errorf("wrap: %w", err)
+	}
+	return nil
+}
+
+func nullToNil(raw json.RawMessage) json.RawMessage {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+	return raw
+}
+
+const (
+	fixtureEpochInfoFile              = "epochInfo.json"
+	fixtureStakeMinimumDelegationFile = "stakeMinimumDelegation.json"
+	fixtureVoteAccountsFile           = "voteAccounts.json"
+)
+
+// FixtureAccountSource reads accounts from a directory of JSON files.
+//
+//	<dir>/epochInfo.json               result of getEpochInfo
+//	<dir>/stakeMinimumDelegation.json  value of getStakeMinimumDelegation
+//	<dir>/voteAccounts.json            result of getVoteAccounts
+//	<dir>/<address>.json               value of getAccountInfo (jsonParsed)
+//
+// Accounts without a file are treated as non-existent.
+type FixtureAccountSource struct {
+	dir string
+}
+
+func NewFixtureAccountSource(dir string) *FixtureAccountSource {
+	return &FixtureAccountSource{dir: dir}
+}
+
+func (s *FixtureAccountSource) GetAccount(ctx context.Context, address string) (json.RawMessage, error) {
+	if filepath.Base(address) != address {
+		return nil, fmt.Errorf("invalid address: %s", address)
+	}
+
+	b, err := os.ReadFile(filepath.Join(s.dir, address+".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, xerrors.Errorf("failed to read fixture: %w", err)
+	}
+	return nullToNil(b), nil
+}
+
+func (s *FixtureAccountSource) GetMultipleAccounts(ctx context.Context, addresses []string) ([]json.RawMessage, error) {
+	accounts := make([]json.RawMessage, 0, len(addresses))
+	for _, address := range addresses {
+		account, err := s.GetAccount(ctx, address)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, account)
+	}
+	return accounts, nil
+}
+
+func (s *FixtureAccountSource) GetEpoch(ctx context.Context) (uint64, error) {
+	b, err := os.ReadFile(filepath.Join(s.dir, fixtureEpochInfoFile))
+	if err != nil {
+		return 0, xerrors.Errorf("failed to read fixture: %w", err)
+	}
+
+	var epochInfo sdkRpc.GetEpochInfo
+	if err := json.Unmarshal(b, &epochInfo); err != nil {
+		return 0, xerrors.Errorf("failed to unmarshal %s: %w", fixtureEpochInfoFile, err)
+	}
+	return epochInfo.Epoch, nil
+}
+
+func (s *FixtureAccountSource) GetStakeMinimumDelegation(ctx context.Context) (uint64, error) {
+	b, err := os.ReadFile(filepath.Join(s.dir, fixtureStakeMinimumDelegationFile))
+	if err != nil {
+		return 0, xerrors.Errorf("failed to read fixture: %w", err)
+	}
+
+	var minimumDelegation uint64
+	if err := json.Unmarshal(b, &minimumDelegation); err != nil {
+		return 0, xerrors.Errorf("failed to unmarshal %s: %w", fixtureStakeMinimumDelegationFile, err)
+	}
+	return minimumDelegation, nil
+}
+
+func (s *FixtureAccountSource) GetVoteAccounts(ctx context.Context) (*sdkRpc.GetVoteAccounts, error) {
+	b, err := os.ReadFile(filepath.Join(s.dir, fixtureVoteAccountsFile))
+	if err != nil {
+		return nil, xerrors.Errorf("failed to read fixture: %w", err)
+	}
+
+	var voteAccounts sdkRpc.GetVoteAccounts
+	if err := json.Unmarshal(b, &voteAccounts); err != nil {
+		return nil, xerrors.Errorf("failed to unmarshal %s: %w", fixtureVoteAccountsFile, err)
+	}
+	return &voteAccounts, nil
+}
+
+// MemoryAccountSource serves accounts from memory.
+// Accounts can be any value that marshals to the jsonParsed account, e.g. *types.StakeAccount or json.RawMessage.
+type MemoryAccountSource struct {
+	mu                sync.RWMutex
+	epoch             uint64
+	minimumDelegation uint64
+	voteAccounts      sdkRpc.GetVoteAccounts
+	accounts          map[string]any
+}
+
+func NewMemoryAccountSource(epoch uint64) *MemoryAccountSource {
+	return &MemoryAccountSource{
+		epoch:    epoch,
+		accounts: map[string]any{},
+	}
+}
+
+func (s *MemoryAccountSource) SetEpoch(epoch uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.epoch = epoch
+}
+
+func (s *MemoryAccountSource) SetStakeMinimumDelegation(minimumDelegation uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.minimumDelegation = minimumDelegation
+}
+
+func (s *MemoryAccountSource) SetVoteAccounts(voteAccounts sdkRpc.GetVoteAccounts) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.voteAccounts = voteAccounts
+}
+
+func (s *MemoryAccountSource) SetAccount(address string, account any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.accounts[address] = account
+}
+
+func (s *MemoryAccountSource) DeleteAccount(address string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.accounts, address)
+}
+
+func (s *MemoryAccountSource) GetAccount(ctx context.Context, address string) (json.RawMessage, error) {
+	s.mu.RLock()
+	account, ok := s.accounts[address]
+	s.mu.RUnlock()
+	if !ok || account == nil {
+		return nil, nil
+	}
+
+	b, err := json.Marshal(account)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to marshal: %w", err)
+	}
+	return nullToNil(b), nil
+}
+
+func (s *MemoryAccountSource) GetMultipleAccounts(ctx context.Context, addresses []string) ([]json.RawMessage, error) {
+	accounts := make([]json.RawMessage, 0, len(addresses))
+	for _, address := range addresses {
+		account, err := s.GetAccount(ctx, address)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, account)
+	}
+	return accounts, nil
+}
+
+func (s *MemoryAccountSource) GetEpoch(ctx context.Context) (uint64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.epoch, nil
+}
+
+func (s *MemoryAccountSource) GetStakeMinimumDelegation(ctx context.Context) (uint64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.minimumDelegation, nil
+}
+
+func (s *MemoryAccountSource) GetVoteAccounts(ctx context.Context) (*sdkRpc.GetVoteAccounts, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	voteAccounts := s.voteAccounts
+	return &voteAccounts, nil
+}
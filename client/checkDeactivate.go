@@ -0,0 +1,33 @@
+package client
+
+import (
+	"github.com/skport/solana-rpc-client-extensions-go/types"
+)
+
+// CheckDeactivate returns nil if a Deactivate instruction for stakeAccount is permitted at epoch,
+// otherwise an *AccountError explaining why the stake program would reject it.
+// https://github.com/anza-xyz/agave/blob/v2.0.0/programs/stake/src/stake_state.rs#L615
+//
+// Stake flags are only decoded from base64 account data. For a jsonParsed stake account, the check of
+// StakeFlagMustFullyActivateBeforeDeactivationIsPermitted is skipped.
+func CheckDeactivate(stakeAccountAddress string, epoch uint64, stakeAccount *types.StakeAccount, stakeHistoryAccount *types.StakeHistoryAccount) error {
+	stakeInfo, err := stakeAccount.GetInfoStake()
+	if err != nil {
+		return &AccountError{Address: stakeAccountAddress, Epoch: epoch, Err: err}
+	}
+
+	if stakeInfo.Flags.Contains(types.StakeFlagMustFullyActivateBeforeDeactivationIsPermitted) {
+		_, activating, _, err := getSolanaStakeActivatingAndDeactivating(stakeAccountAddress, stakeAccount, epoch, stakeHistoryAccount)
+		if err != nil {
+			return &AccountError{Address: stakeAccountAddress, Epoch: epoch, Err: err}
+		}
+		if activating != 0 {
+			return &AccountError{Address: stakeAccountAddress, Epoch: epoch, Err: ErrRedelegatedStakeMustFullyActivate}
+		}
+	}
+
+	if stakeInfo.Delegation.IsDeactivating() {
+		return &AccountError{Address: stakeAccountAddress, Epoch: epoch, Err: ErrAlreadyDeactivated}
+	}
+	return nil
+}
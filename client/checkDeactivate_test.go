@@ -0,0 +1,70 @@
+package client
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+
+	"github.com/skport/solana-rpc-client-extensions-go/types"
+)
+
+func TestCheckDeactivate(t *testing.T) {
+	// Without stake history, a delegation is fully active from the epoch after its activation.
+	history := genStakeHistory(rand.New(rand.NewSource(0)), 10, 0)
+
+	initialized := newTestStakeAccount(1002282880, 0, 0, 0)
+	initialized.Data.Parsed.Type = "initialized"
+	initialized.Data.Parsed.Info.Stake = nil
+
+	redelegatedActivating := newTestStakeAccount(1002282880, 1000000000, 10, types.MaxEpoch)
+	redelegatedActivating.Data.Parsed.Info.Stake.Flags = types.StakeFlagMustFullyActivateBeforeDeactivationIsPermitted
+
+	redelegatedActive := newTestStakeAccount(1002282880, 1000000000, 0, types.MaxEpoch)
+	redelegatedActive.Data.Parsed.Info.Stake.Flags = types.StakeFlagMustFullyActivateBeforeDeactivationIsPermitted
+
+	tests := []struct {
+		name         string
+		stakeAccount *types.StakeAccount
+		wantErr      error
+	}{
+		{
+			name:         "active",
+			stakeAccount: newTestStakeAccount(1002282880, 1000000000, 0, types.MaxEpoch),
+			wantErr:      nil,
+		},
+		{
+			name:         "activating",
+			stakeAccount: newTestStakeAccount(1002282880, 1000000000, 10, types.MaxEpoch),
+			wantErr:      nil,
+		},
+		{
+			name:         "already deactivated",
+			stakeAccount: newTestStakeAccount(1002282880, 1000000000, 0, 5),
+			wantErr:      ErrAlreadyDeactivated,
+		},
+		{
+			name:         "not delegated",
+			stakeAccount: initialized,
+			wantErr:      ErrNotDelegated,
+		},
+		{
+			name:         "redelegated, activating",
+			stakeAccount: redelegatedActivating,
+			wantErr:      ErrRedelegatedStakeMustFullyActivate,
+		},
+		{
+			name:         "redelegated, fully active",
+			stakeAccount: redelegatedActive,
+			wantErr:      nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckDeactivate("", 10, tt.stakeAccount, history)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("CheckDeactivate error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
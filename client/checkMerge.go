@@ -0,0 +1,126 @@
+package client
+
+import (
+	"github.com/skport/solana-rpc-client-extensions-go/types"
+
+	"golang.org/x/xerrors"
+)
+
+// MergeKind is the classification of a stake account by the Merge instruction.
+// https://github.com/anza-xyz/agave/blob/v2.0.0/programs/stake/src/stake_state.rs#L1297
+type MergeKind string
+
+const (
+	// MergeKindInactive is an initialized account, or a delegation without any effective, activating or deactivating stake.
+	MergeKindInactive MergeKind = "inactive"
+	// MergeKindActivationEpoch is a delegation which is only activating.
+	MergeKindActivationEpoch MergeKind = "activationEpoch"
+	// MergeKindFullyActive is a delegation which is fully effective.
+	MergeKindFullyActive MergeKind = "fullyActive"
+)
+
+type CheckMergeResponse struct {
+	DestinationKind MergeKind `json:"destinationKind"`
+	SourceKind      MergeKind `json:"sourceKind"`
+}
+
+// CheckMerge reports whether source can be merged into destination at epoch and unixTimestamp (of the Clock sysvar),
+// and the merge kinds of both. Otherwise an error wrapping ErrMergeTransientStake or ErrMergeMismatch explains why the stake program would reject it.
+func CheckMerge(epoch uint64, unixTimestamp int64, destination *types.StakeAccount, source *types.StakeAccount, stakeHistoryAccount *types.StakeHistoryAccount) (*CheckMergeResponse, error) {
+	destinationKind, err := getMergeKind(epoch, destination, stakeHistoryAccount)
+	if err != nil {
+		return nil, xerrors.Errorf("destination: %w", err)
+	}
+	sourceKind, err := getMergeKind(epoch, source, stakeHistoryAccount)
+	if err != nil {
+		return nil, xerrors.Errorf("source: %w", err)
+	}
+
+	if err := checkMetasCanMerge(epoch, unixTimestamp, destination.GetInfoMeta(), source.GetInfoMeta()); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case destinationKind == MergeKindInactive && sourceKind == MergeKindInactive:
+	case destinationKind == MergeKindInactive && sourceKind == MergeKindActivationEpoch:
+	case destinationKind == MergeKindActivationEpoch && sourceKind == MergeKindInactive:
+	case destinationKind == MergeKindActivationEpoch && sourceKind == MergeKindActivationEpoch,
+		destinationKind == MergeKindFullyActive && sourceKind == MergeKindFullyActive:
+		if err := checkDelegationsCanMerge(destination, source); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, xerrors.Errorf("%w: destination: %s, source: %s", ErrMergeMismatch, destinationKind, sourceKind)
+	}
+
+	return &CheckMergeResponse{
+		DestinationKind: destinationKind,
+		SourceKind:      sourceKind,
+	}, nil
+}
+
+func getMergeKind(epoch uint64, stakeAccount *types.StakeAccount, stakeHistoryAccount *types.StakeHistoryAccount) (MergeKind, error) {
+	state, err := stakeAccount.GetState()
+	if err != nil {
+		return "", xerrors.Errorf("wrap: %w", err)
+	}
+
+	switch state {
+	case types.StakeStateInitialized:
+		return MergeKindInactive, nil
+	case types.StakeStateStake:
+	default:
+		return "", xerrors.Errorf("%w: state: %s", ErrNotStakeAccount, state)
+	}
+
+	effective, activating, deactivating, err := getSolanaStakeActivatingAndDeactivating("", stakeAccount, epoch, stakeHistoryAccount)
+	if err != nil {
+		return "", xerrors.Errorf("wrap: %w", err)
+	}
+	switch {
+	case effective == 0 && activating == 0 && deactivating == 0:
+		return MergeKindInactive, nil
+	case effective == 0:
+		return MergeKindActivationEpoch, nil
+	case activating == 0 && deactivating == 0:
+		return MergeKindFullyActive, nil
+	default:
+		return "", xerrors.Errorf("%w: effective: %d, activating: %d, deactivating: %d", ErrMergeTransientStake, effective, activating, deactivating)
+	}
+}
+
+// https://github.com/anza-xyz/agave/blob/v2.0.0/programs/stake/src/stake_state.rs#L1355
+func checkMetasCanMerge(epoch uint64, unixTimestamp int64, destination types.StakeAccountInfoMeta, source types.StakeAccountInfoMeta) error {
+	if destination.Authorized != source.Authorized {
+		return xerrors.Errorf("%w: authorities differ", ErrMergeMismatch)
+	}
+	// Lockups may differ once both have expired.
+	if destination.Lockup != source.Lockup && (isLockupInForce(epoch, unixTimestamp, destination) || isLockupInForce(epoch, unixTimestamp, source)) {
+		return xerrors.Errorf("%w: lockups differ and are in force", ErrMergeMismatch)
+	}
+	return nil
+}
+
+func isLockupInForce(epoch uint64, unixTimestamp int64, meta types.StakeAccountInfoMeta) bool {
+	return meta.Lockup.UnixTimestamp > uint64(unixTimestamp) || meta.Lockup.Epoch > epoch
+}
+
+// https://github.com/anza-xyz/agave/blob/v2.0.0/programs/stake/src/stake_state.rs#L1385
+func checkDelegationsCanMerge(destination *types.StakeAccount, source *types.StakeAccount) error {
+	d, err := destination.GetInfoStake()
+	if err != nil {
+		return xerrors.Errorf("destination: %w", err)
+	}
+	s, err := source.GetInfoStake()
+	if err != nil {
+		return xerrors.Errorf("source: %w", err)
+	}
+
+	if d.Delegation.Voter != s.Delegation.Voter {
+		return xerrors.Errorf("%w: voters differ, destination: %s, source: %s", ErrMergeMismatch, d.Delegation.Voter, s.Delegation.Voter)
+	}
+	if d.Delegation.IsDeactivating() || s.Delegation.IsDeactivating() {
+		return xerrors.Errorf("%w: stake is deactivated", ErrMergeMismatch)
+	}
+	return nil
+}
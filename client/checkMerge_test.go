@@ -0,0 +1,118 @@
+package client
+
+import (
+	"errors"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/skport/solana-rpc-client-extensions-go/types"
+)
+
+func TestCheckMerge(t *testing.T) {
+	// Without stake history, a delegation is fully active from the epoch after its activation.
+	history := genStakeHistory(rand.New(rand.NewSource(0)), 10, 0)
+
+	initialized := newTestStakeAccount(1002282880, 0, 0, 0)
+	initialized.Data.Parsed.Type = "initialized"
+	initialized.Data.Parsed.Info.Stake = nil
+
+	active := newTestStakeAccount(1002282880, 1000000000, 0, types.MaxEpoch)
+	activating := newTestStakeAccount(1002282880, 1000000000, 10, types.MaxEpoch)
+	deactivating := newTestStakeAccount(1002282880, 1000000000, 0, 10)
+	cooledDown := newTestStakeAccount(1002282880, 1000000000, 0, 5)
+
+	otherVoter := newTestStakeAccount(1002282880, 1000000000, 0, types.MaxEpoch)
+	otherVoter.Data.Parsed.Info.Stake.Delegation.Voter = "J2nUHEAgZFRyuJbFjdqPrAa9gyWDuc7hErtDQHPhsYRp"
+
+	otherStaker := newTestStakeAccount(1002282880, 1000000000, 0, types.MaxEpoch)
+	otherStaker.Data.Parsed.Info.Meta.Authorized.Staker = testStakeAuthorityAddr
+
+	lockedUp := newTestStakeAccount(1002282880, 1000000000, 0, types.MaxEpoch)
+	lockedUp.Data.Parsed.Info.Meta.Lockup.Epoch = 20
+
+	lockupExpired := newTestStakeAccount(1002282880, 1000000000, 0, types.MaxEpoch)
+	lockupExpired.Data.Parsed.Info.Meta.Lockup.Epoch = 5
+
+	tests := []struct {
+		name        string
+		destination *types.StakeAccount
+		source      *types.StakeAccount
+		want        *CheckMergeResponse
+		wantErr     error
+	}{
+		{
+			name:        "inactive + inactive",
+			destination: initialized,
+			source:      cooledDown,
+			want:        &CheckMergeResponse{DestinationKind: MergeKindInactive, SourceKind: MergeKindInactive},
+		},
+		{
+			name:        "inactive + activating",
+			destination: initialized,
+			source:      activating,
+			want:        &CheckMergeResponse{DestinationKind: MergeKindInactive, SourceKind: MergeKindActivationEpoch},
+		},
+		{
+			name:        "activating + activating",
+			destination: activating,
+			source:      activating,
+			want:        &CheckMergeResponse{DestinationKind: MergeKindActivationEpoch, SourceKind: MergeKindActivationEpoch},
+		},
+		{
+			name:        "fully active + fully active",
+			destination: active,
+			source:      active,
+			want:        &CheckMergeResponse{DestinationKind: MergeKindFullyActive, SourceKind: MergeKindFullyActive},
+		},
+		{
+			name:        "fully active + activating",
+			destination: active,
+			source:      activating,
+			wantErr:     ErrMergeMismatch,
+		},
+		{
+			name:        "deactivating",
+			destination: active,
+			source:      deactivating,
+			wantErr:     ErrMergeTransientStake,
+		},
+		{
+			name:        "voters differ",
+			destination: active,
+			source:      otherVoter,
+			wantErr:     ErrMergeMismatch,
+		},
+		{
+			name:        "authorities differ",
+			destination: active,
+			source:      otherStaker,
+			wantErr:     ErrMergeMismatch,
+		},
+		{
+			name:        "lockup in force",
+			destination: active,
+			source:      lockedUp,
+			wantErr:     ErrMergeMismatch,
+		},
+		{
+			name:        "lockup expired",
+			destination: active,
+			source:      lockupExpired,
+			want:        &CheckMergeResponse{DestinationKind: MergeKindFullyActive, SourceKind: MergeKindFullyActive},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CheckMerge(10, 1700000000, tt.destination, tt.source, history)
+
+			if !reflect.DeepEqual(tt.want, got) {
+				t.Errorf("CheckMerge = %+v, want %+v", got, tt.want)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("CheckMerge error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package client
+
+import (
+	"github.com/skport/solana-rpc-client-extensions-go/types"
+
+	"golang.org/x/xerrors"
+)
+
+type SplitRequest struct {
+	// Lamports to move to the destination
+	Lamports uint64
+	// DestinationLamports is the balance of the destination before the split, as the destination of an active stake must be prefunded for rent.
+	DestinationLamports uint64
+	// DestinationRentExemptReserve is the rent-exempt minimum balance of the destination (200 bytes for a stake account).
+	DestinationRentExemptReserve uint64
+	// MinimumDelegation of the cluster, as returned by getStakeMinimumDelegation.
+	MinimumDelegation uint64
+}
+
+// CheckSplitResponse is the balance and delegated stake of both accounts after the split.
+type CheckSplitResponse struct {
+	SourceLamports      uint64 `json:"sourceLamports"`
+	SourceStake         uint64 `json:"sourceStake"`
+	DestinationLamports uint64 `json:"destinationLamports"`
+	DestinationStake    uint64 `json:"destinationStake"`
+}
+
+// CheckSplit reports whether req.Lamports can be split from stakeAccount at epoch, leaving both sides rent-exempt
+// and above the minimum delegation. Otherwise an error wrapping ErrInsufficientFunds, ErrInsufficientDelegation or
+// ErrInsufficientStake explains why the stake program would reject it.
+// https://github.com/anza-xyz/agave/blob/v2.0.0/programs/stake/src/stake_state.rs#L715
+func CheckSplit(epoch uint64, stakeAccount *types.StakeAccount, stakeHistoryAccount *types.StakeHistoryAccount, req SplitRequest) (*CheckSplitResponse, error) {
+	state, err := stakeAccount.GetState()
+	if err != nil {
+		return nil, xerrors.Errorf("wrap: %w", err)
+	}
+
+	switch state {
+	case types.StakeStateUninitialized:
+		if req.Lamports > stakeAccount.Lamports {
+			return nil, xerrors.Errorf("%w: lamports: %d, split: %d", ErrInsufficientFunds, stakeAccount.Lamports, req.Lamports)
+		}
+		return &CheckSplitResponse{
+			SourceLamports:      stakeAccount.Lamports - req.Lamports,
+			DestinationLamports: req.DestinationLamports + req.Lamports,
+		}, nil
+	case types.StakeStateInitialized:
+		if _, err := validateSplitAmount(stakeAccount, req, 0, false); err != nil {
+			return nil, err
+		}
+		return &CheckSplitResponse{
+			SourceLamports:      stakeAccount.Lamports - req.Lamports,
+			DestinationLamports: req.DestinationLamports + req.Lamports,
+		}, nil
+	case types.StakeStateStake:
+	default:
+		return nil, xerrors.Errorf("%w: state: %s", ErrNotStakeAccount, state)
+	}
+
+	stakeInfo, err := stakeAccount.GetInfoStake()
+	if err != nil {
+		return nil, xerrors.Errorf("wrap: %w", err)
+	}
+	rentExemptReserve, err := stakeAccount.GetRentExemptReserve()
+	if err != nil {
+		return nil, xerrors.Errorf("wrap: %w", err)
+	}
+	effective, _, _, err := getSolanaStakeActivatingAndDeactivating("", stakeAccount, epoch, stakeHistoryAccount)
+	if err != nil {
+		return nil, xerrors.Errorf("wrap: %w", err)
+	}
+	isActive := effective > 0
+
+	// The minimum delegation is required of a delegated source whether or not its stake is active yet.
+	sourceRemainingBalance, err := validateSplitAmount(stakeAccount, req, req.MinimumDelegation, isActive)
+	if err != nil {
+		return nil, err
+	}
+
+	stake := stakeInfo.Delegation.Stake
+	var remainingStakeDelta, splitStakeAmount uint64
+	if sourceRemainingBalance == 0 {
+		// Full split, the rent-exempt reserve of the source is not delegated.
+		remainingStakeDelta = saturatingSub(req.Lamports, rentExemptReserve)
+		splitStakeAmount = remainingStakeDelta
+	} else {
+		if saturatingSub(stake, req.Lamports) < req.MinimumDelegation {
+			return nil, xerrors.Errorf("%w: remaining stake: %d, minimum: %d", ErrInsufficientDelegation, saturatingSub(stake, req.Lamports), req.MinimumDelegation)
+		}
+		remainingStakeDelta = req.Lamports
+		splitStakeAmount = saturatingSub(req.Lamports, saturatingSub(req.DestinationRentExemptReserve, req.DestinationLamports))
+	}
+	if splitStakeAmount < req.MinimumDelegation {
+		return nil, xerrors.Errorf("%w: split stake: %d, minimum: %d", ErrInsufficientDelegation, splitStakeAmount, req.MinimumDelegation)
+	}
+	if remainingStakeDelta > stake {
+		return nil, xerrors.Errorf("%w: stake: %d, split: %d", ErrInsufficientStake, stake, remainingStakeDelta)
+	}
+
+	return &CheckSplitResponse{
+		SourceLamports:      sourceRemainingBalance,
+		SourceStake:         stake - remainingStakeDelta,
+		DestinationLamports: req.DestinationLamports + req.Lamports,
+		DestinationStake:    splitStakeAmount,
+	}, nil
+}
+
+// validateSplitAmount returns the remaining balance of the source.
+// https://github.com/anza-xyz/agave/blob/v2.0.0/programs/stake/src/stake_state.rs#L1213
+func validateSplitAmount(stakeAccount *types.StakeAccount, req SplitRequest, additionalRequiredLamports uint64, sourceIsActive bool) (uint64, error) {
+	if req.Lamports == 0 || req.Lamports > stakeAccount.Lamports {
+		return 0, xerrors.Errorf("%w: lamports: %d, split: %d", ErrInsufficientFunds, stakeAccount.Lamports, req.Lamports)
+	}
+	rentExemptReserve, err := stakeAccount.GetRentExemptReserve()
+	if err != nil {
+		return 0, xerrors.Errorf("wrap: %w", err)
+	}
+
+	sourceMinimumBalance := rentExemptReserve + additionalRequiredLamports
+	sourceRemainingBalance := stakeAccount.Lamports - req.Lamports
+	if sourceRemainingBalance != 0 && sourceRemainingBalance < sourceMinimumBalance {
+		return 0, xerrors.Errorf("%w: source remaining balance: %d, minimum: %d", ErrInsufficientFunds, sourceRemainingBalance, sourceMinimumBalance)
+	}
+
+	destinationMinimumBalance := req.DestinationRentExemptReserve + additionalRequiredLamports
+	if deficit := saturatingSub(destinationMinimumBalance, req.DestinationLamports); req.Lamports < deficit {
+		return 0, xerrors.Errorf("%w: destination balance deficit: %d, split: %d", ErrInsufficientFunds, deficit, req.Lamports)
+	}
+
+	// The destination of a partial split of an active stake must be prefunded for rent.
+	if sourceIsActive && sourceRemainingBalance != 0 && req.DestinationLamports < req.DestinationRentExemptReserve {
+		return 0, xerrors.Errorf("%w: destination is not prefunded for rent, lamports: %d, rent-exempt reserve: %d", ErrInsufficientFunds, req.DestinationLamports, req.DestinationRentExemptReserve)
+	}
+	return sourceRemainingBalance, nil
+}
+
+func saturatingSub(x uint64, y uint64) uint64 {
+	if x < y {
+		return 0
+	}
+	return x - y
+}
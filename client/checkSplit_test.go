@@ -0,0 +1,112 @@
+package client
+
+import (
+	"errors"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/skport/solana-rpc-client-extensions-go/types"
+)
+
+func TestCheckSplit(t *testing.T) {
+	const minimumDelegation = 1000000000
+
+	// Without stake history, a delegation is fully active from the epoch after its activation.
+	history := genStakeHistory(rand.New(rand.NewSource(0)), 10, 0)
+
+	active := newTestStakeAccount(10002282880, 10000000000, 0, types.MaxEpoch)
+	cooledDown := newTestStakeAccount(10002282880, 10000000000, 0, 5)
+
+	initialized := newTestStakeAccount(10002282880, 0, 0, 0)
+	initialized.Data.Parsed.Type = "initialized"
+	initialized.Data.Parsed.Info.Stake = nil
+
+	tests := []struct {
+		name         string
+		stakeAccount *types.StakeAccount
+		req          SplitRequest
+		want         *CheckSplitResponse
+		wantErr      error
+	}{
+		{
+			name:         "active, prefunded destination",
+			stakeAccount: active,
+			req:          SplitRequest{Lamports: 4000000000, DestinationLamports: testRentExemptReserve, DestinationRentExemptReserve: testRentExemptReserve, MinimumDelegation: minimumDelegation},
+			want:         &CheckSplitResponse{SourceLamports: 6002282880, SourceStake: 6000000000, DestinationLamports: 4002282880, DestinationStake: 4000000000},
+		},
+		{
+			name:         "active, destination not prefunded",
+			stakeAccount: active,
+			req:          SplitRequest{Lamports: 4000000000, DestinationRentExemptReserve: testRentExemptReserve, MinimumDelegation: minimumDelegation},
+			wantErr:      ErrInsufficientFunds,
+		},
+		{
+			name:         "active, full split",
+			stakeAccount: active,
+			req:          SplitRequest{Lamports: 10002282880, DestinationRentExemptReserve: testRentExemptReserve, MinimumDelegation: minimumDelegation},
+			want:         &CheckSplitResponse{SourceLamports: 0, SourceStake: 0, DestinationLamports: 10002282880, DestinationStake: 10000000000},
+		},
+		{
+			name:         "active, source below minimum delegation",
+			stakeAccount: active,
+			req:          SplitRequest{Lamports: 9500000000, DestinationLamports: testRentExemptReserve, DestinationRentExemptReserve: testRentExemptReserve, MinimumDelegation: minimumDelegation},
+			wantErr:      ErrInsufficientFunds,
+		},
+		{
+			name:         "active, destination below minimum delegation",
+			stakeAccount: active,
+			req:          SplitRequest{Lamports: 500000000, DestinationLamports: testRentExemptReserve, DestinationRentExemptReserve: testRentExemptReserve, MinimumDelegation: minimumDelegation},
+			wantErr:      ErrInsufficientFunds,
+		},
+		{
+			name:         "inactive, destination not prefunded",
+			stakeAccount: cooledDown,
+			req:          SplitRequest{Lamports: 4000000000, DestinationRentExemptReserve: testRentExemptReserve, MinimumDelegation: minimumDelegation},
+			want:         &CheckSplitResponse{SourceLamports: 6002282880, SourceStake: 6000000000, DestinationLamports: 4000000000, DestinationStake: 4000000000 - testRentExemptReserve},
+		},
+		{
+			name:         "inactive, source below minimum delegation",
+			stakeAccount: cooledDown,
+			req:          SplitRequest{Lamports: 9500000000, DestinationRentExemptReserve: testRentExemptReserve, MinimumDelegation: minimumDelegation},
+			wantErr:      ErrInsufficientFunds,
+		},
+		{
+			name:         "inactive, destination below minimum delegation",
+			stakeAccount: cooledDown,
+			req:          SplitRequest{Lamports: 500000000, DestinationRentExemptReserve: testRentExemptReserve, MinimumDelegation: minimumDelegation},
+			wantErr:      ErrInsufficientFunds,
+		},
+		{
+			name:         "initialized",
+			stakeAccount: initialized,
+			req:          SplitRequest{Lamports: 4000000000, DestinationRentExemptReserve: testRentExemptReserve, MinimumDelegation: minimumDelegation},
+			want:         &CheckSplitResponse{SourceLamports: 6002282880, DestinationLamports: 4000000000},
+		},
+		{
+			name:         "initialized, source not rent-exempt",
+			stakeAccount: initialized,
+			req:          SplitRequest{Lamports: 10001000000, DestinationRentExemptReserve: testRentExemptReserve, MinimumDelegation: minimumDelegation},
+			wantErr:      ErrInsufficientFunds,
+		},
+		{
+			name:         "zero lamports",
+			stakeAccount: active,
+			req:          SplitRequest{Lamports: 0, DestinationRentExemptReserve: testRentExemptReserve, MinimumDelegation: minimumDelegation},
+			wantErr:      ErrInsufficientFunds,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CheckSplit(10, tt.stakeAccount, history, tt.req)
+
+			if !reflect.DeepEqual(tt.want, got) {
+				t.Errorf("CheckSplit = %+v, want %+v", got, tt.want)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("CheckSplit error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -1,28 +1,17 @@
 package client
 
 import (
-	"context"
+	"flag"
 	"fmt"
-	"log"
 	"os"
 	"testing"
-
-	sdkRpc "github.com/blocto/solana-go-sdk/rpc"
-)
-
-var (
-	rpc sdkRpc.RpcClient
 )
 
 func TestMain(t *testing.M) {
-	rpc = sdkRpc.NewRpcClient(sdkRpc.DevnetRPCEndpoint)
-
-	ctx := context.Background()
-	r, err := rpc.GetVersion(ctx)
-	if err != nil {
-		log.Fatalf("failed to version info, err: %v", err)
+	flag.Parse()
+	if *record {
+		fmt.Println("recording JSON-RPC responses into", replayTestdataDir)
 	}
-	fmt.Println("solana core version", r.Result.SolanaCore)
 
 	os.Exit(t.Run())
 }
@@ -0,0 +1,85 @@
+package client
+
+import (
+	"fmt"
+
+	"github.com/blocto/solana-go-sdk/common"
+	"github.com/mr-tron/base58"
+
+	"golang.org/x/xerrors"
+)
+
+const (
+	// https://docs.anza.xyz/runtime/programs#stake-program
+	StakeProgramAddress = "Stake11111111111111111111111111111111111111"
+	// Seed prefix used by wallets and the CLI when creating stake accounts with `createAccountWithSeed` (stake:0, stake:1, ...)
+	DefaultStakeSeedPrefix = "stake:"
+)
+
+// CreateWithSeedAddress derives the address used by SystemProgram.createAccountWithSeed.
+// https://github.com/anza-xyz/agave/blob/v2.0.0/sdk/program/src/pubkey.rs#L200
+func CreateWithSeedAddress(baseAddress string, seed string, programAddress string) (string, error) {
+	base, err := decodePublicKey(baseAddress)
+	if err != nil {
+		return "", xerrors.Errorf("baseAddress: %s, wrap: %w", baseAddress, err)
+	}
+	program, err := decodePublicKey(programAddress)
+	if err != nil {
+		return "", xerrors.Errorf("programAddress: %s, wrap: %w", programAddress, err)
+	}
+	if len(seed) > common.MaxSeedLength {
+		return "", fmt.Errorf("seed is too long, seed: %s, length: %d, max: %d", seed, len(seed), common.MaxSeedLength)
+	}
+
+	return common.CreateWithSeed(base, seed, program).ToBase58(), nil
+}
+
+// CreateStakeAccountWithSeedAddress derives the address of a stake account created with `createAccountWithSeed`.
+func CreateStakeAccountWithSeedAddress(baseAddress string, seed string) (string, error) {
+	return CreateWithSeedAddress(baseAddress, seed, StakeProgramAddress)
+}
+
+// FindProgramAddress searches the bump seed from 255 downwards and returns the first off-curve program derived address.
+// https://github.com/anza-xyz/agave/blob/v2.0.0/sdk/program/src/pubkey.rs#L500
+func FindProgramAddress(seeds [][]byte, programAddress string) (string, uint8, error) {
+	program, err := decodePublicKey(programAddress)
+	if err != nil {
+		return "", 0, xerrors.Errorf("programAddress: %s, wrap: %w", programAddress, err)
+	}
+	// One seed slot is reserved for the bump seed.
+	if len(seeds) >= common.MaxSeed {
+		return "", 0, fmt.Errorf("too many seeds, count: %d, max: %d", len(seeds), common.MaxSeed-1)
+	}
+
+	address, bump, err := common.FindProgramAddress(seeds, program)
+	if err != nil {
+		return "", 0, xerrors.Errorf("programAddress: %s, wrap: %w", programAddress, err)
+	}
+	return address.ToBase58(), bump, nil
+}
+
+// CreateProgramAddress derives a program address from seeds that already include the bump seed.
+func CreateProgramAddress(seeds [][]byte, programAddress string) (string, error) {
+	program, err := decodePublicKey(programAddress)
+	if err != nil {
+		return "", xerrors.Errorf("programAddress: %s, wrap: %w", programAddress, err)
+	}
+
+	address, err := common.CreateProgramAddress(seeds, program)
+	if err != nil {
+		return "", xerrors.Errorf("programAddress: %s, wrap: %w", programAddress, err)
+	}
+	return address.ToBase58(), nil
+}
+
+// decodePublicKey is a strict version of common.PublicKeyFromString, which silently ignores invalid input.
+func decodePublicKey(address string) (common.PublicKey, error) {
+	b, err := base58.Decode(address)
+	if err != nil {
+		return common.PublicKey{}, xerrors.Errorf("failed to decode base58: %w", err)
+	}
+	if len(b) != common.PublicKeyLength {
+		return common.PublicKey{}, fmt.Errorf("invalid public key length: %d", len(b))
+	}
+	return common.PublicKeyFromBytes(b), nil
+}
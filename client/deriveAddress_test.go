@@ -0,0 +1,117 @@
+package client
+
+import (
+	"testing"
+)
+
+// Expected values are taken from the @solana/web3.js test suite.
+func TestCreateWithSeedAddress(t *testing.T) {
+	tests := []struct {
+		name           string
+		baseAddress    string
+		seed           string
+		programAddress string
+		want           string
+		wantErr        bool
+	}{
+		{
+			name:           "success 1",
+			baseAddress:    "11111111111111111111111111111111",
+			seed:           "limber chicken: 4/45",
+			programAddress: "11111111111111111111111111111111",
+			want:           "9h1HyLCW5dZnBVap8C5egQ9Z6pHyjsh5MNy83iPqqRuq",
+			wantErr:        false,
+		},
+		{
+			name:           "error 1: seed is too long",
+			baseAddress:    "11111111111111111111111111111111",
+			seed:           "123456789012345678901234567890123",
+			programAddress: "11111111111111111111111111111111",
+			want:           "",
+			wantErr:        true,
+		},
+		{
+			name:           "error 2: invalid base address",
+			baseAddress:    "invalid",
+			seed:           "stake:0",
+			programAddress: StakeProgramAddress,
+			want:           "",
+			wantErr:        true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CreateWithSeedAddress(tt.baseAddress, tt.seed, tt.programAddress)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CreateWithSeedAddress error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("CreateWithSeedAddress = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateProgramAddress(t *testing.T) {
+	tests := []struct {
+		name    string
+		seeds   [][]byte
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "success 1",
+			seeds:   [][]byte{[]byte(""), {1}},
+			want:    "3gF2KMe9KiC6FNVBmfg9i267aMPvK37FewCip4eGBFcT",
+			wantErr: false,
+		},
+		{
+			name:    "success 2",
+			seeds:   [][]byte{[]byte("☉")},
+			want:    "7ytmC1nT1xY4RfxCV2ZgyA7UakC93do5ZdyhdF3EtPj7",
+			wantErr: false,
+		},
+		{
+			name:    "error 1: seed is too long",
+			seeds:   [][]byte{make([]byte, 33)},
+			want:    "",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CreateProgramAddress(tt.seeds, "BPFLoader1111111111111111111111111111111111")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CreateProgramAddress error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("CreateProgramAddress = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindProgramAddress(t *testing.T) {
+	programAddress := "BPFLoader1111111111111111111111111111111111"
+	seeds := [][]byte{[]byte("")}
+
+	address, bump, err := FindProgramAddress(seeds, programAddress)
+	if err != nil {
+		t.Fatalf("FindProgramAddress error: %v", err)
+	}
+
+	// The found address must be reproducible with the bump seed.
+	want, err := CreateProgramAddress(append(seeds, []byte{bump}), programAddress)
+	if err != nil {
+		t.Fatalf("CreateProgramAddress error: %v", err)
+	}
+	if address != want {
+		t.Errorf("FindProgramAddress = %v, want %v", address, want)
+	}
+
+	if _, _, err := FindProgramAddress(make([][]byte, 16), programAddress); err == nil {
+		t.Errorf("FindProgramAddress error = nil, want too many seeds")
+	}
+}
@@ -0,0 +1,414 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	sdkRpc "github.com/blocto/solana-go-sdk/rpc"
+	"github.com/skport/solana-rpc-client-extensions-go/types"
+
+	"golang.org/x/sync/singleflight"
+	"golang.org/x/xerrors"
+)
+
+// CacheOption configures NewEpochCache.
+type CacheOption func(*cacheOptions)
+
+type cacheOptions struct {
+	dir string
+}
+
+// WithCacheDir persists cached values to dir, so that a restarted process doesn't fetch them again.
+//
+//	<dir>/<cluster>/<epoch>/<name>.json
+func WithCacheDir(dir string) CacheOption {
+	return func(o *cacheOptions) {
+		o.dir = dir
+	}
+}
+
+// EpochCache caches values which only change at epoch boundaries, keyed by cluster, epoch and name.
+// When a value of a newer epoch is requested for a cluster, the values of older epochs are dropped.
+// It is safe for concurrent use, and concurrent fetches of the same value are deduplicated.
+type EpochCache struct {
+	dir string
+
+	mu      sync.Mutex
+	epochs  map[string]uint64
+	entries map[epochCacheKey]json.RawMessage
+	group   singleflight.Group
+}
+
+type epochCacheKey struct {
+	cluster string
+	epoch   uint64
+	name    string
+}
+
+func (k epochCacheKey) String() string {
+	return fmt.Sprintf("%s/%d/%s", k.cluster, k.epoch, k.name)
+}
+
+func NewEpochCache(opts ...CacheOption) *EpochCache {
+	o := &cacheOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return &EpochCache{
+		dir:     o.dir,
+		epochs:  map[string]uint64{},
+		entries: map[epochCacheKey]json.RawMessage{},
+	}
+}
+
+// Get returns the value of name in cluster at epoch, and calls fetch on a miss.
+// A nil value, e.g. of an account that doesn't exist, is not cached.
+func (c *EpochCache) Get(ctx context.Context, cluster string, epoch uint64, name string, fetch func(ctx context.Context) (json.RawMessage, error)) (json.RawMessage, error) {
+	if filepath.Base(cluster) != cluster || filepath.Base(name) != name {
+		return nil, fmt.Errorf("invalid cache key, cluster: %s, name: %s", cluster, name)
+	}
+	key := epochCacheKey{cluster: cluster, epoch: epoch, name: name}
+
+	c.Advance(cluster, epoch)
+	if value, ok := c.load(key); ok {
+		return value, nil
+	}
+
+	// The fetch is shared by the concurrent callers, so it isn't canceled with the caller which started it.
+	// Each caller stops waiting when its own ctx is done.
+	ch := c.group.DoChan(key.String(), func() (interface{}, error) {
+		if value, ok := c.load(key); ok {
+			return value, nil
+		}
+		value, err := fetch(withoutCancel{ctx})
+		if err != nil {
+			return nil, err
+		}
+		if value != nil {
+			c.store(key, value)
+		}
+		return value, nil
+	})
+	select {
+	case r := <-ch:
+		if r.Err != nil {
+			return nil, xerrors.Errorf("cache: %s, wrap: %w", key, r.Err)
+		}
+		return r.Val.(json.RawMessage), nil
+	case <-ctx.Done():
+		return nil, xerrors.Errorf("cache: %s, wrap: %w", key, ctx.Err())
+	}
+}
+
+// withoutCancel is a context with the values of its parent which is never canceled, like context.WithoutCancel of Go 1.21.
+type withoutCancel struct {
+	parent context.Context
+}
+
+func (withoutCancel) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (withoutCancel) Done() <-chan struct{}       { return nil }
+func (withoutCancel) Err() error                  { return nil }
+func (c withoutCancel) Value(key any) any         { return c.parent.Value(key) }
+
+// Delete drops the value of name in cluster at epoch, e.g. when it turned out to be stale.
+func (c *EpochCache) Delete(cluster string, epoch uint64, name string) {
+	key := epochCacheKey{cluster: cluster, epoch: epoch, name: name}
+
+	c.mu.Lock()
+	delete(c.entries, key)
+	c.mu.Unlock()
+
+	if c.dir != "" {
+		_ = os.Remove(c.path(key))
+	}
+}
+
+// Advance records that cluster has reached epoch, and drops the values of older epochs.
+func (c *EpochCache) Advance(cluster string, epoch uint64) {
+	c.mu.Lock()
+	if current, ok := c.epochs[cluster]; ok && current >= epoch {
+		c.mu.Unlock()
+		return
+	}
+	c.epochs[cluster] = epoch
+	for key := range c.entries {
+		if key.cluster == cluster && key.epoch < epoch {
+			delete(c.entries, key)
+		}
+	}
+	c.mu.Unlock()
+
+	if c.dir != "" {
+		c.removeOlderEpochs(cluster, epoch)
+	}
+}
+
+// epoch returns the newest epoch cluster has been advanced to.
+func (c *EpochCache) epoch(cluster string) (uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	epoch, ok := c.epochs[cluster]
+	return epoch, ok
+}
+
+func (c *EpochCache) load(key epochCacheKey) (json.RawMessage, bool) {
+	c.mu.Lock()
+	value, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok || c.dir == "" {
+		return value, ok
+	}
+
+	b, err := os.ReadFile(c.path(key))
+	if err != nil || !json.Valid(b) {
+		return nil, false
+	}
+	c.mu.Lock()
+	c.entries[key] = b
+	c.mu.Unlock()
+	return b, true
+}
+
+func (c *EpochCache) store(key epochCacheKey, value json.RawMessage) {
+	c.mu.Lock()
+	// Values of an epoch which has already passed are not kept.
+	current := key.epoch >= c.epochs[key.cluster]
+	if current {
+		c.entries[key] = value
+	}
+	c.mu.Unlock()
+
+	if current && c.dir != "" {
+		// Persistence is best-effort, the value is fetched again after a restart if it fails.
+		_ = writeFileAtomic(c.path(key), value)
+	}
+}
+
+func (c *EpochCache) path(key epochCacheKey) string {
+	return filepath.Join(c.dir, key.cluster, strconv.FormatUint(key.epoch, 10), key.name+".json")
+}
+
+func (c *EpochCache) removeOlderEpochs(cluster string, epoch uint64) {
+	dirs, err := os.ReadDir(filepath.Join(c.dir, cluster))
+	if err != nil {
+		return
+	}
+	for _, dir := range dirs {
+		if e, err := strconv.ParseUint(dir.Name(), 10, 64); err == nil && e < epoch {
+			_ = os.RemoveAll(filepath.Join(c.dir, cluster, dir.Name()))
+		}
+	}
+}
+
+func writeFileAtomic(path string, b []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+const (
+	cacheNameStakeMinimumDelegation = "stakeMinimumDelegation"
+	cacheNameVoteAccounts           = "voteAccounts"
+)
+
+// epochScopedAccounts are the sysvars which only change at epoch boundaries.
+var epochScopedAccounts = map[string]bool{
+	StakeHistoryAccountAddress:  true,
+	EpochScheduleAccountAddress: true,
+}
+
+// CachingAccountSource serves the epoch-scoped data of source from an EpochCache: the StakeHistory and EpochSchedule sysvars,
+// the minimum delegation, and getVoteAccounts. Other accounts are fetched from source on every call.
+//
+// Cached values are served for the epoch of the last GetEpoch call for the cluster, without fetching the epoch again,
+// so that a cache hit doesn't cost a request. GetEpoch advances the cache to a new epoch, as GetStakeActivationFromSource
+// calls it first. Before the first GetEpoch call, the epoch is fetched once.
+//
+// Vote accounts are cached for their activated stake, which is fixed for an epoch. Their votes and credits are stale within the epoch.
+type CachingAccountSource struct {
+	source  AccountSource
+	cluster string
+	cache   *EpochCache
+}
+
+// NewCachingAccountSource caches the data of source in cache under cluster, e.g. "mainnet-beta".
+// A cache can be shared by the sources of several clusters.
+func NewCachingAccountSource(source AccountSource, cluster string, cache *EpochCache) *CachingAccountSource {
+	return &CachingAccountSource{
+		source:  source,
+		cluster: cluster,
+		cache:   cache,
+	}
+}
+
+func (s *CachingAccountSource) GetAccount(ctx context.Context, address string) (json.RawMessage, error) {
+	if !epochScopedAccounts[address] {
+		return s.source.GetAccount(ctx, address)
+	}
+
+	epoch, err := s.epoch(ctx)
+	if err != nil {
+		return nil, xerrors.Errorf("wrap: %w", err)
+	}
+	raw, err := s.cache.Get(ctx, s.cluster, epoch, address, func(ctx context.Context) (json.RawMessage, error) {
+		return s.source.GetAccount(ctx, address)
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("wrap: %w", err)
+	}
+	if !isCurrentSysvar(address, raw, epoch) {
+		s.cache.Delete(s.cluster, epoch, address)
+	}
+	return raw, nil
+}
+
+// GetMultipleAccounts serves the cached sysvars from the cache, and fetches the rest of addresses in one request.
+func (s *CachingAccountSource) GetMultipleAccounts(ctx context.Context, addresses []string) ([]json.RawMessage, error) {
+	var epoch uint64
+	for _, address := range addresses {
+		if epochScopedAccounts[address] {
+			var err error
+			if epoch, err = s.epoch(ctx); err != nil {
+				return nil, xerrors.Errorf("wrap: %w", err)
+			}
+			break
+		}
+	}
+
+	accounts := make([]json.RawMessage, len(addresses))
+	var uncached []string
+	var indexes []int
+	for i, address := range addresses {
+		if epochScopedAccounts[address] {
+			if account, ok := s.cache.load(epochCacheKey{cluster: s.cluster, epoch: epoch, name: address}); ok {
+				accounts[i] = account
+				continue
+			}
+		}
+		uncached = append(uncached, address)
+		indexes = append(indexes, i)
+	}
+	if len(uncached) == 0 {
+		return accounts, nil
+	}
+
+	fetched, err := s.source.GetMultipleAccounts(ctx, uncached)
+	if err != nil {
+		return nil, xerrors.Errorf("wrap: %w", err)
+	}
+	if len(fetched) != len(uncached) {
+		return nil, fmt.Errorf("GetMultipleAccounts returned %d accounts, want %d", len(fetched), len(uncached))
+	}
+	for i, account := range fetched {
+		accounts[indexes[i]] = account
+		if address := uncached[i]; epochScopedAccounts[address] && account != nil && isCurrentSysvar(address, account, epoch) {
+			s.cache.store(epochCacheKey{cluster: s.cluster, epoch: epoch, name: address}, account)
+		}
+	}
+	return accounts, nil
+}
+
+// isCurrentSysvar reports whether the sysvar raw can be cached for epoch.
+// The StakeHistory sysvar may have been fetched from a node which has not reached the epoch yet.
+func isCurrentSysvar(address string, raw json.RawMessage, epoch uint64) bool {
+	if address != StakeHistoryAccountAddress {
+		return true
+	}
+	stakeHistoryAccount, err := types.DecodeStakeHistoryAccount(raw)
+	return err != nil || checkStakeHistoryAccount(stakeHistoryAccount, epoch) == nil
+}
+
+// epoch returns the epoch of the last GetEpoch call, and only fetches it before the first one.
+func (s *CachingAccountSource) epoch(ctx context.Context) (uint64, error) {
+	if epoch, ok := s.cache.epoch(s.cluster); ok {
+		return epoch, nil
+	}
+	return s.GetEpoch(ctx)
+}
+
+// GetEpoch is not cached, and advances the cache to the epoch.
+func (s *CachingAccountSource) GetEpoch(ctx context.Context) (uint64, error) {
+	epoch, err := s.source.GetEpoch(ctx)
+	if err != nil {
+		return 0, err
+	}
+	s.cache.Advance(s.cluster, epoch)
+	return epoch, nil
+}
+
+func (s *CachingAccountSource) GetStakeMinimumDelegation(ctx context.Context) (uint64, error) {
+	minimumDelegationSource, ok := s.source.(MinimumDelegationSource)
+	if !ok {
+		return 0, xerrors.Errorf("%T doesn't implement MinimumDelegationSource", s.source)
+	}
+
+	var minimumDelegation uint64
+	err := s.getCached(ctx, cacheNameStakeMinimumDelegation, &minimumDelegation, func(ctx context.Context) (any, error) {
+		return minimumDelegationSource.GetStakeMinimumDelegation(ctx)
+	})
+	return minimumDelegation, err
+}
+
+func (s *CachingAccountSource) GetVoteAccounts(ctx context.Context) (*sdkRpc.GetVoteAccounts, error) {
+	voteAccountsSource, ok := s.source.(VoteAccountsSource)
+	if !ok {
+		return nil, xerrors.Errorf("%T doesn't implement VoteAccountsSource", s.source)
+	}
+
+	var voteAccounts sdkRpc.GetVoteAccounts
+	err := s.getCached(ctx, cacheNameVoteAccounts, &voteAccounts, func(ctx context.Context) (any, error) {
+		return voteAccountsSource.GetVoteAccounts(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &voteAccounts, nil
+}
+
+// getCached unmarshals the cached value of name at the current epoch into v.
+func (s *CachingAccountSource) getCached(ctx context.Context, name string, v any, fetch func(ctx context.Context) (any, error)) error {
+	epoch, err := s.epoch(ctx)
+	if err != nil {
+		return xerrors.Errorf("wrap: %w", err)
+	}
+	raw, err := s.cache.Get(ctx, s.cluster, epoch, name, func(ctx context.Context) (json.RawMessage, error) {
+		value, err := fetch(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(value)
+	})
+	if err != nil {
+		return xerrors.Errorf("wrap: %w", err)
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return xerrors.Errorf("failed to unmarshal cached %s: %w", name, err)
+	}
+	return nil
+}
+
+var _ interface {
+	AccountSource
+	MinimumDelegationSource
+	VoteAccountsSource
+} = (*CachingAccountSource)(nil)
@@ -0,0 +1,445 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	sdkRpc "github.com/blocto/solana-go-sdk/rpc"
+	"github.com/skport/solana-rpc-client-extensions-go/types"
+)
+
+// countingAccountSource counts the fetches of each account and method.
+type countingAccountSource struct {
+	*MemoryAccountSource
+
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func newCountingAccountSource(source *MemoryAccountSource) *countingAccountSource {
+	return &countingAccountSource{MemoryAccountSource: source, counts: map[string]int{}}
+}
+
+func (s *countingAccountSource) count(name string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.counts[name]
+}
+
+func (s *countingAccountSource) inc(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.counts[name]++
+}
+
+func (s *countingAccountSource) GetAccount(ctx context.Context, address string) (json.RawMessage, error) {
+	s.inc(address)
+	return s.MemoryAccountSource.GetAccount(ctx, address)
+}
+
+func (s *countingAccountSource) GetMultipleAccounts(ctx context.Context, addresses []string) ([]json.RawMessage, error) {
+	accounts := make([]json.RawMessage, 0, len(addresses))
+	for _, address := range addresses {
+		account, err := s.GetAccount(ctx, address)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, account)
+	}
+	return accounts, nil
+}
+
+func (s *countingAccountSource) GetEpoch(ctx context.Context) (uint64, error) {
+	s.inc("getEpoch")
+	return s.MemoryAccountSource.GetEpoch(ctx)
+}
+
+func (s *countingAccountSource) GetStakeMinimumDelegation(ctx context.Context) (uint64, error) {
+	s.inc(cacheNameStakeMinimumDelegation)
+	return s.MemoryAccountSource.GetStakeMinimumDelegation(ctx)
+}
+
+func (s *countingAccountSource) GetVoteAccounts(ctx context.Context) (*sdkRpc.GetVoteAccounts, error) {
+	s.inc(cacheNameVoteAccounts)
+	return s.MemoryAccountSource.GetVoteAccounts(ctx)
+}
+
+func TestCachingAccountSource(t *testing.T) {
+	ctx := context.Background()
+
+	memory := NewMemoryAccountSource(10)
+	memory.SetAccount(StakeHistoryAccountAddress, genStakeHistory(rand.New(rand.NewSource(0)), 10, 5))
+	memory.SetAccount(testAddress2, newTestStakeAccount(10002282880, 10000000000, 0, types.MaxEpoch))
+	memory.SetStakeMinimumDelegation(1000000000)
+	memory.SetVoteAccounts(sdkRpc.GetVoteAccounts{Current: []sdkRpc.VoteAccount{{VotePubkey: testAddress5, ActivatedStake: 10000000000}}})
+	counting := newCountingAccountSource(memory)
+	source := NewCachingAccountSource(counting, "testnet", NewEpochCache())
+
+	for i := 0; i < 3; i++ {
+		if _, err := GetStakeActivationFromSource(ctx, source, testAddress2); err != nil {
+			t.Fatalf("GetStakeActivationFromSource error = %v", err)
+		}
+		if got, err := source.GetStakeMinimumDelegation(ctx); err != nil || got != 1000000000 {
+			t.Fatalf("GetStakeMinimumDelegation = %d, %v, want %d", got, err, 1000000000)
+		}
+		voteAccounts, err := source.GetVoteAccounts(ctx)
+		if err != nil {
+			t.Fatalf("GetVoteAccounts error = %v", err)
+		}
+		if len(voteAccounts.Current) != 1 || voteAccounts.Current[0].ActivatedStake != 10000000000 {
+			t.Fatalf("GetVoteAccounts = %+v", voteAccounts)
+		}
+	}
+
+	want := map[string]int{
+		StakeHistoryAccountAddress:      1,
+		testAddress2:                    3,
+		cacheNameStakeMinimumDelegation: 1,
+		cacheNameVoteAccounts:           1,
+		// Only GetStakeActivationFromSource fetches the epoch, the cached values are served for it.
+		"getEpoch": 3,
+	}
+	for name, count := range want {
+		if got := counting.count(name); got != count {
+			t.Errorf("fetches of %s = %d, want %d", name, got, count)
+		}
+	}
+
+	// A new epoch invalidates the cache.
+	memory.SetEpoch(11)
+	memory.SetAccount(StakeHistoryAccountAddress, genStakeHistory(rand.New(rand.NewSource(0)), 11, 5))
+	for i := 0; i < 2; i++ {
+		if _, err := GetStakeActivationFromSource(ctx, source, testAddress2); err != nil {
+			t.Fatalf("GetStakeActivationFromSource error = %v", err)
+		}
+	}
+	if got := counting.count(StakeHistoryAccountAddress); got != 2 {
+		t.Errorf("fetches of stake history after the epoch advanced = %d, want %d", got, 2)
+	}
+}
+
+func TestCachingAccountSource_GetMultipleAccounts(t *testing.T) {
+	ctx := context.Background()
+
+	epochScheduleAccount, err := os.ReadFile(testAccountSourceDir + "/" + EpochScheduleAccountAddress + ".json")
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+	memory := NewMemoryAccountSource(10)
+	memory.SetAccount(StakeHistoryAccountAddress, genStakeHistory(rand.New(rand.NewSource(0)), 10, 5))
+	memory.SetAccount(EpochScheduleAccountAddress, json.RawMessage(epochScheduleAccount))
+	memory.SetAccount(testAddress2, newTestStakeAccount(10002282880, 10000000000, 0, types.MaxEpoch))
+	counting := newCountingAccountSource(memory)
+	source := NewCachingAccountSource(counting, "testnet", NewEpochCache())
+
+	addresses := []string{StakeHistoryAccountAddress, testAddress2, EpochScheduleAccountAddress}
+	for i := 0; i < 3; i++ {
+		accounts, err := source.GetMultipleAccounts(ctx, addresses)
+		if err != nil {
+			t.Fatalf("GetMultipleAccounts error = %v", err)
+		}
+		for j, account := range accounts {
+			if account == nil {
+				t.Errorf("GetMultipleAccounts()[%d] = nil, want %s", j, addresses[j])
+			}
+		}
+	}
+
+	want := map[string]int{
+		StakeHistoryAccountAddress:  1,
+		EpochScheduleAccountAddress: 1,
+		testAddress2:                3,
+		"getEpoch":                  1,
+	}
+	for name, count := range want {
+		if got := counting.count(name); got != count {
+			t.Errorf("fetches of %s = %d, want %d", name, got, count)
+		}
+	}
+}
+
+func TestCachingAccountSource_StaleStakeHistory(t *testing.T) {
+	ctx := context.Background()
+
+	// The node serving the sysvar has not reached epoch 11 yet.
+	memory := NewMemoryAccountSource(11)
+	memory.SetAccount(StakeHistoryAccountAddress, genStakeHistory(rand.New(rand.NewSource(0)), 10, 5))
+	counting := newCountingAccountSource(memory)
+	source := NewCachingAccountSource(counting, "testnet", NewEpochCache())
+
+	for i := 0; i < 2; i++ {
+		if _, err := GetStakeHistoryAccount(ctx, source); err != nil {
+			t.Fatalf("GetStakeHistoryAccount error = %v", err)
+		}
+	}
+	if got := counting.count(StakeHistoryAccountAddress); got != 2 {
+		t.Errorf("fetches of stale stake history = %d, want %d", got, 2)
+	}
+
+	memory.SetAccount(StakeHistoryAccountAddress, genStakeHistory(rand.New(rand.NewSource(0)), 11, 5))
+	for i := 0; i < 2; i++ {
+		if _, err := GetStakeHistoryAccount(ctx, source); err != nil {
+			t.Fatalf("GetStakeHistoryAccount error = %v", err)
+		}
+	}
+	if got := counting.count(StakeHistoryAccountAddress); got != 3 {
+		t.Errorf("fetches of stake history = %d, want %d", got, 3)
+	}
+}
+
+func TestEpochCache_Get(t *testing.T) {
+	ctx := context.Background()
+	errFetch := errors.New("fetch error")
+
+	tests := []struct {
+		name      string
+		fetches   []uint64
+		values    []json.RawMessage
+		errs      []error
+		wantCalls int
+		wantErr   error
+	}{
+		{
+			name:      "hit",
+			fetches:   []uint64{10, 10, 10},
+			values:    []json.RawMessage{json.RawMessage(`1`)},
+			wantCalls: 1,
+		},
+		{
+			name:      "epoch advanced",
+			fetches:   []uint64{10, 11, 11},
+			values:    []json.RawMessage{json.RawMessage(`1`), json.RawMessage(`2`)},
+			wantCalls: 2,
+		},
+		{
+			name:      "past epoch is not cached",
+			fetches:   []uint64{11, 10, 10},
+			values:    []json.RawMessage{json.RawMessage(`2`), json.RawMessage(`1`), json.RawMessage(`1`)},
+			wantCalls: 3,
+		},
+		{
+			name:      "nil is not cached",
+			fetches:   []uint64{10, 10},
+			values:    []json.RawMessage{nil, nil},
+			wantCalls: 2,
+		},
+		{
+			name:      "error is not cached",
+			fetches:   []uint64{10, 10},
+			values:    []json.RawMessage{nil, json.RawMessage(`1`)},
+			errs:      []error{errFetch, nil},
+			wantCalls: 2,
+			wantErr:   errFetch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := NewEpochCache()
+			calls := 0
+			var firstErr error
+			for _, epoch := range tt.fetches {
+				got, err := cache.Get(ctx, "testnet", epoch, "value", func(ctx context.Context) (json.RawMessage, error) {
+					i := calls
+					calls++
+					if i < len(tt.errs) && tt.errs[i] != nil {
+						return nil, tt.errs[i]
+					}
+					return tt.values[i], nil
+				})
+				if err != nil {
+					if firstErr == nil {
+						firstErr = err
+					}
+					continue
+				}
+				if want := tt.values[calls-1]; !reflect.DeepEqual(got, want) {
+					t.Errorf("Get(%d) = %s, want %s", epoch, got, want)
+				}
+			}
+			if !errors.Is(firstErr, tt.wantErr) {
+				t.Errorf("Get error = %v, wantErr %v", firstErr, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("fetches = %d, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestEpochCache_Singleflight(t *testing.T) {
+	ctx := context.Background()
+	cache := NewEpochCache()
+
+	var calls int32
+	release := make(chan struct{})
+	fetch := func(ctx context.Context) (json.RawMessage, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return json.RawMessage(`1`), nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := cache.Get(ctx, "testnet", 10, "value", fetch); err != nil {
+				t.Errorf("Get error = %v", err)
+			}
+		}()
+	}
+	// Let the goroutines wait for the first fetch.
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Errorf("fetches = %d, want %d", got, 1)
+	}
+}
+
+func TestEpochCache_Singleflight_Cancel(t *testing.T) {
+	cache := NewEpochCache()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	fetch := func(ctx context.Context) (json.RawMessage, error) {
+		close(started)
+		select {
+		case <-release:
+			return json.RawMessage(`1`), nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	// The caller which starts the fetch cancels while another one waits for it.
+	first, cancel := context.WithCancel(context.Background())
+	firstErr := make(chan error)
+	go func() {
+		_, err := cache.Get(first, "testnet", 10, "value", fetch)
+		firstErr <- err
+	}()
+	<-started
+	secondValue := make(chan json.RawMessage)
+	go func() {
+		value, err := cache.Get(context.Background(), "testnet", 10, "value", fetch)
+		if err != nil {
+			t.Errorf("Get error = %v", err)
+		}
+		secondValue <- value
+	}()
+	// Let the second caller wait for the fetch.
+	time.Sleep(50 * time.Millisecond)
+
+	cancel()
+	if err := <-firstErr; !errors.Is(err, context.Canceled) {
+		t.Errorf("Get error = %v, want %v", err, context.Canceled)
+	}
+	close(release)
+	if value := <-secondValue; string(value) != `1` {
+		t.Errorf("Get = %s, want 1", value)
+	}
+}
+
+func TestEpochCache_Dir(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	calls := 0
+	fetch := func(ctx context.Context) (json.RawMessage, error) {
+		calls++
+		return json.RawMessage(`{"epoch":10}`), nil
+	}
+
+	// A restarted process reads the value of the previous one.
+	for i := 0; i < 2; i++ {
+		got, err := NewEpochCache(WithCacheDir(dir)).Get(ctx, "testnet", 10, "value", fetch)
+		if err != nil {
+			t.Fatalf("Get error = %v", err)
+		}
+		if string(got) != `{"epoch":10}` {
+			t.Errorf("Get = %s", got)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("fetches = %d, want %d", calls, 1)
+	}
+
+	// A new epoch removes the values of older epochs.
+	cache := NewEpochCache(WithCacheDir(dir))
+	cache.Advance("testnet", 11)
+	if _, err := NewEpochCache(WithCacheDir(dir)).Get(ctx, "testnet", 10, "value", fetch); err != nil {
+		t.Fatalf("Get error = %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("fetches after the epoch advanced = %d, want %d", calls, 2)
+	}
+
+	// A value of an epoch which has already passed is not persisted.
+	if err := os.RemoveAll(filepath.Join(dir, "testnet", "10")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.Get(ctx, "testnet", 10, "value", fetch); err != nil {
+		t.Fatalf("Get error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "testnet", "10")); !os.IsNotExist(err) {
+		t.Errorf("value of a past epoch was persisted, stat error = %v", err)
+	}
+
+	if _, err := cache.Get(ctx, "../testnet", 10, "value", fetch); err == nil {
+		t.Errorf("Get with an invalid cluster succeeded")
+	}
+}
+
+func TestEpochSchedule(t *testing.T) {
+	ctx := context.Background()
+
+	epochScheduleAccount, err := GetEpochScheduleAccount(ctx, NewFixtureAccountSource(testAccountSourceDir))
+	if err != nil {
+		t.Fatalf("GetEpochScheduleAccount error = %v", err)
+	}
+	mainnet := epochScheduleAccount.Data.Parsed.Info
+	warmup := types.EpochSchedule{FirstNormalEpoch: 8, FirstNormalSlot: 8160, LeaderScheduleSlotOffset: 8192, SlotsPerEpoch: 8192, Warmup: true}
+
+	tests := []struct {
+		name          string
+		schedule      types.EpochSchedule
+		slot          uint64
+		wantEpoch     uint64
+		wantSlotIndex uint64
+	}{
+		{name: "mainnet", schedule: mainnet, slot: 300000000, wantEpoch: 694, wantSlotIndex: 192000},
+		{name: "warmup: first slot", schedule: warmup, slot: 0, wantEpoch: 0, wantSlotIndex: 0},
+		{name: "warmup: last slot of epoch 0", schedule: warmup, slot: 31, wantEpoch: 0, wantSlotIndex: 31},
+		{name: "warmup: first slot of epoch 1", schedule: warmup, slot: 32, wantEpoch: 1, wantSlotIndex: 0},
+		{name: "warmup: last slot of epoch 1", schedule: warmup, slot: 95, wantEpoch: 1, wantSlotIndex: 63},
+		{name: "warmup: first slot of epoch 2", schedule: warmup, slot: 96, wantEpoch: 2, wantSlotIndex: 0},
+		{name: "warmup: first normal slot", schedule: warmup, slot: 8160, wantEpoch: 8, wantSlotIndex: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			epoch, slotIndex := tt.schedule.GetEpochAndSlotIndex(tt.slot)
+			if epoch != tt.wantEpoch || slotIndex != tt.wantSlotIndex {
+				t.Fatalf("GetEpochAndSlotIndex(%d) = (%d, %d), want (%d, %d)", tt.slot, epoch, slotIndex, tt.wantEpoch, tt.wantSlotIndex)
+			}
+			if got := tt.schedule.GetFirstSlotInEpoch(epoch) + slotIndex; got != tt.slot {
+				t.Errorf("GetFirstSlotInEpoch(%d) + %d = %d, want %d", epoch, slotIndex, got, tt.slot)
+			}
+			if slotIndex >= tt.schedule.GetSlotsInEpoch(epoch) {
+				t.Errorf("slot index %d >= GetSlotsInEpoch(%d) = %d", slotIndex, epoch, tt.schedule.GetSlotsInEpoch(epoch))
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/skport/solana-rpc-client-extensions-go/types"
+)
+
+var (
+	ErrWrongOwner = errors.New("wrong owner")
+	// ErrWrongAccountType is returned for a sysvar owned by the Sysvar program whose data is another sysvar.
+	ErrWrongAccountType = errors.New("wrong account type")
+	// ErrMissingStakeHistoryEntry is returned with WithCurrentEpoch when the StakeHistory sysvar doesn't cover the epoch
+	// before the target epoch, e.g. when it was fetched in an earlier epoch than the stake account.
+	ErrMissingStakeHistoryEntry = errors.New("missing stake history entry")
+	// ErrAlreadyDeactivated and ErrRedelegatedStakeMustFullyActivate are returned by CheckDeactivate.
+	ErrAlreadyDeactivated                = errors.New("stake already deactivated")
+	ErrRedelegatedStakeMustFullyActivate = errors.New("redelegated stake must fully activate before deactivation is permitted")
+	// ErrMergeTransientStake and ErrMergeMismatch are returned by CheckMerge.
+	ErrMergeTransientStake = errors.New("stake is in a transient state")
+	ErrMergeMismatch       = errors.New("merge mismatch")
+	// ErrInsufficientFunds, ErrInsufficientDelegation and ErrInsufficientStake are returned by CheckSplit.
+	ErrInsufficientFunds      = errors.New("insufficient funds")
+	ErrInsufficientDelegation = errors.New("insufficient delegation")
+	ErrInsufficientStake      = errors.New("insufficient stake")
+	// ErrTooSoonToRedelegate, ErrLockupInForce and ErrUnsupportedInstruction are returned by SimulateStakeTransaction.
+	ErrTooSoonToRedelegate    = errors.New("too soon to redelegate")
+	ErrLockupInForce          = errors.New("lockup is in force")
+	ErrUnsupportedInstruction = errors.New("unsupported instruction")
+	// ErrDisconnected is delivered by SubscribeStakeActivations when the websocket connection is lost.
+	ErrDisconnected = errors.New("disconnected")
+	// ErrStaleSlot is returned by FailoverAccountSource for a response older than a previous response.
+	ErrStaleSlot = errors.New("stale slot")
+	// ErrNoQuorum is returned by GetStakeActivationWithQuorum, as a *QuorumError, when the endpoints disagree.
+	ErrNoQuorum = errors.New("no quorum")
+	// ErrArithmeticOverflow is returned when an account snapshot is inconsistent and a calculation would
+	// overflow or underflow, e.g. lamports below rent-exempt reserve plus effective stake.
+	ErrArithmeticOverflow = errors.New("arithmetic overflow")
+
+	ErrAccountNotFound        = types.ErrAccountNotFound
+	ErrNotStakeAccount        = types.ErrNotStakeAccount
+	ErrNotStakeHistoryAccount = types.ErrNotStakeHistoryAccount
+	ErrNotDelegated           = types.ErrNotDelegated
+	ErrNoMeta                 = types.ErrNoMeta
+	ErrMissingField           = types.ErrMissingField
+	ErrInvalidField           = types.ErrInvalidField
+)
+
+// AccountError is returned for a failure related to a specific account.
+// Use errors.Is with the sentinel errors above to classify Err.
+type AccountError struct {
+	Address string
+	// Epoch is the target epoch of the calculation, or 0 if the failure is not related to an epoch.
+	Epoch uint64
+	Err   error
+}
+
+func (e *AccountError) Error() string {
+	if e.Epoch == 0 {
+		return fmt.Sprintf("account: %s: %v", e.Address, e.Err)
+	}
+	return fmt.Sprintf("account: %s, epoch: %d: %v", e.Address, e.Epoch, e.Err)
+}
+
+func (e *AccountError) Unwrap() error {
+	return e.Err
+}
+
+// ArithmeticError describes the invariant that was violated by a calculation.
+type ArithmeticError struct {
+	// Invariant violated by the inputs, e.g. "lamports >= active + rentExemptReserve"
+	Invariant string
+	Op        string
+	X         uint64
+	Y         uint64
+}
+
+func (e *ArithmeticError) Error() string {
+	return fmt.Sprintf("%v: %d %s %d, invariant: %s", ErrArithmeticOverflow, e.X, e.Op, e.Y, e.Invariant)
+}
+
+func (e *ArithmeticError) Unwrap() error {
+	return ErrArithmeticOverflow
+}
+
+// MissingStakeHistoryEntryError is returned with the epoch whose entry is missing.
+type MissingStakeHistoryEntryError struct {
+	Epoch uint64
+	// Newest is the newest epoch in the StakeHistory sysvar.
+	Newest uint64
+}
+
+func (e *MissingStakeHistoryEntryError) Error() string {
+	return fmt.Sprintf("%v: epoch: %d, newest: %d", ErrMissingStakeHistoryEntry, e.Epoch, e.Newest)
+}
+
+func (e *MissingStakeHistoryEntryError) Unwrap() error {
+	return ErrMissingStakeHistoryEntry
+}
+
+func checkedAdd(x uint64, y uint64, invariant string) (uint64, error) {
+	if z := x + y; z >= x {
+		return z, nil
+	}
+	return 0, &ArithmeticError{Invariant: invariant, Op: "+", X: x, Y: y}
+}
+
+func checkedSub(x uint64, y uint64, invariant string) (uint64, error) {
+	if x >= y {
+		return x - y, nil
+	}
+	return 0, &ArithmeticError{Invariant: invariant, Op: "-", X: x, Y: y}
+}
@@ -0,0 +1,429 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	sdkRpc "github.com/blocto/solana-go-sdk/rpc"
+
+	"golang.org/x/time/rate"
+	"golang.org/x/xerrors"
+)
+
+// FailoverOption configures NewFailoverAccountSource.
+type FailoverOption func(*failoverOptions)
+
+type failoverOptions struct {
+	maxAttempts int
+	minBackoff  time.Duration
+	maxBackoff  time.Duration
+	rateLimit   rate.Limit
+	burst       int
+	cooldown    time.Duration
+	httpClient  *http.Client
+}
+
+// WithRetry sets the number of attempts of a request, 5 by default, and the delay before retrying when no endpoint is healthy,
+// which doubles from min up to max with jitter. The delay is at least the Retry-After of a 429 response, up to max.
+func WithRetry(maxAttempts int, min time.Duration, max time.Duration) FailoverOption {
+	return func(o *failoverOptions) {
+		o.maxAttempts = maxAttempts
+		o.minBackoff = min
+		o.maxBackoff = max
+	}
+}
+
+// WithRateLimit limits the HTTP requests to each endpoint with a token bucket of burst tokens, refilled at requestsPerSecond.
+// A burst below 1 is 1, since a bucket without tokens would block all requests. Requests are not limited by default.
+func WithRateLimit(requestsPerSecond float64, burst int) FailoverOption {
+	return func(o *failoverOptions) {
+		o.rateLimit = rate.Limit(requestsPerSecond)
+		o.burst = burst
+		if o.burst < 1 {
+			o.burst = 1
+		}
+	}
+}
+
+// WithUnhealthyCooldown sets how long an endpoint is skipped after a failed request, 30s by default.
+func WithUnhealthyCooldown(cooldown time.Duration) FailoverOption {
+	return func(o *failoverOptions) {
+		o.cooldown = cooldown
+	}
+}
+
+// WithFailoverHTTPClient sets the HTTP client of the requests, e.g. for a timeout.
+func WithFailoverHTTPClient(httpClient *http.Client) FailoverOption {
+	return func(o *failoverOptions) {
+		o.httpClient = httpClient
+	}
+}
+
+// EndpointHealth is the health of an endpoint of a FailoverAccountSource.
+type EndpointHealth struct {
+	Endpoint string
+	Healthy  bool
+	// Failures is the number of consecutive failed requests.
+	Failures  int
+	LastError error
+	// Slot is the highest context slot returned by the endpoint.
+	Slot uint64
+}
+
+// FailoverAccountSource is an AccountSource backed by a list of RPC endpoints in order of preference.
+// Requests go to the first healthy endpoint. An endpoint is unhealthy for a cooldown after a transport error,
+// a 429 or 5xx response, or a JSON-RPC error of a node which is unhealthy or behind, and the request is retried on the next one.
+//
+// Each request requires the highest slot of the previous responses as minContextSlot, so that failing over to a lagging endpoint
+// never returns older state than a previous response. That doesn't make separate requests read the same slot: the epoch,
+// the StakeHistory sysvar and a stake account fetched one after another can still straddle an epoch boundary, and so can the
+// requests GetMultipleAccounts splits more than 100 addresses into. Use GetStakeActivationSnapshot to read them at one slot.
+// It is safe for concurrent use.
+type FailoverAccountSource struct {
+	o         failoverOptions
+	endpoints []*rpcEndpoint
+
+	mu   sync.Mutex
+	slot uint64
+	rand *rand.Rand
+	now  func() time.Time
+}
+
+type rpcEndpoint struct {
+	url    string
+	source *RpcAccountSource
+
+	failures       int
+	lastErr        error
+	unhealthyUntil time.Time
+	slot           uint64
+}
+
+func NewFailoverAccountSource(endpoints []string, opts ...FailoverOption) *FailoverAccountSource {
+	o := failoverOptions{
+		maxAttempts: 5,
+		minBackoff:  500 * time.Millisecond,
+		maxBackoff:  30 * time.Second,
+		rateLimit:   rate.Inf,
+		cooldown:    30 * time.Second,
+		httpClient:  http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	s := &FailoverAccountSource{
+		o:    o,
+		rand: rand.New(rand.NewSource(time.Now().UnixNano())),
+		now:  time.Now,
+	}
+	for _, endpoint := range endpoints {
+		base := o.httpClient.Transport
+		if base == nil {
+			base = http.DefaultTransport
+		}
+		httpClient := *o.httpClient
+		httpClient.Transport = &endpointTransport{base: base, limiter: rate.NewLimiter(o.rateLimit, o.burst)}
+		rpc := sdkRpc.New(sdkRpc.WithEndpoint(endpoint), sdkRpc.WithHTTPClient(&httpClient))
+		s.endpoints = append(s.endpoints, &rpcEndpoint{url: endpoint, source: NewRpcAccountSource(rpc)})
+	}
+	return s
+}
+
+func (s *FailoverAccountSource) GetAccount(ctx context.Context, address string) (json.RawMessage, error) {
+	account, _, err := s.GetAccountWithSlot(ctx, address, 0)
+	return account, err
+}
+
+func (s *FailoverAccountSource) GetAccountWithSlot(ctx context.Context, address string, minContextSlot uint64) (json.RawMessage, uint64, error) {
+	var account json.RawMessage
+	slot, err := s.do(ctx, minContextSlot, func(ctx context.Context, source *RpcAccountSource, minContextSlot uint64) (slot uint64, err error) {
+		account, slot, err = source.GetAccountWithSlot(ctx, address, minContextSlot)
+		return slot, err
+	})
+	return account, slot, err
+}
+
+// GetMultipleAccounts fetches the accounts in requests of up to 100 addresses, which may be served at different slots.
+func (s *FailoverAccountSource) GetMultipleAccounts(ctx context.Context, addresses []string) ([]json.RawMessage, error) {
+	accounts := make([]json.RawMessage, 0, len(addresses))
+	for start := 0; start < len(addresses); start += getMultipleAccountsLimit {
+		end := start + getMultipleAccountsLimit
+		if end > len(addresses) {
+			end = len(addresses)
+		}
+		chunk, _, err := s.GetMultipleAccountsWithSlot(ctx, addresses[start:end], 0)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, chunk...)
+	}
+	return accounts, nil
+}
+
+// GetMultipleAccountsWithSlot fetches the accounts in one request, so that they are all from the returned slot.
+// It fails for more than 100 addresses.
+func (s *FailoverAccountSource) GetMultipleAccountsWithSlot(ctx context.Context, addresses []string, minContextSlot uint64) ([]json.RawMessage, uint64, error) {
+	if len(addresses) > getMultipleAccountsLimit {
+		return nil, 0, fmt.Errorf("%d addresses exceed the getMultipleAccounts limit of %d", len(addresses), getMultipleAccountsLimit)
+	}
+	var accounts []json.RawMessage
+	slot, err := s.do(ctx, minContextSlot, func(ctx context.Context, source *RpcAccountSource, minContextSlot uint64) (slot uint64, err error) {
+		accounts, slot, err = source.GetMultipleAccountsWithSlot(ctx, addresses, minContextSlot)
+		return slot, err
+	})
+	return accounts, slot, err
+}
+
+func (s *FailoverAccountSource) GetEpoch(ctx context.Context) (uint64, error) {
+	epoch, _, err := s.GetEpochWithSlot(ctx, 0)
+	return epoch, err
+}
+
+func (s *FailoverAccountSource) GetEpochWithSlot(ctx context.Context, minContextSlot uint64) (uint64, uint64, error) {
+	var epoch uint64
+	slot, err := s.do(ctx, minContextSlot, func(ctx context.Context, source *RpcAccountSource, minContextSlot uint64) (slot uint64, err error) {
+		epoch, slot, err = source.GetEpochWithSlot(ctx, minContextSlot)
+		return slot, err
+	})
+	return epoch, slot, err
+}
+
+func (s *FailoverAccountSource) GetStakeMinimumDelegation(ctx context.Context) (uint64, error) {
+	var minimumDelegation uint64
+	_, err := s.do(ctx, 0, func(ctx context.Context, source *RpcAccountSource, minContextSlot uint64) (slot uint64, err error) {
+		minimumDelegation, err = source.GetStakeMinimumDelegation(ctx)
+		return 0, err
+	})
+	return minimumDelegation, err
+}
+
+func (s *FailoverAccountSource) GetVoteAccounts(ctx context.Context) (*sdkRpc.GetVoteAccounts, error) {
+	var voteAccounts *sdkRpc.GetVoteAccounts
+	_, err := s.do(ctx, 0, func(ctx context.Context, source *RpcAccountSource, minContextSlot uint64) (slot uint64, err error) {
+		voteAccounts, err = source.GetVoteAccounts(ctx)
+		return 0, err
+	})
+	return voteAccounts, err
+}
+
+// Slot returns the highest context slot of the responses, which is the minContextSlot of the next request.
+func (s *FailoverAccountSource) Slot() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.slot
+}
+
+// Health returns the health of the endpoints in order of preference.
+func (s *FailoverAccountSource) Health() []EndpointHealth {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	health := make([]EndpointHealth, 0, len(s.endpoints))
+	for _, e := range s.endpoints {
+		health = append(health, EndpointHealth{
+			Endpoint:  e.url,
+			Healthy:   !now.Before(e.unhealthyUntil),
+			Failures:  e.failures,
+			LastError: e.lastErr,
+			Slot:      e.slot,
+		})
+	}
+	return health
+}
+
+// do calls f with the endpoints until it succeeds, a non-retryable error is returned, or the attempts are exhausted,
+// and returns the context slot of the response. f returns the context slot, or 0 if the response has no context.
+// The minContextSlot of f is the higher of minContextSlot and the highest slot of the previous responses.
+func (s *FailoverAccountSource) do(ctx context.Context, minContextSlot uint64, f func(ctx context.Context, source *RpcAccountSource, minContextSlot uint64) (uint64, error)) (uint64, error) {
+	if len(s.endpoints) == 0 {
+		return 0, errors.New("no RPC endpoints")
+	}
+
+	var lastErr error
+	var retryAfter time.Duration
+	backoffs := 0
+	for attempt := 0; attempt < s.o.maxAttempts; attempt++ {
+		e, healthy, highestSlot := s.pick()
+		if attempt > 0 && !healthy {
+			if err := sleepContext(ctx, s.backoff(backoffs, retryAfter)); err != nil {
+				return 0, xerrors.Errorf("wrap: %w", err)
+			}
+			backoffs++
+		}
+		requiredSlot := minContextSlot
+		if highestSlot > requiredSlot {
+			requiredSlot = highestSlot
+		}
+
+		record := &httpAttempt{}
+		slot, err := f(context.WithValue(ctx, httpAttemptKey{}, record), e.source, requiredSlot)
+		if err == nil && slot != 0 && slot < requiredSlot {
+			err = xerrors.Errorf("slot %d is older than minContextSlot %d: %w", slot, requiredSlot, ErrStaleSlot)
+		}
+		if err == nil {
+			s.succeed(e, slot)
+			return slot, nil
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return 0, xerrors.Errorf("wrap: %w", ctxErr)
+		}
+		err = xerrors.Errorf("endpoint: %s: %w", e.url, err)
+		if !isRetryable(err, record) {
+			return 0, err
+		}
+		s.fail(e, err)
+		lastErr = err
+		retryAfter = record.retryAfter
+	}
+	return 0, xerrors.Errorf("failed after %d attempts: %w", s.o.maxAttempts, lastErr)
+}
+
+// pick returns the first healthy endpoint, or the one which becomes healthy first, and the minContextSlot of the request.
+func (s *FailoverAccountSource) pick() (*rpcEndpoint, bool, uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	next := s.endpoints[0]
+	for _, e := range s.endpoints {
+		if !now.Before(e.unhealthyUntil) {
+			return e, true, s.slot
+		}
+		if e.unhealthyUntil.Before(next.unhealthyUntil) {
+			next = e
+		}
+	}
+	return next, false, s.slot
+}
+
+func (s *FailoverAccountSource) succeed(e *rpcEndpoint, slot uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e.failures = 0
+	e.lastErr = nil
+	e.unhealthyUntil = time.Time{}
+	if slot > e.slot {
+		e.slot = slot
+	}
+	if slot > s.slot {
+		s.slot = slot
+	}
+}
+
+func (s *FailoverAccountSource) fail(e *rpcEndpoint, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e.failures++
+	e.lastErr = err
+	e.unhealthyUntil = s.now().Add(s.o.cooldown)
+}
+
+// backoff returns the n-th delay between min and max, with jitter of up to half of it.
+func (s *FailoverAccountSource) backoff(n int, retryAfter time.Duration) time.Duration {
+	d := s.o.minBackoff
+	for i := 0; i < n && d < s.o.maxBackoff; i++ {
+		d *= 2
+	}
+	if d > s.o.maxBackoff {
+		d = s.o.maxBackoff
+	}
+	if half := int64(d / 2); half > 0 {
+		s.mu.Lock()
+		d = time.Duration(half + s.rand.Int63n(half+1))
+		s.mu.Unlock()
+	}
+	if retryAfter > d {
+		d = retryAfter
+		if d > s.o.maxBackoff {
+			d = s.o.maxBackoff
+		}
+	}
+	return d
+}
+
+// JSON-RPC error codes of a node which may succeed on retry, or on another node.
+const (
+	rpcErrorBlockNotAvailable        = -32004
+	rpcErrorNodeUnhealthy            = -32005
+	rpcErrorMinContextSlotNotReached = -32016
+)
+
+func isRetryable(err error, record *httpAttempt) bool {
+	if record.err != nil || record.statusCode == http.StatusTooManyRequests || record.statusCode >= 500 {
+		return true
+	}
+	if errors.Is(err, ErrStaleSlot) {
+		return true
+	}
+	var rpcErr *sdkRpc.JsonRpcError
+	if errors.As(err, &rpcErr) {
+		switch rpcErr.Code {
+		case rpcErrorBlockNotAvailable, rpcErrorNodeUnhealthy, rpcErrorMinContextSlotNotReached:
+			return true
+		}
+	}
+	return false
+}
+
+// httpAttempt records the outcome of the HTTP request of an attempt, since the SDK returns it as text.
+type httpAttempt struct {
+	statusCode int
+	retryAfter time.Duration
+	err        error
+}
+
+type httpAttemptKey struct{}
+
+// endpointTransport rate-limits the requests to an endpoint and records their outcome.
+type endpointTransport struct {
+	base    http.RoundTripper
+	limiter *rate.Limiter
+}
+
+func (t *endpointTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	record, _ := req.Context().Value(httpAttemptKey{}).(*httpAttempt)
+	if err := t.limiter.Wait(req.Context()); err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+
+	res, err := t.base.RoundTrip(req)
+	if record != nil {
+		if err != nil {
+			record.err = err
+		} else {
+			record.statusCode = res.StatusCode
+			if seconds, err := strconv.Atoi(res.Header.Get("Retry-After")); err == nil && seconds > 0 {
+				record.retryAfter = time.Duration(seconds) * time.Second
+			}
+		}
+	}
+	return res, err
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+var _ interface {
+	AccountSource
+	SlotAccountSource
+	MinimumDelegationSource
+	VoteAccountsSource
+} = (*FailoverAccountSource)(nil)
@@ -0,0 +1,342 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	sdkRpc "github.com/blocto/solana-go-sdk/rpc"
+)
+
+// testRpcServer serves getEpochInfo, getAccountInfo and getMultipleAccounts at slot, or the error of fail.
+type testRpcServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	slot     uint64
+	fail     func(minContextSlot uint64) (int, string)
+	requests int
+	// minContextSlots are the minContextSlot of the requests.
+	minContextSlots []uint64
+}
+
+func newTestRpcServer(t *testing.T, slot uint64) *testRpcServer {
+	t.Helper()
+	s := &testRpcServer{slot: slot}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		var req struct {
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		if err := json.Unmarshal(b, &req); err != nil {
+			t.Errorf("failed to unmarshal request: %v", err)
+		}
+		var config rpcConfig
+		if len(req.Params) > 0 {
+			_ = json.Unmarshal(req.Params[len(req.Params)-1], &config)
+		}
+		var minContextSlot uint64
+		if config.MinContextSlot != nil {
+			minContextSlot = *config.MinContextSlot
+		}
+
+		s.mu.Lock()
+		s.requests++
+		s.minContextSlots = append(s.minContextSlots, minContextSlot)
+		slot, fail := s.slot, s.fail
+		s.mu.Unlock()
+
+		if fail != nil {
+			if status, body := fail(minContextSlot); status != 0 {
+				w.WriteHeader(status)
+				io.WriteString(w, body)
+				return
+			}
+		}
+		switch req.Method {
+		case "getEpochInfo":
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":1,"result":{"absoluteSlot":%d,"blockHeight":1,"epoch":816,"slotIndex":0,"slotsInEpoch":432000}}`, slot)
+		case "getAccountInfo":
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":1,"result":{"context":{"slot":%d},"value":{"lamports":1}}}`, slot)
+		case "getMultipleAccounts":
+			var addresses []string
+			_ = json.Unmarshal(req.Params[0], &addresses)
+			values := make([]string, len(addresses))
+			for i := range values {
+				values[i] = `{"lamports":1}`
+			}
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":1,"result":{"context":{"slot":%d},"value":[%s]}}`, slot, strings.Join(values, ","))
+		default:
+			io.WriteString(w, `{"jsonrpc":"2.0","id":1,"error":{"code":-32601,"message":"Method not found"}}`)
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *testRpcServer) setFail(fail func(minContextSlot uint64) (int, string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fail = fail
+}
+
+func (s *testRpcServer) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+func failWithStatus(status int) func(uint64) (int, string) {
+	return func(uint64) (int, string) {
+		return status, ""
+	}
+}
+
+func TestFailoverAccountSource_Failover(t *testing.T) {
+	ctx := context.Background()
+
+	primary := newTestRpcServer(t, 100)
+	secondary := newTestRpcServer(t, 100)
+	primary.setFail(failWithStatus(http.StatusTooManyRequests))
+	source := NewFailoverAccountSource([]string{primary.URL, secondary.URL}, WithRetry(3, time.Millisecond, time.Millisecond))
+
+	for i := 0; i < 2; i++ {
+		epoch, err := source.GetEpoch(ctx)
+		if err != nil || epoch != 816 {
+			t.Fatalf("GetEpoch = %v, %v, want 816", epoch, err)
+		}
+	}
+	// The primary is skipped during the cooldown.
+	if primary.count() != 1 || secondary.count() != 2 {
+		t.Errorf("requests = %d, %d, want 1, 2", primary.count(), secondary.count())
+	}
+
+	health := source.Health()
+	if len(health) != 2 || health[0].Healthy || health[0].Failures != 1 || health[0].LastError == nil || !health[1].Healthy || health[1].Slot != 100 {
+		t.Errorf("Health = %+v", health)
+	}
+
+	// The primary is preferred again after the cooldown.
+	primary.setFail(nil)
+	source.now = func() time.Time { return time.Now().Add(time.Minute) }
+	if _, err := source.GetEpoch(ctx); err != nil {
+		t.Fatalf("GetEpoch error = %v", err)
+	}
+	if primary.count() != 2 {
+		t.Errorf("requests to primary = %d, want 2", primary.count())
+	}
+}
+
+func TestFailoverAccountSource_Retry(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name         string
+		fail         func(minContextSlot uint64) (int, string)
+		failures     int
+		wantRequests int
+		wantErr      bool
+	}{
+		{
+			name:         "server error",
+			fail:         failWithStatus(http.StatusServiceUnavailable),
+			failures:     2,
+			wantRequests: 3,
+		},
+		{
+			name: "node unhealthy",
+			fail: func(uint64) (int, string) {
+				return http.StatusOK, `{"jsonrpc":"2.0","id":1,"error":{"code":-32005,"message":"Node is unhealthy"}}`
+			},
+			failures:     2,
+			wantRequests: 3,
+		},
+		{
+			name:         "attempts exhausted",
+			fail:         failWithStatus(http.StatusTooManyRequests),
+			failures:     3,
+			wantRequests: 3,
+			wantErr:      true,
+		},
+		{
+			name: "not retryable",
+			fail: func(uint64) (int, string) {
+				return http.StatusOK, `{"jsonrpc":"2.0","id":1,"error":{"code":-32602,"message":"Invalid params"}}`
+			},
+			failures:     3,
+			wantRequests: 1,
+			wantErr:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestRpcServer(t, 100)
+			failures := 0
+			server.setFail(func(minContextSlot uint64) (int, string) {
+				if failures == tt.failures {
+					return 0, ""
+				}
+				failures++
+				return tt.fail(minContextSlot)
+			})
+			source := NewFailoverAccountSource([]string{server.URL}, WithRetry(3, time.Millisecond, 2*time.Millisecond))
+
+			_, err := source.GetAccount(ctx, testActivatingStakeAddr)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetAccount error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if server.count() != tt.wantRequests {
+				t.Errorf("requests = %d, want %d", server.count(), tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestFailoverAccountSource_MinContextSlot(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		// lagging is the response of the secondary, which is at slot 50, to a request with a minContextSlot ahead of it.
+		lagging func(minContextSlot uint64) (int, string)
+		wantErr error
+	}{
+		{
+			name: "minimum context slot not reached",
+			lagging: func(minContextSlot uint64) (int, string) {
+				if minContextSlot > 50 {
+					return http.StatusOK, `{"jsonrpc":"2.0","id":1,"error":{"code":-32016,"message":"Minimum context slot has not been reached"}}`
+				}
+				return 0, ""
+			},
+		},
+		{
+			name:    "minContextSlot ignored",
+			wantErr: ErrStaleSlot,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary := newTestRpcServer(t, 100)
+			secondary := newTestRpcServer(t, 50)
+			secondary.setFail(tt.lagging)
+			// An attempt for each endpoint.
+			source := NewFailoverAccountSource([]string{primary.URL, secondary.URL}, WithRetry(2, time.Millisecond, time.Millisecond))
+
+			if _, err := source.GetEpoch(ctx); err != nil {
+				t.Fatalf("GetEpoch error = %v", err)
+			}
+			if source.Slot() != 100 {
+				t.Errorf("Slot = %d, want 100", source.Slot())
+			}
+
+			// The StakeHistory sysvar isn't fetched from the secondary, which is behind the epoch.
+			primary.setFail(failWithStatus(http.StatusBadGateway))
+			_, err := source.GetAccount(ctx, StakeHistoryAccountAddress)
+			if err == nil {
+				t.Fatal("GetAccount from a lagging endpoint succeeded")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("GetAccount error = %v, want %v", err, tt.wantErr)
+			}
+			var rpcErr *sdkRpc.JsonRpcError
+			if tt.wantErr == nil && (!errors.As(err, &rpcErr) || rpcErr.Code != rpcErrorMinContextSlotNotReached) {
+				t.Errorf("GetAccount error = %v, want JSON-RPC error %d", err, rpcErrorMinContextSlotNotReached)
+			}
+			for _, minContextSlot := range secondary.minContextSlots {
+				if minContextSlot != 100 {
+					t.Errorf("minContextSlot = %d, want 100", minContextSlot)
+				}
+			}
+
+			// The secondary catches up.
+			secondary.mu.Lock()
+			secondary.slot = 120
+			secondary.fail = nil
+			secondary.mu.Unlock()
+			source.now = func() time.Time { return time.Now().Add(time.Minute) }
+			if _, err := source.GetAccount(ctx, StakeHistoryAccountAddress); err != nil {
+				t.Fatalf("GetAccount error = %v", err)
+			}
+			if source.Slot() != 120 {
+				t.Errorf("Slot = %d, want 120", source.Slot())
+			}
+		})
+	}
+}
+
+func TestFailoverAccountSource_GetMultipleAccounts(t *testing.T) {
+	ctx := context.Background()
+
+	server := newTestRpcServer(t, 100)
+	source := NewFailoverAccountSource([]string{server.URL}, WithRetry(1, time.Millisecond, time.Millisecond))
+
+	addresses := make([]string, 150)
+	for i := range addresses {
+		addresses[i] = StakeHistoryAccountAddress
+	}
+	accounts, err := source.GetMultipleAccounts(ctx, addresses)
+	if err != nil {
+		t.Fatalf("GetMultipleAccounts error = %v", err)
+	}
+	if len(accounts) != 150 || server.count() != 2 {
+		t.Errorf("GetMultipleAccounts = %d accounts in %d requests, want 150 in 2", len(accounts), server.count())
+	}
+
+	// The accounts of one request are from the same slot, so it's not split.
+	if _, _, err := source.GetMultipleAccountsWithSlot(ctx, addresses, 0); err == nil {
+		t.Error("GetMultipleAccountsWithSlot of 150 addresses succeeded")
+	}
+	if server.count() != 2 {
+		t.Errorf("requests = %d, want 2", server.count())
+	}
+
+	// minContextSlot is the higher of the argument and the highest slot seen.
+	for _, minContextSlot := range []uint64{90, 100} {
+		_, slot, err := source.GetMultipleAccountsWithSlot(ctx, addresses[:100], minContextSlot)
+		if err != nil || slot != 100 {
+			t.Fatalf("GetMultipleAccountsWithSlot slot = %v, %v, want 100", slot, err)
+		}
+	}
+	if _, _, err := source.GetEpochWithSlot(ctx, 110); !errors.Is(err, ErrStaleSlot) {
+		t.Errorf("GetEpochWithSlot error = %v, want %v", err, ErrStaleSlot)
+	}
+	for i, want := range []uint64{0, 100, 100, 100, 110} {
+		if server.minContextSlots[i] != want {
+			t.Errorf("minContextSlot of request %d = %d, want %d", i, server.minContextSlots[i], want)
+		}
+	}
+}
+
+func TestFailoverAccountSource_RateLimit(t *testing.T) {
+	ctx := context.Background()
+
+	server := newTestRpcServer(t, 100)
+
+	// A burst of 0 is a burst of 1.
+	for _, burst := range []int{1, 0} {
+		source := NewFailoverAccountSource([]string{server.URL}, WithRateLimit(20, burst))
+
+		start := time.Now()
+		for i := 0; i < 3; i++ {
+			if _, err := source.GetEpoch(ctx); err != nil {
+				t.Fatalf("burst %d: GetEpoch error = %v", burst, err)
+			}
+		}
+		// The burst allows the first request, and the others wait 50ms each.
+		if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+			t.Errorf("burst %d: 3 requests took %v, want at least 100ms", burst, elapsed)
+		}
+	}
+}
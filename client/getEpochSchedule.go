@@ -0,0 +1,40 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/skport/solana-rpc-client-extensions-go/types"
+
+	"golang.org/x/xerrors"
+)
+
+const (
+	// https://docs.anza.xyz/runtime/sysvars#epochschedule
+	EpochScheduleAccountAddress = "SysvarEpochSchedu1e111111111111111111111111"
+)
+
+func GetEpochScheduleAccount(ctx context.Context, source AccountSource) (*types.EpochScheduleAccount, error) {
+	raw, err := source.GetAccount(ctx, EpochScheduleAccountAddress)
+	if err != nil {
+		return nil, xerrors.Errorf("epochScheduleAccount: %s, wrap: %w", EpochScheduleAccountAddress, err)
+	}
+	if raw == nil {
+		return nil, &AccountError{Address: EpochScheduleAccountAddress, Err: ErrAccountNotFound}
+	}
+	return decodeEpochScheduleAccount(raw)
+}
+
+func decodeEpochScheduleAccount(raw json.RawMessage) (*types.EpochScheduleAccount, error) {
+	var epochScheduleAccount types.EpochScheduleAccount
+	if err := json.Unmarshal(raw, &epochScheduleAccount); err != nil {
+		return nil, xerrors.Errorf("failed to unmarshal to EpochScheduleAccount: %w", err)
+	}
+	if epochScheduleAccount.Owner != SysvarProgramAddress {
+		return nil, &AccountError{Address: EpochScheduleAccountAddress, Err: xerrors.Errorf("%w: owner: %s, want: %s", ErrWrongOwner, epochScheduleAccount.Owner, SysvarProgramAddress)}
+	}
+	if epochScheduleAccount.Data.Parsed.Type != "epochSchedule" {
+		return nil, &AccountError{Address: EpochScheduleAccountAddress, Err: xerrors.Errorf("%w: type: %q, want: %q", ErrWrongAccountType, epochScheduleAccount.Data.Parsed.Type, "epochSchedule")}
+	}
+	return &epochScheduleAccount, nil
+}
@@ -0,0 +1,90 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/skport/solana-rpc-client-extensions-go/types"
+
+	"golang.org/x/xerrors"
+)
+
+// MaxSeededStakeAccounts is the largest count of GetSeededStakeActivations, i.e. 10 getMultipleAccounts requests.
+const MaxSeededStakeAccounts = 1000
+
+type SeededStakeActivation struct {
+	Seed       string                      `json:"seed"`
+	Address    string                      `json:"address"`
+	Activation *GetStakeActivationResponse `json:"activation"`
+}
+
+// GetSeededStakeActivations derives the stake accounts `<seedPrefix>0` .. `<seedPrefix><count-1>` for baseAddress
+// and returns the activation of the ones that exist on-chain. count must be between 0 and MaxSeededStakeAccounts.
+func GetSeededStakeActivations(ctx context.Context, source AccountSource, baseAddress string, seedPrefix string, count int) ([]SeededStakeActivation, error) {
+	if count < 0 || count > MaxSeededStakeAccounts {
+		return nil, fmt.Errorf("count %d is out of range [0, %d]", count, MaxSeededStakeAccounts)
+	}
+
+	seeds := make([]string, 0, count)
+	addresses := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		seed := fmt.Sprintf("%s%d", seedPrefix, i)
+		address, err := CreateStakeAccountWithSeedAddress(baseAddress, seed)
+		if err != nil {
+			return nil, xerrors.Errorf("seed: %s, wrap: %w", seed, err)
+		}
+		seeds = append(seeds, seed)
+		addresses = append(addresses, address)
+	}
+
+	epoch, err := source.GetEpoch(ctx)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to get epoch: %w", err)
+	}
+	stakeHistoryAccount, err := GetStakeHistoryAccount(ctx, source)
+	if err != nil {
+		return nil, xerrors.Errorf("wrap: %w", err)
+	}
+
+	accounts, err := source.GetMultipleAccounts(ctx, addresses)
+	if err != nil {
+		return nil, xerrors.Errorf("wrap: %w", err)
+	}
+
+	var result []SeededStakeActivation
+	for i, account := range accounts {
+		if account == nil {
+			continue
+		}
+
+		// Something other than a stake account may live at the derived address.
+		var owner struct {
+			Owner string `json:"owner"`
+		}
+		if err := json.Unmarshal(account, &owner); err != nil {
+			return nil, xerrors.Errorf("address: %s, failed to unmarshal: %w", addresses[i], err)
+		}
+		if owner.Owner != StakeProgramAddress {
+			continue
+		}
+
+		stakeAccount, err := types.DecodeStakeAccount(account)
+		if err != nil {
+			return nil, xerrors.Errorf("stakeAccount: %s, wrap: %w", addresses[i], err)
+		}
+
+		activation, err := GetStakeActivation(addresses[i], epoch, stakeAccount, stakeHistoryAccount, WithCurrentEpoch())
+		if err != nil {
+			return nil, xerrors.Errorf("wrap: %w", err)
+		}
+
+		result = append(result, SeededStakeActivation{
+			Seed:       seeds[i],
+			Address:    addresses[i],
+			Activation: activation,
+		})
+	}
+
+	return result, nil
+}
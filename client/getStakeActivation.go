@@ -1,12 +1,11 @@
 package client
 
 import (
-	"encoding/json"
-	"fmt"
+	"errors"
 	"math"
-	"math/big"
 
 	sdkRpc "github.com/blocto/solana-go-sdk/rpc"
+	"github.com/skport/solana-rpc-client-extensions-go/adapter/blocto"
 	"github.com/skport/solana-rpc-client-extensions-go/types"
 
 	"golang.org/x/xerrors"
@@ -16,53 +15,160 @@ type GetStakeActivationResponse struct {
 	Active   uint64 `json:"active"`
 	Inactive uint64 `json:"inactive"`
 	State    string `json:"state"`
+	// Diagnostics explains which invariants of the account snapshot were violated, when saturating arithmetic is enabled.
+	Diagnostics []string `json:"diagnostics,omitempty"`
 }
 
 const (
 	// https://docs.anza.xyz/runtime/sysvars#stakehistory
 	StakeHistoryAccountAddress = "SysvarStakeHistory1111111111111111111111111"
+	SysvarProgramAddress       = "Sysvar1111111111111111111111111111111111111"
 	// https://github.com/anza-xyz/solana-rpc-client-extensions/blob/aed9a86988f7f8055fe3dd3cd3e28761ad10ce04/js-v1/src/delegation.ts#L26
 	stakeWarmupCooldownRate = 0.09
 )
 
-func GetStakeActivation(stakeAccountAddress string, epoch uint64, stakeAccount *types.StakeAccount, stakeHistoryAccount *types.StakeHistoryAccount) (*GetStakeActivationResponse, error) {
-	var (
-		effective    uint64
-		activating   uint64
-		deactivating uint64
-	)
+func GetStakeActivation(stakeAccountAddress string, epoch uint64, stakeAccount *types.StakeAccount, stakeHistoryAccount *types.StakeHistoryAccount, opts ...Option) (*GetStakeActivationResponse, error) {
+	o := newOptions(opts)
 
-	// Calculates the amount of valid staking only during staking (when Info.Stake of stakeAccount is not nil).
-	stakeInfo, err := stakeAccount.GetInfoStake()
-	if err == nil && stakeInfo.Delegation.Stake != "" {
-		effective, activating, deactivating, err = getSolanaStakeActivatingAndDeactivating(stakeAccountAddress, stakeAccount, epoch, stakeHistoryAccount)
-		if err != nil {
-			return nil, xerrors.Errorf("epoch: %d, stakeAccount: %s, wrap: %w", epoch, stakeAccountAddress, err)
-		}
+	status, err := getStakeActivationStatus(stakeAccountAddress, epoch, stakeAccount, stakeHistoryAccount, o)
+	if err != nil {
+		return nil, err
 	}
 
-	state := "inactive"
-	if deactivating > 0 {
-		state = "deactivating"
-	} else if activating > 0 {
-		state = "activating"
-	} else if effective > 0 {
-		state = "active"
+	// Uninitialized and RewardsPool accounts have neither a delegation nor a rent-exempt reserve.
+	if !stakeAccount.HasMeta() {
+		return &GetStakeActivationResponse{
+			Active:   0,
+			Inactive: stakeAccount.Lamports,
+			State:    "inactive",
+		}, nil
 	}
 
-	rentExemptReserve, err := stakeAccount.GetRentExemptReserve()
+	// Lamports may be below the rent-exempt reserve plus effective stake for an inconsistent snapshot.
+	var diagnostics []string
+	inactive, err := status.InactiveStake(stakeAccount)
 	if err != nil {
-		return nil, xerrors.Errorf("epoch: %d, stakeAccount: %s, wrap: %w", epoch, stakeAccountAddress, err)
+		if !o.saturating || !errors.Is(err, ErrArithmeticOverflow) {
+			return nil, &AccountError{Address: stakeAccountAddress, Epoch: epoch, Err: err}
+		}
+		inactive = 0
+		diagnostics = append(diagnostics, err.Error())
 	}
-	inactive := stakeAccount.Lamports - effective - rentExemptReserve
 
 	return &GetStakeActivationResponse{
-		Active:   effective,
-		Inactive: inactive,
-		State:    state,
+		Active:      status.Effective,
+		Inactive:    inactive,
+		State:       status.State(),
+		Diagnostics: diagnostics,
 	}, nil
 }
 
+// StakeActivationStatus is the effective, activating and deactivating stake of a delegation at an epoch,
+// as StakeActivationStatus of the stake program. All are 0 for an account without a delegation.
+type StakeActivationStatus struct {
+	Effective    uint64 `json:"effective"`
+	Activating   uint64 `json:"activating"`
+	Deactivating uint64 `json:"deactivating"`
+}
+
+// State is the state GetStakeActivation reports for the status: "inactive", "activating", "active" or "deactivating".
+func (s *StakeActivationStatus) State() string {
+	if s.Deactivating > 0 {
+		return "deactivating"
+	} else if s.Activating > 0 {
+		return "activating"
+	} else if s.Effective > 0 {
+		return "active"
+	}
+	return "inactive"
+}
+
+// InactiveStake returns the lamports of stakeAccount which are neither effective nor its rent-exempt reserve,
+// which GetStakeActivation reports as Inactive. It fails with ErrArithmeticOverflow if the lamports are below both.
+func (s *StakeActivationStatus) InactiveStake(stakeAccount *types.StakeAccount) (uint64, error) {
+	// Uninitialized and RewardsPool accounts have no rent-exempt reserve.
+	if !stakeAccount.HasMeta() {
+		return stakeAccount.Lamports, nil
+	}
+	rentExemptReserve, err := stakeAccount.GetRentExemptReserve()
+	if err != nil {
+		return 0, err
+	}
+	return getInactiveStake(stakeAccount.Lamports, s.Effective, rentExemptReserve)
+}
+
+// GetStakeActivationStatus returns the stake of stakeAccount in warmup and cooldown at epoch,
+// which GetStakeActivation reduces to a state.
+func GetStakeActivationStatus(stakeAccountAddress string, epoch uint64, stakeAccount *types.StakeAccount, stakeHistoryAccount *types.StakeHistoryAccount, opts ...Option) (*StakeActivationStatus, error) {
+	return getStakeActivationStatus(stakeAccountAddress, epoch, stakeAccount, stakeHistoryAccount, newOptions(opts))
+}
+
+func getStakeActivationStatus(stakeAccountAddress string, epoch uint64, stakeAccount *types.StakeAccount, stakeHistoryAccount *types.StakeHistoryAccount, o *options) (*StakeActivationStatus, error) {
+	if !o.skipValidation {
+		if err := ValidateStakeAccount(stakeAccount); err != nil {
+			return nil, &AccountError{Address: stakeAccountAddress, Epoch: epoch, Err: err}
+		}
+		if err := ValidateStakeHistoryAccount(stakeHistoryAccount); err != nil {
+			return nil, &AccountError{Address: StakeHistoryAccountAddress, Epoch: epoch, Err: err}
+		}
+	}
+
+	status := &StakeActivationStatus{}
+	if !stakeAccount.HasMeta() {
+		return status, nil
+	}
+
+	// Calculates the amount of valid staking only during staking (when Info.Stake of stakeAccount is not nil).
+	if _, err := stakeAccount.GetInfoStake(); err == nil {
+		if o.currentEpoch {
+			if err := checkStakeHistoryAccount(stakeHistoryAccount, epoch); err != nil {
+				return nil, &AccountError{Address: stakeAccountAddress, Epoch: epoch, Err: err}
+			}
+		}
+		status.Effective, status.Activating, status.Deactivating, err = getSolanaStakeActivatingAndDeactivating(stakeAccountAddress, stakeAccount, epoch, stakeHistoryAccount)
+		if err != nil {
+			return nil, &AccountError{Address: stakeAccountAddress, Epoch: epoch, Err: err}
+		}
+	}
+	return status, nil
+}
+
+// ValidateStakeAccount checks that stakeAccount is a jsonParsed account of the Stake program in one of the StakeStateV2 variants.
+func ValidateStakeAccount(stakeAccount *types.StakeAccount) error {
+	if stakeAccount.Owner != StakeProgramAddress {
+		return xerrors.Errorf("%w: owner: %s, want: %s", ErrWrongOwner, stakeAccount.Owner, StakeProgramAddress)
+	}
+	if stakeAccount.Data.Program != "stake" {
+		return xerrors.Errorf("%w: program: %q, want: %q", ErrNotStakeAccount, stakeAccount.Data.Program, "stake")
+	}
+	if _, err := stakeAccount.GetState(); err != nil {
+		return xerrors.Errorf("%w: %v", ErrNotStakeAccount, err)
+	}
+	return nil
+}
+
+// ValidateStakeHistoryAccount checks that stakeHistoryAccount is the StakeHistory sysvar.
+func ValidateStakeHistoryAccount(stakeHistoryAccount *types.StakeHistoryAccount) error {
+	if stakeHistoryAccount.Owner != SysvarProgramAddress {
+		return xerrors.Errorf("%w: owner: %s, want: %s", ErrWrongOwner, stakeHistoryAccount.Owner, SysvarProgramAddress)
+	}
+	if stakeHistoryAccount.Data.Program != "sysvar" || stakeHistoryAccount.Data.Parsed.Type != "stakeHistory" {
+		return xerrors.Errorf("%w: program: %q, type: %q, want: %q, %q", ErrNotStakeHistoryAccount,
+			stakeHistoryAccount.Data.Program, stakeHistoryAccount.Data.Parsed.Type, "sysvar", "stakeHistory")
+	}
+	return nil
+}
+
+func getInactiveStake(lamports uint64, effective uint64, rentExemptReserve uint64) (uint64, error) {
+	const invariant = "lamports >= active + rentExemptReserve"
+
+	reserved, err := checkedAdd(effective, rentExemptReserve, invariant)
+	if err != nil {
+		return 0, err
+	}
+	return checkedSub(lamports, reserved, invariant)
+}
+
 func getSolanaStakeActivatingAndDeactivating(stakeAccountAddress string, stakeAccount *types.StakeAccount, targetEpoch uint64, stakeHistoryAccount *types.StakeHistoryAccount) (uint64, uint64, uint64, error) {
 	var (
 		effective    uint64
@@ -89,12 +195,8 @@ func getSolanaStakeActivatingAndDeactivating(stakeAccountAddress string, stakeAc
 		return effective, activating, deactivating, nil
 	}
 
-	currentEpoch, err := stakeAccount.GetDeactivationEpoch()
-	if err != nil {
-		return 0, 0, 0, xerrors.Errorf("wrap: %w", err)
-	}
-
-	stakeHistoryEntry := getSolanaStakeHistoryEntry(stakeHistoryAccount, targetEpoch)
+	currentEpoch := deactivationEpoch
+	stakeHistoryEntry := getSolanaStakeHistoryEntry(stakeHistoryAccount, currentEpoch)
 
 	if stakeHistoryEntry != nil {
 		currentEffectiveStake := effective
@@ -107,33 +209,19 @@ func getSolanaStakeActivatingAndDeactivating(stakeAccountAddress string, stakeAc
 			}
 
 			// calculate weight
-			currentEffectiveStakeBig := new(big.Float).SetUint64(currentEffectiveStake)
-			historyDeactivatingBig := new(big.Float).SetUint64(stakeHistoryEntry.StakeHistory.Deactivating)
-			weightBig := new(big.Float).Quo(currentEffectiveStakeBig, historyDeactivatingBig)
+			weight := float64(currentEffectiveStake) / float64(stakeHistoryEntry.StakeHistory.Deactivating)
 
 			// calculate newly not effective cluster stake
-			effectiveBig := new(big.Float).SetUint64(stakeHistoryEntry.StakeHistory.Effective)
-			StakeWarmupCooldownRateBig := new(big.Float).SetFloat64(stakeWarmupCooldownRate)
-			newlyNotEffectiveClusterStakeBig := new(big.Float).Mul(effectiveBig, StakeWarmupCooldownRateBig)
+			newlyNotEffectiveClusterStake := float64(stakeHistoryEntry.StakeHistory.Effective) * stakeWarmupCooldownRate
 
 			// calculate newly not effective stake
-			r := new(big.Float).Mul(weightBig, newlyNotEffectiveClusterStakeBig)
-			roundedResult, accuracy := r.Float64()
-			if accuracy != big.Exact {
-				return 0, 0, 0, fmt.Errorf("failed to calculate newly not effective cluster stake, stakeAccount: %s, currentEpoch: %d, roundedResult: %f, accuracy: %s",
-					stakeAccountAddress,
-					currentEpoch,
-					roundedResult,
-					accuracy.String(),
-				)
-			}
-			newlyNotEffectiveStake := uint64(math.Max(1, math.Round(roundedResult)))
+			newlyNotEffectiveStake := truncateStake(weight * newlyNotEffectiveClusterStake)
 
-			currentEffectiveStake -= newlyNotEffectiveStake
-			if currentEffectiveStake <= 0 {
+			if currentEffectiveStake <= newlyNotEffectiveStake {
 				currentEffectiveStake = 0
 				break
 			}
+			currentEffectiveStake -= newlyNotEffectiveStake
 
 			if currentEpoch >= targetEpoch {
 				break
@@ -173,7 +261,10 @@ func getSolanaStakeAndActivating(stakeAccountAddress string, stakeAccount *types
 		return 0, 0, xerrors.Errorf("wrap: %w", err)
 	}
 
-	if activationEpoch == deactivationEpoch {
+	if activationEpoch == types.MaxEpoch {
+		// Bootstrap stakes of the genesis config are fully effective from the first epoch.
+		return delegationStake, 0, nil
+	} else if activationEpoch == deactivationEpoch {
 		return 0, 0, nil
 	} else if targetEpoch == activationEpoch {
 		return 0, delegationStake, nil
@@ -182,7 +273,7 @@ func getSolanaStakeAndActivating(stakeAccountAddress string, stakeAccount *types
 	}
 
 	currentEpoch := activationEpoch
-	stakeHistoryEntry := getSolanaStakeHistoryEntry(stakeHistoryAccount, targetEpoch)
+	stakeHistoryEntry := getSolanaStakeHistoryEntry(stakeHistoryAccount, currentEpoch)
 
 	if stakeHistoryEntry != nil {
 		currentEffectiveStake := uint64(0)
@@ -190,36 +281,33 @@ func getSolanaStakeAndActivating(stakeAccountAddress string, stakeAccount *types
 		for stakeHistoryEntry != nil {
 			currentEpoch++
 
-			remaining := delegationStake - currentEffectiveStake
+			// No stake was warming up in the cluster (only possible with an inconsistent history).
+			if stakeHistoryEntry.StakeHistory.Activating == 0 {
+				break
+			}
+
+			remaining, err := checkedSub(delegationStake, currentEffectiveStake, "effective <= delegation")
+			if err != nil {
+				return 0, 0, xerrors.Errorf("epoch: %d, wrap: %w", currentEpoch, err)
+			}
 
 			// calculate weight
-			remainingBig := new(big.Float).SetUint64(remaining)
-			activatingBig := new(big.Float).SetUint64(activating)
-			weightBig := new(big.Float).Quo(remainingBig, activatingBig)
+			weight := float64(remaining) / float64(stakeHistoryEntry.StakeHistory.Activating)
 
 			// calculate newly effective cluster stake
-			effectiveBig := new(big.Float).SetUint64(stakeHistoryEntry.StakeHistory.Effective)
-			StakeWarmupCooldownRateBig := new(big.Float).SetFloat64(stakeWarmupCooldownRate)
-			newlyEffectiveClusterStakeBig := new(big.Float).Mul(effectiveBig, StakeWarmupCooldownRateBig)
+			newlyEffectiveClusterStake := float64(stakeHistoryEntry.StakeHistory.Effective) * stakeWarmupCooldownRate
 
 			// calculate newly effective stake
-			r := new(big.Float).Mul(weightBig, newlyEffectiveClusterStakeBig)
-			roundedResult, accuracy := r.Float64()
-			if accuracy != big.Exact {
-				return 0, 0, fmt.Errorf("failed to calculate newly effective cluster stake, stakeAccount: %s, currentEpoch: %d, roundedResult: %f, accuracy: %s",
-					stakeAccountAddress,
-					currentEpoch,
-					roundedResult,
-					accuracy.String(),
-				)
-			}
-			newlyEffectiveStake := uint64(math.Max(1, math.Round(roundedResult)))
+			newlyEffectiveStake := truncateStake(weight * newlyEffectiveClusterStake)
 
-			currentEffectiveStake += newlyEffectiveStake
-			if currentEffectiveStake >= delegationStake {
+			if remaining <= newlyEffectiveStake {
 				currentEffectiveStake = delegationStake
 				break
 			}
+			currentEffectiveStake, err = checkedAdd(currentEffectiveStake, newlyEffectiveStake, "effective <= delegation")
+			if err != nil {
+				return 0, 0, xerrors.Errorf("epoch: %d, wrap: %w", currentEpoch, err)
+			}
 
 			if currentEpoch >= targetEpoch || currentEpoch >= deactivationEpoch {
 				break
@@ -229,7 +317,10 @@ func getSolanaStakeAndActivating(stakeAccountAddress string, stakeAccount *types
 		}
 
 		effective = currentEffectiveStake
-		activating = delegationStake - currentEffectiveStake
+		activating, err = checkedSub(delegationStake, currentEffectiveStake, "effective <= delegation")
+		if err != nil {
+			return 0, 0, xerrors.Errorf("wrap: %w", err)
+		}
 	} else {
 		effective = delegationStake
 		activating = 0
@@ -238,6 +329,38 @@ func getSolanaStakeAndActivating(stakeAccountAddress string, stakeAccount *types
 	return effective, activating, nil
 }
 
+// truncateStake mirrors `((x) as u64).max(1)` of the runtime, which the removed getStakeActivation RPC returned.
+// The reference implementation rounds instead, which is off by one lamport per epoch of warmup/cooldown.
+// https://github.com/anza-xyz/agave/blob/v2.0.0/sdk/program/src/stake/state.rs
+func truncateStake(x float64) uint64 {
+	if math.IsNaN(x) || x < 1 {
+		return 1
+	}
+	if x >= math.MaxUint64 {
+		return math.MaxUint64
+	}
+	return uint64(x)
+}
+
+// checkStakeHistoryAccount checks that the StakeHistory sysvar is not older than the epoch before epoch.
+// An empty StakeHistory is valid, e.g. for the first epoch of a test validator.
+func checkStakeHistoryAccount(r *types.StakeHistoryAccount, epoch uint64) error {
+	if len(r.Data.Parsed.Info) == 0 || epoch == 0 {
+		return nil
+	}
+
+	var newest uint64
+	for _, entry := range r.Data.Parsed.Info {
+		if uint64(entry.Epoch) > newest {
+			newest = uint64(entry.Epoch)
+		}
+	}
+	if newest < epoch-1 {
+		return &MissingStakeHistoryEntryError{Epoch: epoch - 1, Newest: newest}
+	}
+	return nil
+}
+
 func getSolanaStakeHistoryEntry(r *types.StakeHistoryAccount, targetEpoch uint64) *types.StakeHistoryAccountInfo {
 	for _, entry := range r.Data.Parsed.Info {
 		if uint64(entry.Epoch) == targetEpoch {
@@ -247,34 +370,11 @@ func getSolanaStakeHistoryEntry(r *types.StakeHistoryAccount, targetEpoch uint64
 	return nil
 }
 
+// ConvertStakeAccountInfo converts a getAccountInfo response of the SDK. See the adapter packages for other SDKs.
 func ConvertStakeAccountInfo(stakeAccountInfo sdkRpc.JsonRpcResponse[sdkRpc.ValueWithContext[sdkRpc.AccountInfo]]) (*types.StakeAccount, error) {
-	b, err := json.Marshal(stakeAccountInfo.Result.Value)
-	if err != nil {
-		return nil, xerrors.Errorf("failed to marshal: %w", err)
-	}
-
-	var stakeAccount *types.StakeAccount
-	if err := json.Unmarshal(b, &stakeAccount); err != nil {
-		return nil, xerrors.Errorf("failed to unmarshal to StakeAccountInfoResponse: %v", err)
-	}
-
-	return stakeAccount, nil
+	return blocto.StakeAccount(stakeAccountInfo.Result.Value)
 }
 
 func ConvertStakeHistoryAccountInfo(stakeHistoryAccountInfo sdkRpc.JsonRpcResponse[sdkRpc.ValueWithContext[sdkRpc.AccountInfo]]) (*types.StakeHistoryAccount, error) {
-	b, err := json.Marshal(stakeHistoryAccountInfo.Result.Value)
-	if err != nil {
-		return nil, xerrors.Errorf("failed to marshal: %w", err)
-	}
-
-	var stakeHistoryAccount *types.StakeHistoryAccount
-	if err := json.Unmarshal(b, &stakeHistoryAccount); err != nil {
-		return nil, xerrors.Errorf("failed to unmarshal to StakeHistoryAccountInfoResponse: %v", err)
-	}
-
-	if stakeHistoryAccount.Data.Parsed.Info == nil {
-		return nil, xerrors.Errorf("stakeHistoryAccount.Data.Parsed.Info is nil")
-	}
-
-	return stakeHistoryAccount, nil
+	return blocto.StakeHistoryAccount(stakeHistoryAccountInfo.Result.Value)
 }
@@ -0,0 +1,94 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/skport/solana-rpc-client-extensions-go/types"
+
+	"golang.org/x/xerrors"
+)
+
+// MaxSnapshotStakeAccounts is the largest count of stake accounts of GetStakeActivationSnapshot,
+// i.e. one getMultipleAccounts request together with the StakeHistory and EpochSchedule sysvars.
+const MaxSnapshotStakeAccounts = getMultipleAccountsLimit - 2
+
+// StakeActivationSnapshot is the activation of stake accounts calculated from the accounts of a single slot.
+type StakeActivationSnapshot struct {
+	Slot  uint64
+	Epoch uint64
+	// StakeHistory is the StakeHistory sysvar at Slot.
+	StakeHistory *types.StakeHistoryAccount
+	// StakeAccounts, Activations and Errors are in the order of the addresses.
+	// A stake account which doesn't exist or whose activation can't be calculated has a nil activation and an *AccountError,
+	// so that it doesn't hide the others.
+	StakeAccounts []*types.StakeAccount
+	Activations   []*GetStakeActivationResponse
+	Errors        []error
+}
+
+// GetStakeActivationSnapshot fetches the StakeHistory and EpochSchedule sysvars and the stake accounts in one request,
+// so that they are all from the same slot, and calculates the activation of the stake accounts at the epoch of that slot.
+// Unlike fetching the epoch, the StakeHistory sysvar and the stake accounts one after another, it can't straddle an epoch boundary.
+// It takes at most MaxSnapshotStakeAccounts addresses.
+func GetStakeActivationSnapshot(ctx context.Context, source SlotAccountSource, addresses []string) (*StakeActivationSnapshot, error) {
+	if len(addresses) > MaxSnapshotStakeAccounts {
+		return nil, fmt.Errorf("%d addresses exceed the limit of %d", len(addresses), MaxSnapshotStakeAccounts)
+	}
+
+	accounts, slot, err := source.GetMultipleAccountsWithSlot(ctx, append([]string{StakeHistoryAccountAddress, EpochScheduleAccountAddress}, addresses...), 0)
+	if err != nil {
+		return nil, xerrors.Errorf("wrap: %w", err)
+	}
+	if len(accounts) != len(addresses)+2 {
+		return nil, fmt.Errorf("got %d accounts for %d addresses", len(accounts), len(addresses)+2)
+	}
+
+	if accounts[1] == nil {
+		return nil, &AccountError{Address: EpochScheduleAccountAddress, Err: ErrAccountNotFound}
+	}
+	epochScheduleAccount, err := decodeEpochScheduleAccount(accounts[1])
+	if err != nil {
+		return nil, xerrors.Errorf("wrap: %w", err)
+	}
+	epoch, _ := epochScheduleAccount.Data.Parsed.Info.GetEpochAndSlotIndex(slot)
+
+	if accounts[0] == nil {
+		return nil, &AccountError{Address: StakeHistoryAccountAddress, Epoch: epoch, Err: ErrAccountNotFound}
+	}
+	stakeHistoryAccount, err := types.DecodeStakeHistoryAccount(accounts[0])
+	if err != nil {
+		return nil, &AccountError{Address: StakeHistoryAccountAddress, Epoch: epoch, Err: err}
+	}
+	// The StakeHistory sysvar is checked once here, so that the errors of the stake accounts are their own.
+	if err := ValidateStakeHistoryAccount(stakeHistoryAccount); err != nil {
+		return nil, &AccountError{Address: StakeHistoryAccountAddress, Epoch: epoch, Err: err}
+	}
+	if err := checkStakeHistoryAccount(stakeHistoryAccount, epoch); err != nil {
+		return nil, &AccountError{Address: StakeHistoryAccountAddress, Epoch: epoch, Err: err}
+	}
+
+	snapshot := &StakeActivationSnapshot{
+		Slot:          slot,
+		Epoch:         epoch,
+		StakeHistory:  stakeHistoryAccount,
+		StakeAccounts: make([]*types.StakeAccount, len(addresses)),
+		Activations:   make([]*GetStakeActivationResponse, len(addresses)),
+		Errors:        make([]error, len(addresses)),
+	}
+	for i, address := range addresses {
+		raw := accounts[i+2]
+		if raw == nil {
+			snapshot.Errors[i] = &AccountError{Address: address, Err: ErrAccountNotFound}
+			continue
+		}
+		stakeAccount, err := types.DecodeStakeAccount(raw)
+		if err != nil {
+			snapshot.Errors[i] = &AccountError{Address: address, Err: err}
+			continue
+		}
+		snapshot.StakeAccounts[i] = stakeAccount
+		snapshot.Activations[i], snapshot.Errors[i] = GetStakeActivation(address, epoch, stakeAccount, stakeHistoryAccount, WithCurrentEpoch())
+	}
+	return snapshot, nil
+}
@@ -0,0 +1,164 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// testSlotAccountSource serves the accounts of a MemoryAccountSource at slot.
+type testSlotAccountSource struct {
+	*MemoryAccountSource
+	slot uint64
+}
+
+func (s *testSlotAccountSource) GetAccountWithSlot(ctx context.Context, address string, minContextSlot uint64) (json.RawMessage, uint64, error) {
+	account, err := s.GetAccount(ctx, address)
+	return account, s.slot, err
+}
+
+func (s *testSlotAccountSource) GetMultipleAccountsWithSlot(ctx context.Context, addresses []string, minContextSlot uint64) ([]json.RawMessage, uint64, error) {
+	accounts, err := s.GetMultipleAccounts(ctx, addresses)
+	return accounts, s.slot, err
+}
+
+func (s *testSlotAccountSource) GetEpochWithSlot(ctx context.Context, minContextSlot uint64) (uint64, uint64, error) {
+	epoch, err := s.GetEpoch(ctx)
+	return epoch, s.slot, err
+}
+
+func TestGetStakeActivationSnapshot(t *testing.T) {
+	ctx := context.Background()
+
+	epochScheduleAccount, err := os.ReadFile(testAccountSourceDir + "/" + EpochScheduleAccountAddress + ".json")
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+	const (
+		activeAddr     = "6RjzDCWjNkGhzx3fSsNYVjFPBzEYFBoWKGADBE6u8r6s"
+		activatingAddr = "9tpQwhrezX5ZGUgYz4SfXVFYMSRRz3G95BBYH7aNmhaS"
+		closedAddr     = "11111111111111111111111111111111"
+	)
+
+	// The slot is in epoch 694 of the mainnet EpochSchedule.
+	newSource := func(newestEntry uint64) *testSlotAccountSource {
+		// The epoch of GetEpoch is ignored.
+		memory := NewMemoryAccountSource(0)
+		memory.SetAccount(EpochScheduleAccountAddress, json.RawMessage(epochScheduleAccount))
+		memory.SetAccount(StakeHistoryAccountAddress, newTestStakeHistoryEntries(newestEntry-4, newestEntry))
+		memory.SetAccount(activeAddr, newTestStakeAccount(2000000000+testRentExemptReserve, 1000000000, 100, math.MaxUint64))
+		memory.SetAccount(activatingAddr, newTestStakeAccount(1000000000+testRentExemptReserve, 1000000000, 694, math.MaxUint64))
+		return &testSlotAccountSource{MemoryAccountSource: memory, slot: 300000000}
+	}
+
+	t.Run("snapshot", func(t *testing.T) {
+		got, err := GetStakeActivationSnapshot(ctx, newSource(693), []string{activeAddr, closedAddr, activatingAddr})
+		if err != nil {
+			t.Fatalf("GetStakeActivationSnapshot error: %v", err)
+		}
+		if got.Slot != 300000000 || got.Epoch != 694 {
+			t.Errorf("GetStakeActivationSnapshot slot, epoch = %d, %d, want 300000000, 694", got.Slot, got.Epoch)
+		}
+		if got.StakeHistory == nil || got.StakeHistory.Data.Parsed.Info[0].Epoch != 693 {
+			t.Errorf("GetStakeActivationSnapshot StakeHistory = %+v", got.StakeHistory)
+		}
+		// The closed stake account doesn't hide the others.
+		wantActivations := []*GetStakeActivationResponse{
+			{Active: 1000000000, Inactive: 1000000000, State: "active"},
+			nil,
+			{Active: 0, Inactive: 1000000000, State: "activating"},
+		}
+		if !reflect.DeepEqual(got.Activations, wantActivations) {
+			t.Errorf("GetStakeActivationSnapshot Activations = %+v, want %+v", got.Activations, wantActivations)
+		}
+		var accountErr *AccountError
+		if got.Errors[0] != nil || !errors.As(got.Errors[1], &accountErr) || accountErr.Address != closedAddr || !errors.Is(got.Errors[1], ErrAccountNotFound) || got.Errors[2] != nil {
+			t.Errorf("GetStakeActivationSnapshot Errors = %v", got.Errors)
+		}
+		if got.StakeAccounts[0] == nil || got.StakeAccounts[1] != nil || got.StakeAccounts[2] == nil {
+			t.Errorf("GetStakeActivationSnapshot StakeAccounts = %+v", got.StakeAccounts)
+		}
+	})
+
+	tooMany := make([]string, MaxSnapshotStakeAccounts+1)
+	for i := range tooMany {
+		tooMany[i] = activeAddr
+	}
+
+	tests := []struct {
+		name      string
+		source    *testSlotAccountSource
+		addresses []string
+		wantErr   error
+	}{
+		{
+			// The StakeHistory sysvar of the slot always has the entry of the previous epoch.
+			name:      "StakeHistory of the previous epoch",
+			source:    newSource(692),
+			addresses: []string{activeAddr},
+			wantErr:   ErrMissingStakeHistoryEntry,
+		},
+		{
+			name: "StakeHistory not found",
+			source: func() *testSlotAccountSource {
+				s := newSource(693)
+				s.DeleteAccount(StakeHistoryAccountAddress)
+				return s
+			}(),
+			addresses: []string{activeAddr},
+			wantErr:   ErrAccountNotFound,
+		},
+		{
+			name: "EpochSchedule not found",
+			source: func() *testSlotAccountSource {
+				s := newSource(693)
+				s.DeleteAccount(EpochScheduleAccountAddress)
+				return s
+			}(),
+			addresses: []string{activeAddr},
+			wantErr:   ErrAccountNotFound,
+		},
+		{
+			name: "EpochSchedule of wrong type",
+			source: func() *testSlotAccountSource {
+				s := newSource(693)
+				s.SetAccount(EpochScheduleAccountAddress, json.RawMessage(bytes.Replace(epochScheduleAccount, []byte(`"type": "epochSchedule"`), []byte(`"type": "rent"`), 1)))
+				return s
+			}(),
+			addresses: []string{activeAddr},
+			wantErr:   ErrWrongAccountType,
+		},
+		{
+			name: "EpochSchedule of wrong owner",
+			source: func() *testSlotAccountSource {
+				s := newSource(693)
+				s.SetAccount(EpochScheduleAccountAddress, json.RawMessage(bytes.Replace(epochScheduleAccount, []byte(SysvarProgramAddress), []byte(StakeProgramAddress), 1)))
+				return s
+			}(),
+			addresses: []string{activeAddr},
+			wantErr:   ErrWrongOwner,
+		},
+		{
+			name:      "too many addresses",
+			source:    newSource(693),
+			addresses: tooMany,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetStakeActivationSnapshot(ctx, tt.source, tt.addresses)
+			if err == nil {
+				t.Fatalf("GetStakeActivationSnapshot = %+v, want error", got)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("GetStakeActivationSnapshot error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
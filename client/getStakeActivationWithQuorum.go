@@ -0,0 +1,249 @@
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	sdkRpc "github.com/blocto/solana-go-sdk/rpc"
+	"github.com/skport/solana-rpc-client-extensions-go/types"
+
+	"golang.org/x/xerrors"
+)
+
+// QuorumOption configures GetStakeActivationWithQuorum.
+type QuorumOption func(*quorumOptions)
+
+type quorumOptions struct {
+	quorum         int
+	minContextSlot uint64
+	httpClient     *http.Client
+}
+
+// WithQuorum sets the number of endpoints which must return the same inputs, a majority by default.
+func WithQuorum(quorum int) QuorumOption {
+	return func(o *quorumOptions) {
+		o.quorum = quorum
+	}
+}
+
+// WithQuorumMinContextSlot sets the minContextSlot of the requests.
+// By default, it's the highest slot which a quorum of the endpoints have reached.
+func WithQuorumMinContextSlot(slot uint64) QuorumOption {
+	return func(o *quorumOptions) {
+		o.minContextSlot = slot
+	}
+}
+
+// WithQuorumHTTPClient sets the HTTP client of the requests, e.g. for a timeout.
+func WithQuorumHTTPClient(httpClient *http.Client) QuorumOption {
+	return func(o *quorumOptions) {
+		o.httpClient = httpClient
+	}
+}
+
+// QuorumResponse is the inputs of GetStakeActivation returned by an endpoint, identified by hashes.
+type QuorumResponse struct {
+	Endpoint string
+	// Slot is the context slot of the accounts.
+	Slot  uint64
+	Epoch uint64
+	// StakeAccountHash and StakeHistoryHash are the hex SHA-256 of the decoded accounts.
+	// StakeAccountHash is empty if the stake account doesn't exist.
+	StakeAccountHash string
+	StakeHistoryHash string
+	Err              error
+
+	stakeAccount        *types.StakeAccount
+	stakeHistoryAccount *types.StakeHistoryAccount
+}
+
+func (r *QuorumResponse) key() string {
+	return fmt.Sprintf("%d/%s/%s", r.Epoch, r.StakeAccountHash, r.StakeHistoryHash)
+}
+
+// QuorumError is returned by GetStakeActivationWithQuorum when fewer than Quorum endpoints returned the same inputs.
+type QuorumError struct {
+	Address        string
+	Quorum         int
+	MinContextSlot uint64
+	// Responses are the responses of all endpoints, in the order of the endpoints.
+	Responses []*QuorumResponse
+}
+
+func (e *QuorumError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%v: account: %s, quorum: %d, minContextSlot: %d", ErrNoQuorum, e.Address, e.Quorum, e.MinContextSlot)
+	for _, r := range e.Responses {
+		if r.Err != nil {
+			fmt.Fprintf(&b, "\n  %s: %v", r.Endpoint, r.Err)
+			continue
+		}
+		fmt.Fprintf(&b, "\n  %s: slot: %d, epoch: %d, stakeAccount: %s, stakeHistory: %s", r.Endpoint, r.Slot, r.Epoch, r.StakeAccountHash, r.StakeHistoryHash)
+	}
+	return b.String()
+}
+
+func (e *QuorumError) Unwrap() error {
+	return ErrNoQuorum
+}
+
+// GetStakeActivationWithQuorum fetches the epoch, the stake account and the StakeHistory sysvar from each endpoint at the same minContextSlot,
+// and calculates the activation only if a quorum of the endpoints returned the same inputs.
+// Otherwise it returns a *QuorumError with the responses of all endpoints.
+func GetStakeActivationWithQuorum(ctx context.Context, endpoints []string, stakeAccountAddress string, opts ...QuorumOption) (*GetStakeActivationResponse, error) {
+	o := quorumOptions{
+		quorum:     len(endpoints)/2 + 1,
+		httpClient: http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.quorum <= 0 || o.quorum > len(endpoints) {
+		return nil, fmt.Errorf("quorum %d of %d endpoints is out of range", o.quorum, len(endpoints))
+	}
+
+	sources := make([]*RpcAccountSource, len(endpoints))
+	for i, endpoint := range endpoints {
+		sources[i] = NewRpcAccountSource(sdkRpc.New(sdkRpc.WithEndpoint(endpoint), sdkRpc.WithHTTPClient(o.httpClient)))
+	}
+
+	minContextSlot := o.minContextSlot
+	if minContextSlot == 0 {
+		slot, responses := quorumSlot(ctx, endpoints, sources, o.quorum)
+		if slot == 0 {
+			return nil, &QuorumError{Address: stakeAccountAddress, Quorum: o.quorum, Responses: responses}
+		}
+		minContextSlot = slot
+	}
+
+	responses := make([]*QuorumResponse, len(endpoints))
+	var wg sync.WaitGroup
+	for i := range endpoints {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			responses[i] = fetchQuorumResponse(ctx, sources[i], stakeAccountAddress, minContextSlot)
+			responses[i].Endpoint = endpoints[i]
+		}(i)
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, xerrors.Errorf("wrap: %w", err)
+	}
+
+	agreed := agreedQuorumResponse(responses, o.quorum)
+	if agreed == nil {
+		return nil, &QuorumError{Address: stakeAccountAddress, Quorum: o.quorum, MinContextSlot: minContextSlot, Responses: responses}
+	}
+	if agreed.stakeAccount == nil {
+		return nil, &AccountError{Address: stakeAccountAddress, Err: ErrAccountNotFound}
+	}
+	return GetStakeActivation(stakeAccountAddress, agreed.Epoch, agreed.stakeAccount, agreed.stakeHistoryAccount, WithCurrentEpoch())
+}
+
+// quorumSlot returns the highest slot which quorum endpoints have reached, or 0 with the responses if fewer than quorum endpoints responded.
+func quorumSlot(ctx context.Context, endpoints []string, sources []*RpcAccountSource, quorum int) (uint64, []*QuorumResponse) {
+	responses := make([]*QuorumResponse, len(endpoints))
+	var wg sync.WaitGroup
+	for i := range endpoints {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			r := &QuorumResponse{Endpoint: endpoints[i]}
+			r.Epoch, r.Slot, r.Err = sources[i].GetEpochWithSlot(ctx, 0)
+			responses[i] = r
+		}(i)
+	}
+	wg.Wait()
+
+	var slots []uint64
+	for _, r := range responses {
+		if r.Err == nil {
+			slots = append(slots, r.Slot)
+		}
+	}
+	if len(slots) < quorum {
+		return 0, responses
+	}
+	sort.Slice(slots, func(i, j int) bool { return slots[i] > slots[j] })
+	return slots[quorum-1], responses
+}
+
+func fetchQuorumResponse(ctx context.Context, source *RpcAccountSource, stakeAccountAddress string, minContextSlot uint64) *QuorumResponse {
+	r := &QuorumResponse{}
+	epoch, _, err := source.GetEpochWithSlot(ctx, minContextSlot)
+	if err != nil {
+		r.Err = err
+		return r
+	}
+	r.Epoch = epoch
+
+	// Both accounts are fetched in a request, so that they are from the same slot.
+	accounts, slot, err := source.GetMultipleAccountsWithSlot(ctx, []string{stakeAccountAddress, StakeHistoryAccountAddress}, minContextSlot)
+	if err != nil {
+		r.Err = err
+		return r
+	}
+	r.Slot = slot
+
+	if accounts[0] != nil {
+		if r.stakeAccount, err = types.DecodeStakeAccount(accounts[0]); err != nil {
+			r.Err = &AccountError{Address: stakeAccountAddress, Err: err}
+			return r
+		}
+		if r.StakeAccountHash, err = hashAccount(r.stakeAccount); err != nil {
+			r.Err = err
+			return r
+		}
+	}
+	if accounts[1] == nil {
+		r.Err = &AccountError{Address: StakeHistoryAccountAddress, Err: ErrAccountNotFound}
+		return r
+	}
+	if r.stakeHistoryAccount, err = types.DecodeStakeHistoryAccount(accounts[1]); err != nil {
+		r.Err = &AccountError{Address: StakeHistoryAccountAddress, Err: err}
+		return r
+	}
+	if r.StakeHistoryHash, err = hashAccount(r.stakeHistoryAccount); err != nil {
+		r.Err = err
+	}
+	return r
+}
+
+// hashAccount hashes the decoded account, so that the fields which GetStakeActivation doesn't use, or their formatting, don't cause a disagreement.
+func hashAccount(account any) (string, error) {
+	b, err := json.Marshal(account)
+	if err != nil {
+		return "", xerrors.Errorf("failed to marshal account: %w", err)
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// agreedQuorumResponse returns a response of the group of identical responses which has at least quorum responses,
+// or nil if there is no such group, or more than one with a quorum of less than a majority.
+func agreedQuorumResponse(responses []*QuorumResponse, quorum int) *QuorumResponse {
+	groups := map[string][]*QuorumResponse{}
+	for _, r := range responses {
+		if r.Err == nil {
+			groups[r.key()] = append(groups[r.key()], r)
+		}
+	}
+	var agreed *QuorumResponse
+	for _, group := range groups {
+		if len(group) >= quorum {
+			if agreed != nil {
+				return nil
+			}
+			agreed = group[0]
+		}
+	}
+	return agreed
+}
@@ -0,0 +1,194 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newTestQuorumServer serves the fixture accounts at slot, with the stake account modified by tamper if it's not nil.
+func newTestQuorumServer(t *testing.T, slot uint64, tamper func(string) string) string {
+	t.Helper()
+
+	accounts := map[string]string{}
+	for _, address := range []string{testActivatingStakeAddr, StakeHistoryAccountAddress} {
+		b, err := os.ReadFile(testAccountSourceDir + "/" + address + ".json")
+		if err != nil {
+			t.Fatalf("failed to read fixture: %v", err)
+		}
+		accounts[address] = string(b)
+	}
+	if tamper != nil {
+		accounts[testActivatingStakeAddr] = tamper(accounts[testActivatingStakeAddr])
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("failed to unmarshal request: %v", err)
+		}
+		var config rpcConfig
+		_ = json.Unmarshal(req.Params[len(req.Params)-1], &config)
+		if config.MinContextSlot != nil && *config.MinContextSlot > slot {
+			io.WriteString(w, `{"jsonrpc":"2.0","id":1,"error":{"code":-32016,"message":"Minimum context slot has not been reached"}}`)
+			return
+		}
+
+		switch req.Method {
+		case "getEpochInfo":
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":1,"result":{"absoluteSlot":%d,"blockHeight":1,"epoch":816,"slotIndex":0,"slotsInEpoch":432000}}`, slot)
+		case "getMultipleAccounts":
+			var addresses []string
+			_ = json.Unmarshal(req.Params[0], &addresses)
+			values := make([]string, 0, len(addresses))
+			for _, address := range addresses {
+				if account, ok := accounts[address]; ok {
+					values = append(values, account)
+				} else {
+					values = append(values, "null")
+				}
+			}
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":1,"result":{"context":{"slot":%d},"value":[%s]}}`, slot, strings.Join(values, ","))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func tamperTestStake(stake string) func(string) string {
+	return func(account string) string {
+		return strings.Replace(account, `"stake": "1000000000"`, `"stake": "`+stake+`"`, 1)
+	}
+}
+
+func TestGetStakeActivationWithQuorum(t *testing.T) {
+	ctx := context.Background()
+
+	want, err := GetStakeActivationFromSource(ctx, newTestMemoryAccountSource(t), testActivatingStakeAddr)
+	if err != nil {
+		t.Fatalf("GetStakeActivationFromSource error = %v", err)
+	}
+
+	type server struct {
+		slot   uint64
+		tamper func(string) string
+	}
+	tests := []struct {
+		name    string
+		servers []server
+		opts    []QuorumOption
+		// wantDisagreement is the number of responses without an error in the QuorumError, or -1 for a response.
+		wantDisagreement int
+		wantSlot         uint64
+	}{
+		{
+			name:             "all agree",
+			servers:          []server{{100, nil}, {100, nil}, {100, nil}},
+			wantDisagreement: -1,
+		},
+		{
+			name:             "tampered minority",
+			servers:          []server{{100, nil}, {100, tamperTestStake("500000000")}, {100, nil}},
+			wantDisagreement: -1,
+		},
+		{
+			name:             "lagging minority",
+			servers:          []server{{100, nil}, {50, nil}, {120, nil}},
+			wantDisagreement: -1,
+		},
+		{
+			name:             "no majority",
+			servers:          []server{{100, nil}, {100, tamperTestStake("500000000")}, {100, tamperTestStake("600000000")}},
+			wantDisagreement: 3,
+			wantSlot:         100,
+		},
+		{
+			name:             "unanimity",
+			servers:          []server{{100, nil}, {100, tamperTestStake("500000000")}, {100, nil}},
+			opts:             []QuorumOption{WithQuorum(3)},
+			wantDisagreement: 3,
+			wantSlot:         100,
+		},
+		{
+			name:             "two groups with a quorum",
+			servers:          []server{{100, nil}, {100, nil}, {100, tamperTestStake("500000000")}, {100, tamperTestStake("500000000")}},
+			opts:             []QuorumOption{WithQuorum(2)},
+			wantDisagreement: 4,
+			wantSlot:         100,
+		},
+		{
+			name:             "minContextSlot ahead of the majority",
+			servers:          []server{{100, nil}, {50, nil}, {50, nil}},
+			opts:             []QuorumOption{WithQuorumMinContextSlot(100)},
+			wantDisagreement: 1,
+			wantSlot:         100,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var endpoints []string
+			for _, s := range tt.servers {
+				endpoints = append(endpoints, newTestQuorumServer(t, s.slot, s.tamper))
+			}
+
+			got, err := GetStakeActivationWithQuorum(ctx, endpoints, testActivatingStakeAddr, tt.opts...)
+			if tt.wantDisagreement < 0 {
+				if err != nil {
+					t.Fatalf("GetStakeActivationWithQuorum error = %v", err)
+				}
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("GetStakeActivationWithQuorum = %+v, want %+v", got, want)
+				}
+				return
+			}
+
+			var quorumErr *QuorumError
+			if !errors.As(err, &quorumErr) || !errors.Is(err, ErrNoQuorum) {
+				t.Fatalf("GetStakeActivationWithQuorum = %+v, %v, want QuorumError", got, err)
+			}
+			if quorumErr.MinContextSlot != tt.wantSlot || len(quorumErr.Responses) != len(endpoints) {
+				t.Errorf("QuorumError = %v", quorumErr)
+			}
+			responded := 0
+			for i, r := range quorumErr.Responses {
+				if r.Endpoint != endpoints[i] {
+					t.Errorf("Responses[%d].Endpoint = %s, want %s", i, r.Endpoint, endpoints[i])
+				}
+				if r.Err == nil {
+					responded++
+				}
+			}
+			if responded != tt.wantDisagreement {
+				t.Errorf("responses without an error = %d, want %d: %v", responded, tt.wantDisagreement, quorumErr)
+			}
+		})
+	}
+}
+
+func TestGetStakeActivationWithQuorum_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	endpoint := newTestQuorumServer(t, 100, nil)
+	if _, err := GetStakeActivationWithQuorum(ctx, []string{endpoint}, testActivatingStakeAddr, WithQuorum(2)); err == nil {
+		t.Error("GetStakeActivationWithQuorum with a quorum above the number of endpoints succeeded")
+	}
+
+	_, err := GetStakeActivationWithQuorum(ctx, []string{endpoint, endpoint}, testSystemAccountAddress)
+	if !errors.Is(err, ErrAccountNotFound) {
+		t.Errorf("GetStakeActivationWithQuorum error = %v, want %v", err, ErrAccountNotFound)
+	}
+}
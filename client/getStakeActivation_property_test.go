@@ -0,0 +1,224 @@
+package client
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"strconv"
+	"testing"
+
+	"github.com/skport/solana-rpc-client-extensions-go/simulator"
+	"github.com/skport/solana-rpc-client-extensions-go/types"
+)
+
+const (
+	testRentExemptReserve = 2282880
+)
+
+// genStakeHistory generates count entries of arbitrary stake history for the epochs before epoch, newest first.
+// Values are not consistent with each other on purpose, and include zero and values close to u64::MAX.
+// The entry of the epoch before epoch is always present, as in the StakeHistory sysvar of that epoch.
+func genStakeHistory(r *rand.Rand, epoch uint64, count int) *types.StakeHistoryAccount {
+	h := &types.StakeHistoryAccount{Owner: "Sysvar1111111111111111111111111111111111111"}
+	h.Data.Program = "sysvar"
+	h.Data.Parsed.Type = "stakeHistory"
+	h.Data.Parsed.Info = []types.StakeHistoryAccountInfo{}
+
+	for i := 0; i < count && uint64(i) < epoch; i++ {
+		// Leave a gap in the history now and then.
+		if i > 0 && r.Intn(20) == 0 {
+			continue
+		}
+
+		entry := types.StakeHistoryAccountInfo{Epoch: int(epoch) - 1 - i}
+		entry.StakeHistory.Effective = genLamports(r)
+		entry.StakeHistory.Activating = genLamports(r)
+		entry.StakeHistory.Deactivating = genLamports(r)
+		h.Data.Parsed.Info = append(h.Data.Parsed.Info, entry)
+	}
+	return h
+}
+
+func genLamports(r *rand.Rand) uint64 {
+	switch r.Intn(5) {
+	case 0:
+		return 0
+	case 1:
+		return uint64(r.Int63n(1_000_000))
+	case 2:
+		return math.MaxUint64 - uint64(r.Int63n(1_000_000))
+	default:
+		// Mainnet has ~4e17 lamports of effective stake.
+		return uint64(r.Int63n(400_000_000_000_000_000))
+	}
+}
+
+func newTestStakeAccount(lamports uint64, stake uint64, activationEpoch uint64, deactivationEpoch uint64) *types.StakeAccount {
+	a := &types.StakeAccount{
+		Lamports: lamports,
+		Owner:    StakeProgramAddress,
+	}
+	a.Data.Program = "stake"
+	a.Data.Space = 200
+	a.Data.Parsed.Type = "delegated"
+	a.Data.Parsed.Info.Meta.RentExemptReserve = strconv.FormatUint(testRentExemptReserve, 10)
+
+	s := &types.StakeAccountInfoStake{}
+	s.Delegation.ActivationEpoch = activationEpoch
+	s.Delegation.DeactivationEpoch = deactivationEpoch
+	s.Delegation.Stake = stake
+	s.Delegation.Voter = "FwR3PbjS5iyqzLiLugrBqKSa5EKZ4vK9SKs7eQXtT59f"
+	a.Data.Parsed.Info.Stake = s
+	return a
+}
+
+// checkActivationInvariants checks the invariants which must hold for any stake history.
+func checkActivationInvariants(t *testing.T, stakeAccount *types.StakeAccount, epoch uint64, stakeHistoryAccount *types.StakeHistoryAccount) {
+	t.Helper()
+
+	stake, err := stakeAccount.GetDelegationStake()
+	if err != nil {
+		t.Fatalf("GetDelegationStake error: %v", err)
+	}
+
+	effective, activating, deactivating, err := getSolanaStakeActivatingAndDeactivating("", stakeAccount, epoch, stakeHistoryAccount)
+	if err != nil {
+		t.Fatalf("getSolanaStakeActivatingAndDeactivating error: %v", err)
+	}
+	if effective > stake || activating > stake-effective {
+		t.Errorf("effective + activating > delegation, effective: %d, activating: %d, stake: %d", effective, activating, stake)
+	}
+	if deactivating > effective {
+		t.Errorf("deactivating > effective, effective: %d, deactivating: %d", effective, deactivating)
+	}
+	if activating > 0 && deactivating > 0 {
+		t.Errorf("activating and deactivating at once, activating: %d, deactivating: %d", activating, deactivating)
+	}
+
+	// Only accounts holding their active stake and rent-exempt reserve are consistent.
+	consistent := stakeAccount.Lamports >= effective && stakeAccount.Lamports-effective >= testRentExemptReserve
+
+	r, err := GetStakeActivation("", epoch, stakeAccount, stakeHistoryAccount)
+	if !consistent {
+		if !errors.Is(err, ErrArithmeticOverflow) {
+			t.Errorf("GetStakeActivation error = %v, want %v", err, ErrArithmeticOverflow)
+		}
+
+		r, err = GetStakeActivation("", epoch, stakeAccount, stakeHistoryAccount, WithSaturatingArithmetic())
+		if err != nil {
+			t.Fatalf("GetStakeActivation error: %v", err)
+		}
+		if r.Active != effective || r.Inactive != 0 || len(r.Diagnostics) == 0 {
+			t.Errorf("GetStakeActivation = %+v, want saturated", r)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("GetStakeActivation error: %v", err)
+	}
+	if r.Active != effective {
+		t.Errorf("Active = %d, want %d", r.Active, effective)
+	}
+	if r.Active+r.Inactive+testRentExemptReserve != stakeAccount.Lamports {
+		t.Errorf("Active + Inactive + rentExemptReserve = %d, lamports %d", r.Active+r.Inactive+testRentExemptReserve, stakeAccount.Lamports)
+	}
+	if len(r.Diagnostics) != 0 {
+		t.Errorf("Diagnostics = %v, want none", r.Diagnostics)
+	}
+}
+
+func TestGetStakeActivation_Invariants(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 2000; i++ {
+		epoch := uint64(r.Intn(1000)) + 1
+		history := genStakeHistory(r, epoch, r.Intn(64))
+
+		stake := genLamports(r)
+		lamports := stake + testRentExemptReserve + uint64(r.Intn(1_000_000))
+		if lamports < stake {
+			lamports = math.MaxUint64
+		}
+		activationEpoch := uint64(r.Int63n(int64(epoch) + 1))
+		deactivationEpoch := uint64(math.MaxUint64)
+		if r.Intn(2) == 0 {
+			deactivationEpoch = activationEpoch + uint64(r.Intn(32))
+		}
+
+		checkActivationInvariants(t, newTestStakeAccount(lamports, stake, activationEpoch, deactivationEpoch), epoch, history)
+	}
+}
+
+// TestGetStakeActivation_Monotonic checks that a stake only warms up before its deactivation and only cools down after it.
+func TestGetStakeActivation_Monotonic(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+
+	for i := 0; i < 200; i++ {
+		history := genStakeHistory(r, 200, 200)
+		stake := uint64(r.Int63n(1_000_000_000_000_000)) + 1
+		activationEpoch := uint64(r.Intn(100))
+		deactivationEpoch := activationEpoch + uint64(r.Intn(50)) + 1
+		stakeAccount := newTestStakeAccount(stake+testRentExemptReserve, stake, activationEpoch, deactivationEpoch)
+
+		var prev uint64
+		for epoch := activationEpoch; epoch < 200; epoch++ {
+			effective, _, _, err := getSolanaStakeActivatingAndDeactivating("", stakeAccount, epoch, history)
+			if err != nil {
+				t.Fatalf("getSolanaStakeActivatingAndDeactivating error: %v", err)
+			}
+
+			if epoch <= deactivationEpoch && effective < prev {
+				t.Errorf("effective stake decreased while warming up, epoch: %d, %d -> %d", epoch, prev, effective)
+			}
+			if epoch > deactivationEpoch && effective > prev {
+				t.Errorf("effective stake increased while cooling down, epoch: %d, %d -> %d", epoch, prev, effective)
+			}
+			prev = effective
+		}
+	}
+}
+
+// TestGetStakeActivation_Simulator checks the invariants with consistent stake history generated by the simulator.
+func TestGetStakeActivation_Simulator(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	c := simulator.NewCluster(0, 5_000_000_000_000)
+
+	for epoch := 0; epoch < 40; epoch++ {
+		address := "account" + strconv.Itoa(epoch)
+		if err := c.CreateStakeAccount(address, testRentExemptReserve+uint64(r.Int63n(1_000_000_000_000))+1, "authority"); err != nil {
+			t.Fatalf("CreateStakeAccount error: %v", err)
+		}
+		if err := c.Delegate(address, "voter"); err != nil {
+			t.Fatalf("Delegate error: %v", err)
+		}
+		if epoch > 0 && r.Intn(2) == 0 {
+			_ = c.Deactivate("account" + strconv.Itoa(r.Intn(epoch)))
+		}
+		c.AdvanceEpoch()
+
+		for _, address := range c.Addresses() {
+			stakeAccount, err := c.StakeAccount(address)
+			if err != nil {
+				t.Fatalf("StakeAccount error: %v", err)
+			}
+			checkActivationInvariants(t, stakeAccount, c.Epoch(), c.StakeHistoryAccount())
+		}
+	}
+}
+
+func FuzzGetStakeActivation(f *testing.F) {
+	f.Add(int64(0), uint64(1000000000), uint64(1002282880), uint64(816), uint64(math.MaxUint64), uint64(817), 3)
+	f.Add(int64(1), uint64(500000000000), uint64(500002282880), uint64(570), uint64(724), uint64(725), 10)
+	f.Add(int64(2), uint64(math.MaxUint64), uint64(math.MaxUint64), uint64(0), uint64(0), uint64(1), 1)
+	f.Add(int64(3), uint64(1), uint64(0), uint64(math.MaxUint64), uint64(math.MaxUint64), uint64(math.MaxUint64), 64)
+
+	f.Fuzz(func(t *testing.T, seed int64, stake uint64, lamports uint64, activationEpoch uint64, deactivationEpoch uint64, epoch uint64, count int) {
+		if count < 0 || count > 512 {
+			return
+		}
+		history := genStakeHistory(rand.New(rand.NewSource(seed)), epoch, count)
+		stakeAccount := newTestStakeAccount(lamports, stake, activationEpoch, deactivationEpoch)
+
+		checkActivationInvariants(t, stakeAccount, epoch, history)
+	})
+}
@@ -2,11 +2,12 @@ package client
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"math/rand"
 	"reflect"
 	"testing"
 
-	sdkRpc "github.com/blocto/solana-go-sdk/rpc"
 	"github.com/skport/solana-rpc-client-extensions-go/types"
 )
 
@@ -14,99 +15,260 @@ func TestClient_GetStakeActivation(t *testing.T) {
 	ctx := context.Background()
 
 	tests := []struct {
-		name    string
-		address string
-		want    *GetStakeActivationResponse
-		wantErr error
+		name     string
+		cluster  string // testdata/rpc/<cluster>
+		scenario string // testdata/rpc/<cluster>/<scenario>
+		// synthetic is whether the responses are in testdata/rpc/synthetic instead, i.e. not recorded.
+		synthetic bool
+		address   string
+		want      *GetStakeActivationResponse
+		wantErr   error
 	}{
-		// Stake Accounts
-		// https://explorer.solana.com/address/HmbKSyhneFd1Nd8BtW7ejBHTFrbnBsVA7JE6GpA9WjiX?cluster=devnet
-		// {
-		// 	name:    "Testnet Success 01: inactive stake",
-		// 	address: "HmbKSyhneFd1Nd8BtW7ejBHTFrbnBsVA7JE6GpA9WjiX",
-		// 	want: &GetStakeActivationResponse{
-		// 		Active:   0,
-		// 		Inactive: 950000000,  // lamports = 0.952282880 SOL
-		// 		State:    "inactive", // active or inactive or activating or deactivating
-		// 	},
-		// 	wantErr: nil,
-		// },
-		// {
-		// 	name:    "Testnet Success 02: activating stake",
-		// 	address: "HmbKSyhneFd1Nd8BtW7ejBHTFrbnBsVA7JE6GpA9WjiX",
-		// 	want: &GetStakeActivationResponse{
-		// 		Active:   0,
-		// 		Inactive: 950000000,
-		// 		State:    "activating",
-		// 	},
-		// 	wantErr: nil,
-		// },
-		// {
-		// 	name:    "Testnet Success 03: active stake",
-		// 	address: "HmbKSyhneFd1Nd8BtW7ejBHTFrbnBsVA7JE6GpA9WjiX",
-		// 	want: &GetStakeActivationResponse{
-		// 		Active:   950000000,
-		// 		Inactive: 0,
-		// 		State:    "active",
-		// 	},
-		// 	wantErr: nil,
-		// },
-		// {
-		// 	name:    "Testnet Success 04: deavtivating stake",
-		// 	address: "HmbKSyhneFd1Nd8BtW7ejBHTFrbnBsVA7JE6GpA9WjiX",
-		// 	want: &GetStakeActivationResponse{
-		// 		Active:   0,
-		// 		Inactive: 950000000,
-		// 		State:    "deactivating",
-		// 	},
-		// 	wantErr: nil,
-		// },
-	}
+		// ─────────────────────────────────────────────
+		// Devnet
+		// ─────────────────────────────────────────────
+		{
+			name:      "Synthetic devnet 01: inactive stake",
+			cluster:   "devnet",
+			scenario:  "inactive",
+			synthetic: true,
+			address:   "HmbKSyhneFd1Nd8BtW7ejBHTFrbnBsVA7JE6GpA9WjiX",
+			want: &GetStakeActivationResponse{
+				Active:   0,
+				Inactive: 950000000,  // lamports = 0.952282880 SOL
+				State:    "inactive", // active or inactive or activating or deactivating
+			},
+			wantErr: nil,
+		},
+		{
+			name:      "Synthetic devnet 02: activating stake",
+			cluster:   "devnet",
+			scenario:  "activating",
+			synthetic: true,
+			address:   "55pRDNDdQBNWfFRQy7eDSz2yyLs5n8ckbTGrtnD5miaQ",
+			want: &GetStakeActivationResponse{
+				Active:   0,
+				Inactive: 1000000000,
+				State:    "activating",
+			},
+			wantErr: nil,
+		},
+		{
+			name:      "Synthetic devnet 03: active stake",
+			cluster:   "devnet",
+			scenario:  "active",
+			synthetic: true,
+			address:   "55pRDNDdQBNWfFRQy7eDSz2yyLs5n8ckbTGrtnD5miaQ",
+			want: &GetStakeActivationResponse{
+				Active:   1000000000,
+				Inactive: 0,
+				State:    "active",
+			},
+			wantErr: nil,
+		},
+		{
+			name:      "Synthetic devnet 04: deactivating stake",
+			cluster:   "devnet",
+			scenario:  "deactivating",
+			synthetic: true,
+			address:   "55pRDNDdQBNWfFRQy7eDSz2yyLs5n8ckbTGrtnD5miaQ",
+			want: &GetStakeActivationResponse{
+				Active:   1000000000,
+				Inactive: 0,
+				State:    "deactivating",
+			},
+			wantErr: nil,
+		},
+		{
+			name:      "Synthetic devnet 05: partially deactivated stake",
+			cluster:   "devnet",
+			scenario:  "partiallyDeactivated",
+			synthetic: true,
+			address:   "55pRDNDdQBNWfFRQy7eDSz2yyLs5n8ckbTGrtnD5miaQ",
+			// The cluster deactivated more than 9% of the effective stake in epoch 818, so only a part of the stake cooled down.
+			want: &GetStakeActivationResponse{
+				Active:   235905548,
+				Inactive: 764094452,
+				State:    "deactivating",
+			},
+			wantErr: nil,
+		},
+		{
+			name:      "Synthetic devnet 06: fully cooled down stake",
+			cluster:   "devnet",
+			scenario:  "cooledDown",
+			synthetic: true,
+			address:   "55pRDNDdQBNWfFRQy7eDSz2yyLs5n8ckbTGrtnD5miaQ",
+			want: &GetStakeActivationResponse{
+				Active:   0,
+				Inactive: 1000000000,
+				State:    "inactive",
+			},
+			wantErr: nil,
+		},
 
-	// GetAccountInfo for stakeHistoryAccount
-	stakeHistoryAccountInfo, err := rpc.GetAccountInfoWithConfig(ctx, StakeHistoryAccountAddress,
-		sdkRpc.GetAccountInfoConfig{
-			Commitment: sdkRpc.CommitmentFinalized,
-			Encoding:   sdkRpc.AccountEncodingJsonParsed,
+		// ─────────────────────────────────────────────
+		// Mainnet
+		// ─────────────────────────────────────────────
+		{
+			name:      "Synthetic mainnet 01: inactive stake",
+			cluster:   "mainnet",
+			scenario:  "inactive",
+			synthetic: true,
+			address:   "Ec5ataLseL5XrUMjKDsNG5UVvSXLALEuBR9aUZ6kwC5a",
+			want: &GetStakeActivationResponse{
+				Active:   0,
+				Inactive: 1000000000,
+				State:    "inactive",
+			},
+			wantErr: nil,
+		},
+		{
+			name:      "Synthetic mainnet 02: activating stake",
+			cluster:   "mainnet",
+			scenario:  "activating",
+			synthetic: true,
+			address:   "FXgcCX7UetVyd6SEdqWEX798sxM59X42M2Ecx1BQq1vT",
+			want: &GetStakeActivationResponse{
+				Active:   0,
+				Inactive: 25000000000,
+				State:    "activating",
+			},
+			wantErr: nil,
+		},
+		{
+			name:      "Synthetic mainnet 03: active stake",
+			cluster:   "mainnet",
+			scenario:  "active",
+			synthetic: true,
+			address:   "HQYSDPjPoSw5gQifjKhDgEy5T65YNyVe8Nst6oGbnnEe",
+			want: &GetStakeActivationResponse{
+				Active:   7799841,
+				Inactive: 31831, // rewards that are not delegated yet
+				State:    "active",
+			},
+			wantErr: nil,
+		},
+		{
+			name:      "Synthetic mainnet 04: deactivating stake",
+			cluster:   "mainnet",
+			scenario:  "deactivating",
+			synthetic: true,
+			address:   "CYwQuCb6dAm9EcNfoU83mdAQbJJzkKJ2e7grXQLtDS5C",
+			want: &GetStakeActivationResponse{
+				Active:   500000000000,
+				Inactive: 0,
+				State:    "deactivating",
+			},
+			wantErr: nil,
+		},
+		{
+			name:      "Synthetic mainnet 05: partially deactivated stake",
+			cluster:   "mainnet",
+			scenario:  "partiallyDeactivated",
+			synthetic: true,
+			address:   "CYwQuCb6dAm9EcNfoU83mdAQbJJzkKJ2e7grXQLtDS5C",
+			// The cluster deactivated more than 9% of the effective stake in epoch 724, so only a part of the stake cooled down.
+			want: &GetStakeActivationResponse{
+				Active:   164629821438,
+				Inactive: 335370178562,
+				State:    "deactivating",
+			},
+			wantErr: nil,
+		},
+		{
+			name:      "Synthetic mainnet 06: fully cooled down stake",
+			cluster:   "mainnet",
+			scenario:  "cooledDown",
+			synthetic: true,
+			address:   "CYwQuCb6dAm9EcNfoU83mdAQbJJzkKJ2e7grXQLtDS5C",
+			want: &GetStakeActivationResponse{
+				Active:   0,
+				Inactive: 500000000000,
+				State:    "inactive",
+			},
+			wantErr: nil,
+		},
+		{
+			name:      "Synthetic mainnet 07: genesis-era bootstrap stake",
+			cluster:   "mainnet",
+			scenario:  "bootstrap",
+			synthetic: true,
+			address:   "mKE6gPj8ZuT9bPM8QeFpbaT4kDkQB6U2T3ATEyWaaTt",
+			want: &GetStakeActivationResponse{
+				Active:   2000000000000, // activation epoch is u64::MAX
+				Inactive: 0,
+				State:    "active",
+			},
+			wantErr: nil,
+		},
+		{
+			name:      "Synthetic mainnet 08: genesis-era bootstrap stake, partially deactivated",
+			cluster:   "mainnet",
+			scenario:  "bootstrapDeactivating",
+			synthetic: true,
+			address:   "mKE6gPj8ZuT9bPM8QeFpbaT4kDkQB6U2T3ATEyWaaTt",
+			want: &GetStakeActivationResponse{
+				Active:   658519285749, // the cluster deactivated more than the cooldown rate allows in epoch 724
+				Inactive: 1341480714251,
+				State:    "deactivating",
+			},
+			wantErr: nil,
+		},
+
+		// ─────────────────────────────────────────────
+		// Localnet (solana-test-validator)
+		// ─────────────────────────────────────────────
+		{
+			name:      "Synthetic localnet 01: bootstrap stake in the first epoch",
+			cluster:   "localnet",
+			scenario:  "bootstrapGenesis",
+			synthetic: true,
+			address:   "HpEGGyognko3D4rx6zXfHh6Jf8iWFMoEVZwDrzNXq61C",
+			want: &GetStakeActivationResponse{
+				Active:   500000000000, // the stake history is still empty
+				Inactive: 0,
+				State:    "active",
+			},
+			wantErr: nil,
+		},
+		{
+			name:      "Synthetic localnet 02: bootstrap stake",
+			cluster:   "localnet",
+			scenario:  "bootstrap",
+			synthetic: true,
+			address:   "HpEGGyognko3D4rx6zXfHh6Jf8iWFMoEVZwDrzNXq61C",
+			want: &GetStakeActivationResponse{
+				Active:   500000000000,
+				Inactive: 0,
+				State:    "active",
+			},
+			wantErr: nil,
+		},
+		{
+			name:      "Synthetic localnet 03: deactivating bootstrap stake",
+			cluster:   "localnet",
+			scenario:  "bootstrapDeactivating",
+			synthetic: true,
+			address:   "HpEGGyognko3D4rx6zXfHh6Jf8iWFMoEVZwDrzNXq61C",
+			want: &GetStakeActivationResponse{
+				Active:   414050000000, // 9% of the only stake of the cluster cools down per epoch
+				Inactive: 85950000000,
+				State:    "deactivating",
+			},
+			wantErr: nil,
 		},
-	)
-	if err != nil {
-		t.Errorf("GetAccountInfo error for stakeHistoryAccount: %v", err)
-		return
-	}
-	stakeHistoryAccount, err := ConvertStakeHistoryAccountInfo(stakeHistoryAccountInfo)
-	if err != nil {
-		t.Errorf("ConvertStakeHistoryAccountInfo error: %v", err)
-		return
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			// GetEpochInfo
-			epochInfo, err := rpc.GetEpochInfo(ctx)
-			if err != nil {
-				t.Errorf("GetEpochInfo error: %v", err)
-				return
-			}
-
-			// GetAccountInfo for stakeAccount
-			stakeAccountInfo, err := rpc.GetAccountInfoWithConfig(ctx, tt.address,
-				sdkRpc.GetAccountInfoConfig{
-					Commitment: sdkRpc.CommitmentFinalized,
-					Encoding:   sdkRpc.AccountEncodingJsonParsed,
-				},
-			)
-			if err != nil {
-				t.Errorf("GetAccountInfo error for stakeAccount: %v", err)
-				return
-			}
-			stakeAccount, err := ConvertStakeAccountInfo(stakeAccountInfo)
-			if err != nil {
-				t.Errorf("ConvertStakeAccountInfo error: %v", err)
-				return
+			rpc := newReplayRpcClient
+			if tt.synthetic {
+				rpc = newSyntheticRpcClient
 			}
+			source := NewRpcAccountSource(rpc(t, tt.cluster, tt.scenario))
 
-			r, err := GetStakeActivation(tt.address, epochInfo.Result.Epoch, stakeAccount, stakeHistoryAccount)
+			r, err := GetStakeActivationFromSource(ctx, source, tt.address)
 			if err != nil {
 				t.Errorf("GetStakeActivation error: %v", err)
 			} else {
@@ -317,16 +479,10 @@ func Test_getSolanaStakeActivatingAndDeactivating(t *testing.T) {
 							},
 							Stake: &types.StakeAccountInfoStake{
 								CreditsObserved: 612480517,
-								Delegation: struct {
-									ActivationEpoch    string  `json:"activationEpoch"`
-									DeactivationEpoch  string  `json:"deactivationEpoch"`
-									Stake              string  `json:"stake"`
-									Voter              string  `json:"voter"`
-									WarmupCooldownRate float64 `json:"warmupCooldownRate"`
-								}{
-									ActivationEpoch:    "816", // When the epoch at the time of delegation is 817, it becomes active.
-									DeactivationEpoch:  "18446744073709551615",
-									Stake:              "1000000000",
+								Delegation: types.Delegation{
+									ActivationEpoch:    816, // When the epoch at the time of delegation is 817, it becomes active.
+									DeactivationEpoch:  18446744073709551615,
+									Stake:              1000000000,
 									Voter:              "FwR3PbjS5iyqzLiLugrBqKSa5EKZ4vK9SKs7eQXtT59f",
 									WarmupCooldownRate: 0.25,
 								},
@@ -453,16 +609,10 @@ func Test_getSolanaStakeActivatingAndDeactivating(t *testing.T) {
 							},
 							Stake: &types.StakeAccountInfoStake{
 								CreditsObserved: 612480517,
-								Delegation: struct {
-									ActivationEpoch    string  `json:"activationEpoch"`
-									DeactivationEpoch  string  `json:"deactivationEpoch"`
-									Stake              string  `json:"stake"`
-									Voter              string  `json:"voter"`
-									WarmupCooldownRate float64 `json:"warmupCooldownRate"`
-								}{
-									ActivationEpoch:    "816", // When the delegated epoch reaches 817, it becomes active.
-									DeactivationEpoch:  "18446744073709551615",
-									Stake:              "1000000000",
+								Delegation: types.Delegation{
+									ActivationEpoch:    816, // When the delegated epoch reaches 817, it becomes active.
+									DeactivationEpoch:  18446744073709551615,
+									Stake:              1000000000,
 									Voter:              "FwR3PbjS5iyqzLiLugrBqKSa5EKZ4vK9SKs7eQXtT59f",
 									WarmupCooldownRate: 0.25,
 								},
@@ -589,16 +739,10 @@ func Test_getSolanaStakeActivatingAndDeactivating(t *testing.T) {
 							},
 							Stake: &types.StakeAccountInfoStake{
 								CreditsObserved: 612480517,
-								Delegation: struct {
-									ActivationEpoch    string  `json:"activationEpoch"`
-									DeactivationEpoch  string  `json:"deactivationEpoch"`
-									Stake              string  `json:"stake"`
-									Voter              string  `json:"voter"`
-									WarmupCooldownRate float64 `json:"warmupCooldownRate"`
-								}{
-									ActivationEpoch:    "816",
-									DeactivationEpoch:  "817", // When the epoch at the time of deactivation is 818, it becomes inactive.
-									Stake:              "1000000000",
+								Delegation: types.Delegation{
+									ActivationEpoch:    816,
+									DeactivationEpoch:  817, // When the epoch at the time of deactivation is 818, it becomes inactive.
+									Stake:              1000000000,
 									Voter:              "FwR3PbjS5iyqzLiLugrBqKSa5EKZ4vK9SKs7eQXtT59f",
 									WarmupCooldownRate: 0.25,
 								},
@@ -725,16 +869,10 @@ func Test_getSolanaStakeActivatingAndDeactivating(t *testing.T) {
 							},
 							Stake: &types.StakeAccountInfoStake{
 								CreditsObserved: 612480517,
-								Delegation: struct {
-									ActivationEpoch    string  `json:"activationEpoch"`
-									DeactivationEpoch  string  `json:"deactivationEpoch"`
-									Stake              string  `json:"stake"`
-									Voter              string  `json:"voter"`
-									WarmupCooldownRate float64 `json:"warmupCooldownRate"`
-								}{
-									ActivationEpoch:    "816",
-									DeactivationEpoch:  "818", // When the epoch at the time of deactivation is 819, it becomes inactive.
-									Stake:              "1000000000",
+								Delegation: types.Delegation{
+									ActivationEpoch:    816,
+									DeactivationEpoch:  818, // When the epoch at the time of deactivation is 819, it becomes inactive.
+									Stake:              1000000000,
 									Voter:              "FwR3PbjS5iyqzLiLugrBqKSa5EKZ4vK9SKs7eQXtT59f",
 									WarmupCooldownRate: 0.25,
 								},
@@ -865,16 +1003,10 @@ func Test_getSolanaStakeActivatingAndDeactivating(t *testing.T) {
 							},
 							Stake: &types.StakeAccountInfoStake{
 								CreditsObserved: 612480517,
-								Delegation: struct {
-									ActivationEpoch    string  `json:"activationEpoch"`
-									DeactivationEpoch  string  `json:"deactivationEpoch"`
-									Stake              string  `json:"stake"`
-									Voter              string  `json:"voter"`
-									WarmupCooldownRate float64 `json:"warmupCooldownRate"`
-								}{
-									ActivationEpoch:    "694", // Epoch at the time of delegation
-									DeactivationEpoch:  "18446744073709551615",
-									Stake:              "7799841",
+								Delegation: types.Delegation{
+									ActivationEpoch:    694, // Epoch at the time of delegation
+									DeactivationEpoch:  18446744073709551615,
+									Stake:              7799841,
 									Voter:              "J2nUHEAgZFRyuJbFjdqPrAa9gyWDuc7hErtDQHPhsYRp",
 									WarmupCooldownRate: 0.25,
 								},
@@ -974,3 +1106,370 @@ func Test_getSolanaStakeActivatingAndDeactivating(t *testing.T) {
 		})
 	}
 }
+
+func TestGetStakeActivation_LamportsBelowReserve(t *testing.T) {
+	// A fully active delegation of 1 SOL whose account only holds 0.5 SOL.
+	stakeAccount := newTestStakeAccount(500000000, 1000000000, 0, 18446744073709551615)
+	history := genStakeHistory(rand.New(rand.NewSource(0)), 0, 0)
+
+	tests := []struct {
+		name    string
+		opts    []Option
+		want    *GetStakeActivationResponse
+		wantErr error
+	}{
+		{
+			name:    "checked",
+			wantErr: ErrArithmeticOverflow,
+		},
+		{
+			name: "saturating",
+			opts: []Option{WithSaturatingArithmetic()},
+			want: &GetStakeActivationResponse{
+				Active:      1000000000,
+				Inactive:    0,
+				State:       "active",
+				Diagnostics: []string{"arithmetic overflow: 500000000 - 1002282880, invariant: lamports >= active + rentExemptReserve"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetStakeActivation("", 10, stakeAccount, history, tt.opts...)
+
+			if !reflect.DeepEqual(tt.want, got) {
+				t.Errorf("GetStakeActivation = %+v, want %+v", got, tt.want)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("wantErr = %v, want %v", err, tt.wantErr)
+			}
+			var arithmeticErr *ArithmeticError
+			if tt.wantErr != nil && !errors.As(err, &arithmeticErr) {
+				t.Errorf("error = %v, want *ArithmeticError", err)
+			}
+		})
+	}
+}
+
+func TestGetStakeActivation_Errors(t *testing.T) {
+	history := genStakeHistory(rand.New(rand.NewSource(0)), 10, 5)
+
+	invalidStake := newTestStakeAccount(1002282880, 1000000000, 0, 18446744073709551615)
+	invalidStake.Data.Parsed.Info.Meta.RentExemptReserve = "-1"
+
+	missingReserve := newTestStakeAccount(1002282880, 1000000000, 0, 18446744073709551615)
+	missingReserve.Data.Parsed.Info.Meta.RentExemptReserve = ""
+
+	tests := []struct {
+		name         string
+		epoch        uint64
+		stakeAccount *types.StakeAccount
+		opts         []Option
+		wantErr      error
+	}{
+		{
+			name:         "invalid field",
+			epoch:        10,
+			stakeAccount: invalidStake,
+			wantErr:      ErrInvalidField,
+		},
+		{
+			name:         "missing field",
+			epoch:        10,
+			stakeAccount: missingReserve,
+			wantErr:      ErrMissingField,
+		},
+		{
+			name:         "missing stake history entry",
+			epoch:        12,
+			stakeAccount: newTestStakeAccount(1002282880, 1000000000, 0, 18446744073709551615),
+			opts:         []Option{WithCurrentEpoch()},
+			wantErr:      ErrMissingStakeHistoryEntry,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := GetStakeActivation("HmbKSyPZ1WA3kVjrLSy5ojybiaSEfgfXwTgsQ1FwBT9C", tt.epoch, tt.stakeAccount, history, tt.opts...)
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("wantErr = %v, want %v", err, tt.wantErr)
+			}
+			var accountErr *AccountError
+			if !errors.As(err, &accountErr) || accountErr.Address != "HmbKSyPZ1WA3kVjrLSy5ojybiaSEfgfXwTgsQ1FwBT9C" || accountErr.Epoch != tt.epoch {
+				t.Errorf("error = %#v, want *AccountError", err)
+			}
+		})
+	}
+}
+
+func TestGetStakeActivation_FutureEpoch(t *testing.T) {
+	history := genStakeHistory(rand.New(rand.NewSource(0)), 10, 5)
+	stakeAccount := newTestStakeAccount(1002282880, 1000000000, 0, 18446744073709551615)
+
+	want, err := GetStakeActivation("", 10, stakeAccount, history, WithCurrentEpoch())
+	if err != nil {
+		t.Fatalf("GetStakeActivation error: %v", err)
+	}
+	// Without WithCurrentEpoch, the epochs after the newest StakeHistory entry are projections.
+	got, err := GetStakeActivation("", 12, stakeAccount, history)
+	if err != nil {
+		t.Fatalf("GetStakeActivation error: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetStakeActivation() = %+v, want %+v", got, want)
+	}
+}
+
+func TestGetStakeActivation_Validation(t *testing.T) {
+	history := genStakeHistory(rand.New(rand.NewSource(0)), 10, 5)
+
+	systemAccount := newTestStakeAccount(1002282880, 1000000000, 0, 18446744073709551615)
+	systemAccount.Owner = "11111111111111111111111111111111"
+
+	tokenAccount := newTestStakeAccount(1002282880, 1000000000, 0, 18446744073709551615)
+	tokenAccount.Data.Program = "spl-token"
+	tokenAccount.Data.Parsed.Type = "account"
+
+	unknownType := newTestStakeAccount(1002282880, 1000000000, 0, 18446744073709551615)
+	unknownType.Data.Parsed.Type = "stakeHistory"
+
+	wrongHistory := genStakeHistory(rand.New(rand.NewSource(0)), 10, 5)
+	wrongHistory.Owner = StakeProgramAddress
+
+	tests := []struct {
+		name                string
+		stakeAccount        *types.StakeAccount
+		stakeHistoryAccount *types.StakeHistoryAccount
+		opts                []Option
+		want                *GetStakeActivationResponse
+		wantErr             error
+	}{
+		{
+			name:                "valid",
+			stakeAccount:        newTestStakeAccount(1002282880, 1000000000, 0, 18446744073709551615),
+			stakeHistoryAccount: history,
+			want:                &GetStakeActivationResponse{Active: 1000000000, Inactive: 0, State: "active"},
+		},
+		{
+			name:                "system account",
+			stakeAccount:        systemAccount,
+			stakeHistoryAccount: history,
+			wantErr:             ErrWrongOwner,
+		},
+		{
+			name:                "token account",
+			stakeAccount:        tokenAccount,
+			stakeHistoryAccount: history,
+			wantErr:             ErrNotStakeAccount,
+		},
+		{
+			name:                "unknown type",
+			stakeAccount:        unknownType,
+			stakeHistoryAccount: history,
+			wantErr:             ErrNotStakeAccount,
+		},
+		{
+			name:                "stake history not owned by sysvar",
+			stakeAccount:        newTestStakeAccount(1002282880, 1000000000, 0, 18446744073709551615),
+			stakeHistoryAccount: wrongHistory,
+			wantErr:             ErrWrongOwner,
+		},
+		{
+			name:                "skip validation",
+			stakeAccount:        systemAccount,
+			stakeHistoryAccount: wrongHistory,
+			opts:                []Option{WithoutValidation()},
+			want:                &GetStakeActivationResponse{Active: 1000000000, Inactive: 0, State: "active"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetStakeActivation("", 10, tt.stakeAccount, tt.stakeHistoryAccount, tt.opts...)
+
+			if !reflect.DeepEqual(tt.want, got) {
+				t.Errorf("GetStakeActivation = %+v, want %+v", got, tt.want)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("wantErr = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetStakeActivation_States(t *testing.T) {
+	history := genStakeHistory(rand.New(rand.NewSource(0)), 10, 5)
+
+	uninitialized := &types.StakeAccount{Lamports: 1000000000, Owner: StakeProgramAddress}
+	uninitialized.Data.Program = "stake"
+	uninitialized.Data.Parsed.Type = "uninitialized"
+
+	rewardsPool := &types.StakeAccount{Lamports: 1000000000, Owner: StakeProgramAddress}
+	rewardsPool.Data.Program = "stake"
+	rewardsPool.Data.Parsed.Type = "rewardsPool"
+
+	initialized := newTestStakeAccount(1002282880, 0, 0, 0)
+	initialized.Data.Parsed.Type = "initialized"
+	initialized.Data.Parsed.Info.Stake = nil
+
+	tests := []struct {
+		name         string
+		stakeAccount *types.StakeAccount
+		want         *GetStakeActivationResponse
+	}{
+		{
+			name:         "uninitialized",
+			stakeAccount: uninitialized,
+			want:         &GetStakeActivationResponse{Active: 0, Inactive: 1000000000, State: "inactive"},
+		},
+		{
+			name:         "rewardsPool",
+			stakeAccount: rewardsPool,
+			want:         &GetStakeActivationResponse{Active: 0, Inactive: 1000000000, State: "inactive"},
+		},
+		{
+			name:         "initialized",
+			stakeAccount: initialized,
+			want:         &GetStakeActivationResponse{Active: 0, Inactive: 1000000000, State: "inactive"},
+		},
+		{
+			name:         "delegated",
+			stakeAccount: newTestStakeAccount(1002282880, 1000000000, 0, 18446744073709551615),
+			want:         &GetStakeActivationResponse{Active: 1000000000, Inactive: 0, State: "active"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetStakeActivation("", 10, tt.stakeAccount, history)
+			if err != nil {
+				t.Fatalf("GetStakeActivation error: %v", err)
+			}
+			if !reflect.DeepEqual(tt.want, got) {
+				t.Errorf("GetStakeActivation = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// The expected values are worked out by hand from the runtime's stake_and_activating and stake_activating_and_deactivating,
+// with a warmup/cooldown rate of 0.09 and a stake of 1000 lamports above the rent-exempt reserve.
+func TestGetStakeActivation_WarmupCooldown(t *testing.T) {
+	const (
+		address           = "55pRDNDdQBNWfFRQy7eDSz2yyLs5n8ckbTGrtnD5miaQ"
+		rentExemptReserve = 2282880
+	)
+	newStakeAccount := func(activationEpoch, deactivationEpoch string) *types.StakeAccount {
+		var stakeAccount types.StakeAccount
+		err := json.Unmarshal([]byte(`{
+			"data": {
+				"parsed": {
+					"info": {
+						"meta": {
+							"authorized": {"staker": "4vJ9JU1bJJE96FWSJKvHsmmFADCg4gpZQff4P3bkLKi", "withdrawer": "4vJ9JU1bJJE96FWSJKvHsmmFADCg4gpZQff4P3bkLKi"},
+							"lockup": {"custodian": "11111111111111111111111111111111", "epoch": 0, "unixTimestamp": 0},
+							"rentExemptReserve": "2282880"
+						},
+						"stake": {
+							"creditsObserved": 0,
+							"delegation": {
+								"activationEpoch": "`+activationEpoch+`",
+								"deactivationEpoch": "`+deactivationEpoch+`",
+								"stake": "1000",
+								"voter": "8qbHbw2BbbTHBW1sbeqakYXVKRQM8Ne7pLK7m6CVfeR",
+								"warmupCooldownRate": 0.25
+							}
+						}
+					},
+					"type": "delegated"
+				},
+				"program": "stake",
+				"space": 200
+			},
+			"executable": false,
+			"lamports": 2283880,
+			"owner": "Stake11111111111111111111111111111111111111",
+			"rentEpoch": 18446744073709551615
+		}`), &stakeAccount)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &stakeAccount
+	}
+	newStakeHistoryAccount := func(entries string) *types.StakeHistoryAccount {
+		var stakeHistoryAccount types.StakeHistoryAccount
+		err := json.Unmarshal([]byte(`{
+			"data": {"parsed": {"info": [`+entries+`], "type": "stakeHistory"}, "program": "sysvar", "space": 16392},
+			"executable": false,
+			"lamports": 114979200,
+			"owner": "Sysvar1111111111111111111111111111111111111",
+			"rentEpoch": 0
+		}`), &stakeHistoryAccount)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &stakeHistoryAccount
+	}
+
+	tests := []struct {
+		name              string
+		activationEpoch   string
+		deactivationEpoch string
+		history           string
+		epoch             uint64
+		want              *GetStakeActivationResponse
+	}{
+		{
+			// 1000 / 128000 of the 1000000 * 0.09 lamports which the cluster warmed up in epoch 10.
+			name:              "first epoch of warmup",
+			activationEpoch:   "10",
+			deactivationEpoch: "18446744073709551615",
+			history:           `{"epoch": 10, "stakeHistory": {"activating": 128000, "deactivating": 0, "effective": 1000000}}`,
+			epoch:             11,
+			want:              &GetStakeActivationResponse{Active: 703, Inactive: 297, State: "activating"},
+		},
+		{
+			// 1000 / 140000 of 1000000 * 0.09 is 642.857..., which the runtime truncates.
+			name:              "truncated like the runtime",
+			activationEpoch:   "10",
+			deactivationEpoch: "18446744073709551615",
+			history:           `{"epoch": 10, "stakeHistory": {"activating": 140000, "deactivating": 0, "effective": 1000000}}`,
+			epoch:             11,
+			want:              &GetStakeActivationResponse{Active: 642, Inactive: 358, State: "activating"},
+		},
+		{
+			// 703.125, then 297 / 300000 of 1000000 * 0.09, i.e. 89.1.
+			name:              "second epoch of warmup",
+			activationEpoch:   "10",
+			deactivationEpoch: "18446744073709551615",
+			history: `{"epoch": 11, "stakeHistory": {"activating": 300000, "deactivating": 0, "effective": 1000000}},
+				{"epoch": 10, "stakeHistory": {"activating": 128000, "deactivating": 0, "effective": 1000000}}`,
+			epoch: 12,
+			want:  &GetStakeActivationResponse{Active: 792, Inactive: 208, State: "activating"},
+		},
+		{
+			// Fully effective in epoch 11, then 1000 / 128000 of the 1000000 * 0.09 lamports which the cluster cooled down in epoch 20.
+			name:              "first epoch of cooldown",
+			activationEpoch:   "10",
+			deactivationEpoch: "20",
+			history: `{"epoch": 20, "stakeHistory": {"activating": 0, "deactivating": 128000, "effective": 1000000}},
+				{"epoch": 10, "stakeHistory": {"activating": 1000, "deactivating": 0, "effective": 1000000}}`,
+			epoch: 21,
+			want:  &GetStakeActivationResponse{Active: 297, Inactive: 703, State: "deactivating"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetStakeActivation(address, tt.epoch, newStakeAccount(tt.activationEpoch, tt.deactivationEpoch), newStakeHistoryAccount(tt.history))
+			if err != nil {
+				t.Fatalf("GetStakeActivation error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetStakeActivation = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
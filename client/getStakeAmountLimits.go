@@ -0,0 +1,118 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/skport/solana-rpc-client-extensions-go/types"
+
+	"golang.org/x/xerrors"
+)
+
+const (
+	// https://docs.anza.xyz/runtime/sysvars#rent
+	RentAccountAddress = "SysvarRent111111111111111111111111111111111"
+)
+
+// MinimumDelegationSource is implemented by an AccountSource which can also serve getStakeMinimumDelegation.
+type MinimumDelegationSource interface {
+	GetStakeMinimumDelegation(ctx context.Context) (uint64, error)
+}
+
+// StakeAmountLimits are the amounts a stake account must hold, to be checked before sending a transaction.
+type StakeAmountLimits struct {
+	// RentExemptReserve of a new stake account
+	RentExemptReserve uint64 `json:"rentExemptReserve"`
+	MinimumDelegation uint64 `json:"minimumDelegation"`
+}
+
+func GetRentAccount(ctx context.Context, source AccountSource) (*types.RentAccount, error) {
+	raw, err := source.GetAccount(ctx, RentAccountAddress)
+	if err != nil {
+		return nil, xerrors.Errorf("rentAccount: %s, wrap: %w", RentAccountAddress, err)
+	}
+	if raw == nil {
+		return nil, &AccountError{Address: RentAccountAddress, Err: ErrAccountNotFound}
+	}
+
+	var rentAccount types.RentAccount
+	if err := json.Unmarshal(raw, &rentAccount); err != nil {
+		return nil, xerrors.Errorf("failed to unmarshal to RentAccount: %w", err)
+	}
+	if rentAccount.Owner != SysvarProgramAddress {
+		return nil, &AccountError{Address: RentAccountAddress, Err: xerrors.Errorf("%w: owner: %s, want: %s", ErrWrongOwner, rentAccount.Owner, SysvarProgramAddress)}
+	}
+	if rentAccount.Data.Parsed.Type != "rent" {
+		return nil, &AccountError{Address: RentAccountAddress, Err: xerrors.Errorf("%w: type: %q, want: %q", ErrWrongAccountType, rentAccount.Data.Parsed.Type, "rent")}
+	}
+	return &rentAccount, nil
+}
+
+// GetStakeAmountLimits fetches the Rent sysvar and the minimum delegation from source, which must implement MinimumDelegationSource.
+func GetStakeAmountLimits(ctx context.Context, source AccountSource) (*StakeAmountLimits, error) {
+	minimumDelegationSource, ok := source.(MinimumDelegationSource)
+	if !ok {
+		return nil, xerrors.Errorf("%T doesn't implement MinimumDelegationSource", source)
+	}
+	minimumDelegation, err := minimumDelegationSource.GetStakeMinimumDelegation(ctx)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to get minimum delegation: %w", err)
+	}
+	rentAccount, err := GetRentAccount(ctx, source)
+	if err != nil {
+		return nil, xerrors.Errorf("wrap: %w", err)
+	}
+
+	return NewStakeAmountLimits(rentAccount, types.StakeStateV2Size, minimumDelegation)
+}
+
+// NewStakeAmountLimits returns the limits for the Rent sysvar and minimum delegation obtained elsewhere,
+// for a stake account with dataLen bytes of data (types.StakeStateV2Size for the accounts the stake program creates).
+func NewStakeAmountLimits(rentAccount *types.RentAccount, dataLen uint64, minimumDelegation uint64) (*StakeAmountLimits, error) {
+	rentExemptReserve, err := rentAccount.MinimumBalance(dataLen)
+	if err != nil {
+		return nil, xerrors.Errorf("wrap: %w", err)
+	}
+	return &StakeAmountLimits{
+		RentExemptReserve: rentExemptReserve,
+		MinimumDelegation: minimumDelegation,
+	}, nil
+}
+
+// ValidateNewStakeAccount checks the lamports of a stake account to be created and delegated.
+func (l *StakeAmountLimits) ValidateNewStakeAccount(lamports uint64) error {
+	if lamports < l.RentExemptReserve {
+		return xerrors.Errorf("%w: lamports: %d, rent-exempt reserve: %d", ErrInsufficientFunds, lamports, l.RentExemptReserve)
+	}
+	if stake := lamports - l.RentExemptReserve; stake < l.MinimumDelegation {
+		return xerrors.Errorf("%w: stake: %d, minimum: %d", ErrInsufficientDelegation, stake, l.MinimumDelegation)
+	}
+	return nil
+}
+
+// ValidateDelegate checks that the lamports of stakeAccount above its own rent-exempt reserve meet the minimum delegation,
+// as the DelegateStake instruction does.
+// https://github.com/anza-xyz/agave/blob/v2.0.0/programs/stake/src/stake_state.rs#L1455
+func (l *StakeAmountLimits) ValidateDelegate(stakeAccount *types.StakeAccount) error {
+	rentExemptReserve, err := stakeAccount.GetRentExemptReserve()
+	if err != nil {
+		return xerrors.Errorf("wrap: %w", err)
+	}
+	if stakeAccount.Lamports < rentExemptReserve {
+		return xerrors.Errorf("%w: lamports: %d, rent-exempt reserve: %d", ErrInsufficientFunds, stakeAccount.Lamports, rentExemptReserve)
+	}
+	if stake := stakeAccount.Lamports - rentExemptReserve; stake < l.MinimumDelegation {
+		return xerrors.Errorf("%w: stake: %d, minimum: %d", ErrInsufficientDelegation, stake, l.MinimumDelegation)
+	}
+	return nil
+}
+
+// SplitRequest returns a SplitRequest of lamports to a new stake account with these limits.
+func (l *StakeAmountLimits) SplitRequest(lamports uint64, destinationLamports uint64) SplitRequest {
+	return SplitRequest{
+		Lamports:                     lamports,
+		DestinationLamports:          destinationLamports,
+		DestinationRentExemptReserve: l.RentExemptReserve,
+		MinimumDelegation:            l.MinimumDelegation,
+	}
+}
@@ -0,0 +1,146 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/skport/solana-rpc-client-extensions-go/types"
+)
+
+func TestGetStakeAmountLimits(t *testing.T) {
+	ctx := context.Background()
+
+	rentAccount, err := os.ReadFile(testAccountSourceDir + "/" + RentAccountAddress + ".json")
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+	memory := NewMemoryAccountSource(816)
+	memory.SetAccount(RentAccountAddress, json.RawMessage(rentAccount))
+	memory.SetStakeMinimumDelegation(1000000000)
+
+	wrongTypeSource := NewMemoryAccountSource(816)
+	wrongTypeSource.SetAccount(RentAccountAddress, json.RawMessage(bytes.Replace(rentAccount, []byte(`"type": "rent"`), []byte(`"type": "epochSchedule"`), 1)))
+	wrongTypeSource.SetStakeMinimumDelegation(1000000000)
+
+	wrongOwnerSource := NewMemoryAccountSource(816)
+	wrongOwnerSource.SetAccount(RentAccountAddress, json.RawMessage(bytes.Replace(rentAccount, []byte(SysvarProgramAddress), []byte(StakeProgramAddress), 1)))
+	wrongOwnerSource.SetStakeMinimumDelegation(1000000000)
+
+	tests := []struct {
+		name    string
+		source  AccountSource
+		want    *StakeAmountLimits
+		wantErr error
+	}{
+		{
+			name:   "fixture",
+			source: NewFixtureAccountSource(testAccountSourceDir),
+			want:   &StakeAmountLimits{RentExemptReserve: 2282880, MinimumDelegation: 1},
+		},
+		{
+			name:   "memory",
+			source: memory,
+			want:   &StakeAmountLimits{RentExemptReserve: 2282880, MinimumDelegation: 1000000000},
+		},
+		{
+			name:    "rent not found",
+			source:  NewMemoryAccountSource(816),
+			wantErr: ErrAccountNotFound,
+		},
+		{
+			name:    "rent of wrong type",
+			source:  wrongTypeSource,
+			wantErr: ErrWrongAccountType,
+		},
+		{
+			name:    "rent of wrong owner",
+			source:  wrongOwnerSource,
+			wantErr: ErrWrongOwner,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetStakeAmountLimits(ctx, tt.source)
+
+			if !reflect.DeepEqual(tt.want, got) {
+				t.Errorf("GetStakeAmountLimits = %+v, want %+v", got, tt.want)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("GetStakeAmountLimits error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewStakeAmountLimits(t *testing.T) {
+	rentAccount, err := GetRentAccount(context.Background(), NewFixtureAccountSource(testAccountSourceDir))
+	if err != nil {
+		t.Fatalf("GetRentAccount error: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		dataLen uint64
+		want    *StakeAmountLimits
+	}{
+		{name: "stake account", dataLen: types.StakeStateV2Size, want: &StakeAmountLimits{RentExemptReserve: 2282880, MinimumDelegation: 1}},
+		// (128 + 0) bytes * 3480 lamports per byte-year * 2 years
+		{name: "no data", dataLen: 0, want: &StakeAmountLimits{RentExemptReserve: 890880, MinimumDelegation: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewStakeAmountLimits(rentAccount, tt.dataLen, 1)
+			if err != nil {
+				t.Fatalf("NewStakeAmountLimits error: %v", err)
+			}
+			if !reflect.DeepEqual(tt.want, got) {
+				t.Errorf("NewStakeAmountLimits = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStakeAmountLimits_Validate(t *testing.T) {
+	limits := &StakeAmountLimits{RentExemptReserve: 2282880, MinimumDelegation: 1000000000}
+
+	tests := []struct {
+		name     string
+		lamports uint64
+		wantErr  error
+	}{
+		{name: "minimum", lamports: 1002282880, wantErr: nil},
+		{name: "below minimum delegation", lamports: 1002282879, wantErr: ErrInsufficientDelegation},
+		{name: "below rent-exempt reserve", lamports: 2282879, wantErr: ErrInsufficientFunds},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := limits.ValidateNewStakeAccount(tt.lamports); !errors.Is(err, tt.wantErr) {
+				t.Errorf("ValidateNewStakeAccount error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			// An existing account with the same lamports and the rent-exempt reserve of its Meta.
+			stakeAccount := newTestStakeAccount(tt.lamports, 0, 0, 0)
+			stakeAccount.Data.Parsed.Type = "initialized"
+			stakeAccount.Data.Parsed.Info.Stake = nil
+			if err := limits.ValidateDelegate(stakeAccount); !errors.Is(err, tt.wantErr) {
+				t.Errorf("ValidateDelegate error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("no meta", func(t *testing.T) {
+		stakeAccount := &types.StakeAccount{Lamports: 1002282880}
+		stakeAccount.Data.Parsed.Type = "uninitialized"
+		if err := limits.ValidateDelegate(stakeAccount); !errors.Is(err, ErrNoMeta) {
+			t.Errorf("ValidateDelegate error = %v, wantErr %v", err, ErrNoMeta)
+		}
+	})
+}
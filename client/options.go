@@ -0,0 +1,46 @@
+package client
+
+// Option configures GetStakeActivation.
+type Option func(*options)
+
+type options struct {
+	saturating     bool
+	skipValidation bool
+	currentEpoch   bool
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithSaturatingArithmetic makes GetStakeActivation report 0 inactive stake instead of returning ErrArithmeticOverflow
+// when the lamports of the stake account are below its effective stake plus rent-exempt reserve, e.g. for an inconsistent snapshot.
+// The violated invariant is reported in GetStakeActivationResponse.Diagnostics.
+// Other checked calculations, such as the warmup and cooldown of GetStakeActivationStatus, still return ErrArithmeticOverflow.
+func WithSaturatingArithmetic() Option {
+	return func(o *options) {
+		o.saturating = true
+	}
+}
+
+// WithCurrentEpoch declares that epoch is the current epoch of the cluster the StakeHistory sysvar was read from,
+// so that a StakeHistory without the entry of the previous epoch is rejected with ErrMissingStakeHistoryEntry
+// instead of being read as a cluster without warmup or cooldown, e.g. when it was fetched before the epoch boundary.
+// Without it, epochs after the newest entry are allowed, to project the activation of future epochs.
+func WithCurrentEpoch() Option {
+	return func(o *options) {
+		o.currentEpoch = true
+	}
+}
+
+// WithoutValidation skips ValidateStakeAccount and ValidateStakeHistoryAccount,
+// e.g. for accounts built by hand in tests.
+func WithoutValidation() Option {
+	return func(o *options) {
+		o.skipValidation = true
+	}
+}
@@ -0,0 +1,249 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	sdkRpc "github.com/blocto/solana-go-sdk/rpc"
+)
+
+// Recorded JSON-RPC responses live in testdata/rpc/<cluster>/<scenario>/<method>[_<address>].json.
+// Tests replay them by default, so they don't depend on the network or on live account state.
+//
+// To refresh a scenario from a live cluster:
+//
+//	go test ./client -run TestClient_GetStakeActivation -record
+//
+// The endpoint of each cluster can be overridden with SOLANA_RPC_ENDPOINT_<CLUSTER> (e.g. SOLANA_RPC_ENDPOINT_DEVNET).
+// No scenario has been recorded from a live cluster yet, and TestReplayRpcClient_RecordReplay checks recording and replaying
+// against a local server instead.
+//
+// Synthetic responses live in testdata/rpc/synthetic/<cluster>/<scenario>. They are written by hand in the recorded format
+// to cover states which a live account can't be relied on to be in, and were not recorded from the cluster,
+// so their slots, transaction counts and StakeHistory entries are made up. -record leaves them alone.
+var record = flag.Bool("record", false, "record JSON-RPC responses from live clusters into testdata/rpc")
+
+const (
+	replayTestdataDir    = "testdata/rpc"
+	syntheticTestdataDir = "testdata/rpc/synthetic"
+)
+
+var recordEndpoints = map[string]string{
+	"mainnet": sdkRpc.MainnetRPCEndpoint,
+	"devnet":  sdkRpc.DevnetRPCEndpoint,
+	"testnet": sdkRpc.TestnetRPCEndpoint,
+	// solana-test-validator
+	"localnet": sdkRpc.LocalnetRPCEndpoint,
+}
+
+// newReplayRpcClient returns a RpcClient which replays (or records) the responses of a scenario.
+func newReplayRpcClient(t *testing.T, cluster string, scenario string) sdkRpc.RpcClient {
+	t.Helper()
+	return newReplayRpcClientAt(t, replayTestdataDir, cluster, scenario, *record)
+}
+
+// newReplayRpcClientAt returns a RpcClient which replays (or records) the responses of a scenario in dir.
+func newReplayRpcClientAt(t *testing.T, dir string, cluster string, scenario string, record bool) sdkRpc.RpcClient {
+	t.Helper()
+
+	endpoint, ok := recordEndpoints[cluster]
+	if !ok {
+		t.Fatalf("unknown cluster: %s", cluster)
+	}
+	if e := os.Getenv("SOLANA_RPC_ENDPOINT_" + strings.ToUpper(cluster)); e != "" {
+		endpoint = e
+	}
+
+	transport := &replayTransport{
+		dir:      filepath.Join(dir, cluster, scenario),
+		endpoint: endpoint,
+		record:   record,
+	}
+	if transport.record {
+		if err := os.MkdirAll(transport.dir, 0o755); err != nil {
+			t.Fatalf("failed to create %s: %v", transport.dir, err)
+		}
+	}
+
+	return sdkRpc.New(
+		sdkRpc.WithEndpoint(endpoint),
+		sdkRpc.WithHTTPClient(&http.Client{Transport: transport}),
+	)
+}
+
+// newSyntheticRpcClient returns a RpcClient which replays the synthetic responses of a scenario.
+func newSyntheticRpcClient(t *testing.T, cluster string, scenario string) sdkRpc.RpcClient {
+	t.Helper()
+
+	endpoint, ok := recordEndpoints[cluster]
+	if !ok {
+		t.Fatalf("unknown cluster: %s", cluster)
+	}
+	transport := &replayTransport{
+		dir:      filepath.Join(syntheticTestdataDir, cluster, scenario),
+		endpoint: endpoint,
+	}
+	return sdkRpc.New(
+		sdkRpc.WithEndpoint(endpoint),
+		sdkRpc.WithHTTPClient(&http.Client{Transport: transport}),
+	)
+}
+
+type replayTransport struct {
+	dir      string
+	endpoint string
+	record   bool
+}
+
+func (tr *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	req.Body.Close()
+
+	var rpcReq sdkRpc.JsonRpcRequest
+	if err := json.Unmarshal(body, &rpcReq); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal JSON-RPC request: %v", err)
+	}
+	path := filepath.Join(tr.dir, replayFileName(rpcReq))
+
+	if tr.record {
+		return tr.forward(req, body, path)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("no recorded response for %s (run `go test -record` to record it): %v", path, err)
+	}
+	return replayResponse(req, http.StatusOK, b), nil
+}
+
+func (tr *replayTransport) forward(req *http.Request, body []byte, path string) (*http.Response, error) {
+	liveReq, err := http.NewRequestWithContext(req.Context(), http.MethodPost, tr.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	liveReq.Header = req.Header.Clone()
+
+	res, err := http.DefaultTransport.RoundTrip(liveReq)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode == http.StatusOK {
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, b, "", "  "); err != nil {
+			return nil, fmt.Errorf("failed to indent response: %v", err)
+		}
+		indented.WriteByte('\n')
+		if err := os.WriteFile(path, indented.Bytes(), 0o644); err != nil {
+			return nil, err
+		}
+	}
+	return replayResponse(req, res.StatusCode, b), nil
+}
+
+// replayFileName names a recording after the method and its first string param (the address for account queries).
+func replayFileName(req sdkRpc.JsonRpcRequest) string {
+	name := req.Method
+	if len(req.Params) > 0 {
+		if address, ok := req.Params[0].(string); ok {
+			name += "_" + filepath.Base(address)
+		}
+	}
+	return name + ".json"
+}
+
+func replayResponse(req *http.Request, statusCode int, body []byte) *http.Response {
+	return &http.Response{
+		Status:        http.StatusText(statusCode),
+		StatusCode:    statusCode,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// TestReplayRpcClient_RecordReplay records the responses of a JSON-RPC server like -record does with a live cluster,
+// and replays them after the server is gone.
+func TestReplayRpcClient_RecordReplay(t *testing.T) {
+	ctx := context.Background()
+	const address = "55pRDNDdQBNWfFRQy7eDSz2yyLs5n8ckbTGrtnD5miaQ"
+	want := &GetStakeActivationResponse{Active: 1000000000, Inactive: 0, State: "active"}
+
+	// The server answers with the synthetic responses of a scenario in compact JSON, like a cluster does.
+	fixtures := filepath.Join(syntheticTestdataDir, "devnet", "active")
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		var req sdkRpc.JsonRpcRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("failed to unmarshal request: %v", err)
+		}
+		b, err := os.ReadFile(filepath.Join(fixtures, replayFileName(req)))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, b); err != nil {
+			t.Errorf("failed to compact %s: %v", replayFileName(req), err)
+		}
+		w.Write(compact.Bytes())
+	}))
+	defer server.Close()
+	t.Setenv("SOLANA_RPC_ENDPOINT_DEVNET", server.URL)
+
+	dir := t.TempDir()
+	for _, record := range []bool{true, false} {
+		source := NewRpcAccountSource(newReplayRpcClientAt(t, dir, "devnet", "active", record))
+		r, err := GetStakeActivationFromSource(ctx, source, address)
+		if err != nil {
+			t.Fatalf("record: %v, GetStakeActivation error: %v", record, err)
+		}
+		if !reflect.DeepEqual(r, want) {
+			t.Errorf("record: %v, GetStakeActivation = %v, want %v", record, r, want)
+		}
+
+		if record {
+			server.Close()
+		}
+	}
+
+	if atomic.LoadInt32(&requests) == 0 {
+		t.Fatalf("no request reached the server")
+	}
+	entries, err := os.ReadDir(fixtures)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", fixtures, err)
+	}
+	for _, entry := range entries {
+		wantFile, _ := os.ReadFile(filepath.Join(fixtures, entry.Name()))
+		gotFile, err := os.ReadFile(filepath.Join(dir, "devnet", "active", entry.Name()))
+		if err != nil {
+			t.Errorf("%s was not recorded: %v", entry.Name(), err)
+		} else if !bytes.Equal(gotFile, wantFile) {
+			t.Errorf("recorded %s = %s, want %s", entry.Name(), gotFile, wantFile)
+		}
+	}
+}
@@ -0,0 +1,711 @@
+package client
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/blocto/solana-go-sdk/common"
+	sdktypes "github.com/blocto/solana-go-sdk/types"
+	"github.com/skport/solana-rpc-client-extensions-go/types"
+
+	"golang.org/x/xerrors"
+)
+
+const (
+	// https://docs.anza.xyz/runtime/programs#system-program
+	SystemProgramAddress = "11111111111111111111111111111111"
+
+	// warmupCooldownRate of a new delegation, which is deprecated and no longer read by the runtime.
+	// https://github.com/anza-xyz/agave/blob/v2.0.0/sdk/program/src/stake/state.rs#L27
+	defaultWarmupCooldownRate = 0.25
+)
+
+type SimulateStakeTransactionRequest struct {
+	Epoch uint64
+	// UnixTimestamp of the Clock sysvar, for lockups.
+	UnixTimestamp int64
+	// Accounts read by the instructions by address: stake accounts, and system accounts prefunded to become stake accounts.
+	Accounts     map[string]*types.StakeAccount
+	StakeHistory *types.StakeHistoryAccount
+	// Limits of new stake accounts and delegations, see GetStakeAmountLimits.
+	Limits StakeAmountLimits
+	// FutureEpochs is the number of epochs after Epoch to predict the activation for.
+	FutureEpochs uint64
+}
+
+type SimulateStakeTransactionResponse struct {
+	// Accounts after the transaction. An account closed by Merge or Withdraw is nil.
+	Accounts map[string]*types.StakeAccount
+	// Activations of the stake accounts at Epoch, Epoch+1, ..., Epoch+FutureEpochs.
+	Activations map[string][]*GetStakeActivationResponse
+}
+
+// SimulateStakeTransaction applies the stake and system instructions of a transaction to req.Accounts locally,
+// and predicts the activation of the resulting stake accounts. Instructions of other programs are ignored.
+//
+// Accounts not in req.Accounts are assumed to be empty. Signatures are not checked, the transaction is assumed
+// to be signed by the right authorities.
+// For epochs after req.Epoch, the rest of the cluster is assumed to stay as in the newest StakeHistory entry,
+// so the prediction of warmup and cooldown is an estimate.
+func SimulateStakeTransaction(req *SimulateStakeTransactionRequest, instructions []sdktypes.Instruction) (*SimulateStakeTransactionResponse, error) {
+	if err := checkStakeHistoryAccount(req.StakeHistory, req.Epoch); err != nil {
+		return nil, &AccountError{Address: StakeHistoryAccountAddress, Epoch: req.Epoch, Err: err}
+	}
+
+	s := &stakeSimulation{req: req, accounts: map[string]*types.StakeAccount{}}
+	for address, account := range req.Accounts {
+		s.accounts[address] = copyStakeAccount(account)
+	}
+
+	for i, instruction := range instructions {
+		var err error
+		switch instruction.ProgramID {
+		case common.StakeProgramID:
+			err = s.applyStakeInstruction(instruction)
+		case common.SystemProgramID:
+			err = s.applySystemInstruction(instruction)
+		}
+		if err != nil {
+			return nil, xerrors.Errorf("instruction: %d, wrap: %w", i, err)
+		}
+	}
+
+	res := &SimulateStakeTransactionResponse{
+		Accounts:    map[string]*types.StakeAccount{},
+		Activations: map[string][]*GetStakeActivationResponse{},
+	}
+	for address, account := range s.accounts {
+		if account == nil || account.Owner == StakeProgramAddress {
+			res.Accounts[address] = account
+		}
+	}
+
+	history, err := s.projectStakeHistory(res.Accounts)
+	if err != nil {
+		return nil, xerrors.Errorf("wrap: %w", err)
+	}
+	for address, account := range res.Accounts {
+		if account == nil {
+			continue
+		}
+		for epoch := req.Epoch; epoch <= req.Epoch+req.FutureEpochs; epoch++ {
+			activation, err := GetStakeActivation(address, epoch, account, history)
+			if err != nil {
+				return nil, xerrors.Errorf("wrap: %w", err)
+			}
+			res.Activations[address] = append(res.Activations[address], activation)
+		}
+	}
+	return res, nil
+}
+
+type stakeSimulation struct {
+	req      *SimulateStakeTransactionRequest
+	accounts map[string]*types.StakeAccount
+}
+
+// projectStakeHistory extends the StakeHistory sysvar up to the last predicted epoch. Each projected entry is the newest entry,
+// with the stake of the accounts before the transaction replaced by the stake of the accounts after it.
+// Accounts without a delegation don't contribute to it.
+func (s *stakeSimulation) projectStakeHistory(accounts map[string]*types.StakeAccount) (*types.StakeHistoryAccount, error) {
+	history := *s.req.StakeHistory
+	entries := s.req.StakeHistory.Data.Parsed.Info
+	if len(entries) == 0 || s.req.FutureEpochs == 0 {
+		return &history, nil
+	}
+
+	newest := entries[0]
+	for _, entry := range entries {
+		if entry.Epoch > newest.Epoch {
+			newest = entry
+		}
+	}
+	rest := newest
+	for address, account := range s.req.Accounts {
+		effective, activating, deactivating, err := getSolanaStakeActivatingAndDeactivating(address, account, uint64(newest.Epoch), s.req.StakeHistory)
+		if errors.Is(err, ErrNotDelegated) {
+			continue
+		}
+		if err != nil {
+			return nil, &AccountError{Address: address, Epoch: uint64(newest.Epoch), Err: err}
+		}
+		rest.StakeHistory.Effective = saturatingSub(rest.StakeHistory.Effective, effective)
+		rest.StakeHistory.Activating = saturatingSub(rest.StakeHistory.Activating, activating)
+		rest.StakeHistory.Deactivating = saturatingSub(rest.StakeHistory.Deactivating, deactivating)
+	}
+
+	for epoch := uint64(newest.Epoch) + 1; epoch < s.req.Epoch+s.req.FutureEpochs; epoch++ {
+		entry := rest
+		entry.Epoch = int(epoch)
+		for address, account := range accounts {
+			if account == nil {
+				continue
+			}
+			effective, activating, deactivating, err := getSolanaStakeActivatingAndDeactivating(address, account, epoch, &history)
+			if errors.Is(err, ErrNotDelegated) {
+				continue
+			}
+			if err != nil {
+				return nil, &AccountError{Address: address, Epoch: epoch, Err: err}
+			}
+			const invariant = "cluster stake <= u64::MAX"
+			if entry.StakeHistory.Effective, err = checkedAdd(entry.StakeHistory.Effective, effective, invariant); err != nil {
+				return nil, &AccountError{Address: address, Epoch: epoch, Err: err}
+			}
+			if entry.StakeHistory.Activating, err = checkedAdd(entry.StakeHistory.Activating, activating, invariant); err != nil {
+				return nil, &AccountError{Address: address, Epoch: epoch, Err: err}
+			}
+			if entry.StakeHistory.Deactivating, err = checkedAdd(entry.StakeHistory.Deactivating, deactivating, invariant); err != nil {
+				return nil, &AccountError{Address: address, Epoch: epoch, Err: err}
+			}
+		}
+		// Entries are ordered from the newest epoch.
+		history.Data.Parsed.Info = append([]types.StakeHistoryAccountInfo{entry}, history.Data.Parsed.Info...)
+	}
+	return &history, nil
+}
+
+// https://github.com/anza-xyz/agave/blob/v2.0.0/programs/stake/src/stake_instruction.rs
+func (s *stakeSimulation) applyStakeInstruction(instruction sdktypes.Instruction) error {
+	r := &instructionDataReader{data: instruction.Data}
+	switch variant := r.uint32(); variant {
+	case 0: // Initialize
+		staker, withdrawer := r.publicKey(), r.publicKey()
+		unixTimestamp, epoch, custodian := r.int64(), r.uint64(), r.publicKey()
+		if r.err != nil {
+			return r.err
+		}
+		return s.initialize(instruction, staker, withdrawer, unixTimestamp, epoch, custodian)
+	case 1: // Authorize
+		newAuthority, authorize := r.publicKey(), StakeAuthorize(r.uint32())
+		if r.err != nil {
+			return r.err
+		}
+		return s.authorize(instruction, newAuthority, authorize)
+	case 2: // DelegateStake
+		return s.delegate(instruction)
+	case 3: // Split
+		lamports := r.uint64()
+		if r.err != nil {
+			return r.err
+		}
+		return s.split(instruction, lamports)
+	case 4: // Withdraw
+		lamports := r.uint64()
+		if r.err != nil {
+			return r.err
+		}
+		return s.withdraw(instruction, lamports)
+	case 5: // Deactivate
+		return s.deactivate(instruction)
+	case 6: // SetLockup
+		unixTimestamp := r.optionalInt64()
+		epoch := r.optionalUint64()
+		custodian := r.optionalPublicKey()
+		if r.err != nil {
+			return r.err
+		}
+		return s.setLockup(instruction, unixTimestamp, epoch, custodian)
+	case 7: // Merge
+		return s.merge(instruction)
+	case 8: // AuthorizeWithSeed
+		newAuthority, authorize := r.publicKey(), StakeAuthorize(r.uint32())
+		if r.err != nil {
+			return r.err
+		}
+		return s.authorize(instruction, newAuthority, authorize)
+	default:
+		return xerrors.Errorf("%w: stake instruction: %d", ErrUnsupportedInstruction, variant)
+	}
+}
+
+// applySystemInstruction tracks accounts which become stake accounts and transfers to tracked accounts.
+// https://github.com/anza-xyz/agave/blob/v2.0.0/sdk/program/src/system_instruction.rs
+func (s *stakeSimulation) applySystemInstruction(instruction sdktypes.Instruction) error {
+	r := &instructionDataReader{data: instruction.Data}
+	switch r.uint32() {
+	case 0: // CreateAccount
+		lamports, space, owner := r.uint64(), r.uint64(), r.publicKey()
+		if r.err != nil {
+			return r.err
+		}
+		return s.createAccount(instruction, 1, lamports, space, owner)
+	case 1: // Assign
+		owner := r.publicKey()
+		if r.err != nil {
+			return r.err
+		}
+		return s.assign(instruction, 0, nil, &owner)
+	case 2: // Transfer
+		lamports := r.uint64()
+		if r.err != nil {
+			return r.err
+		}
+		return s.transfer(instruction, 0, 1, lamports)
+	case 3: // CreateAccountWithSeed
+		_, _, lamports, space, owner := r.publicKey(), r.string(), r.uint64(), r.uint64(), r.publicKey()
+		if r.err != nil {
+			return r.err
+		}
+		return s.createAccount(instruction, 1, lamports, space, owner)
+	case 8: // Allocate
+		space := r.uint64()
+		if r.err != nil {
+			return r.err
+		}
+		return s.assign(instruction, 0, &space, nil)
+	case 9: // AllocateWithSeed
+		_, _, space, owner := r.publicKey(), r.string(), r.uint64(), r.publicKey()
+		if r.err != nil {
+			return r.err
+		}
+		return s.assign(instruction, 0, &space, &owner)
+	case 10: // AssignWithSeed
+		_, _, owner := r.publicKey(), r.string(), r.publicKey()
+		if r.err != nil {
+			return r.err
+		}
+		return s.assign(instruction, 0, nil, &owner)
+	case 11: // TransferWithSeed
+		lamports := r.uint64()
+		if r.err != nil {
+			return r.err
+		}
+		return s.transfer(instruction, 0, 2, lamports)
+	}
+	return nil
+}
+
+func (s *stakeSimulation) createAccount(instruction sdktypes.Instruction, index int, lamports uint64, space uint64, owner common.PublicKey) error {
+	address, err := accountAddress(instruction, index)
+	if err != nil {
+		return err
+	}
+	if account := s.accounts[address]; account != nil && (account.Lamports > 0 || account.Owner == StakeProgramAddress) {
+		return &AccountError{Address: address, Err: fmt.Errorf("account already in use")}
+	}
+	if owner != common.StakeProgramID {
+		return nil
+	}
+	s.accounts[address] = newUninitializedStakeAccount(lamports, space)
+	return nil
+}
+
+// assign tracks the account of Allocate and Assign. A split destination is typically allocated and assigned to the stake program.
+func (s *stakeSimulation) assign(instruction sdktypes.Instruction, index int, space *uint64, owner *common.PublicKey) error {
+	address, err := accountAddress(instruction, index)
+	if err != nil {
+		return err
+	}
+	account := s.accounts[address]
+	if account == nil {
+		if owner == nil || *owner != common.StakeProgramID {
+			if space == nil {
+				return nil
+			}
+			// The account may be assigned to the stake program by a later instruction.
+			account = &types.StakeAccount{Owner: SystemProgramAddress}
+		} else {
+			account = &types.StakeAccount{}
+		}
+		s.accounts[address] = account
+	}
+	if space != nil {
+		account.Data.Space = *space
+	}
+	if owner != nil {
+		account.Owner = owner.ToBase58()
+		if *owner == common.StakeProgramID && account.Data.Parsed.Type == "" {
+			*account = *newUninitializedStakeAccount(account.Lamports, account.Data.Space)
+		}
+	}
+	return nil
+}
+
+func (s *stakeSimulation) transfer(instruction sdktypes.Instruction, fromIndex int, toIndex int, lamports uint64) error {
+	from, err := accountAddress(instruction, fromIndex)
+	if err != nil {
+		return err
+	}
+	to, err := accountAddress(instruction, toIndex)
+	if err != nil {
+		return err
+	}
+	if account := s.accounts[from]; account != nil {
+		if account.Lamports < lamports {
+			return &AccountError{Address: from, Err: xerrors.Errorf("%w: lamports: %d, transfer: %d", ErrInsufficientFunds, account.Lamports, lamports)}
+		}
+		account.Lamports -= lamports
+	}
+	if account := s.accounts[to]; account != nil {
+		account.Lamports += lamports
+	} else if _, closed := s.accounts[to]; !closed {
+		// e.g. prefunding a split destination before it is allocated
+		s.accounts[to] = &types.StakeAccount{Owner: SystemProgramAddress, Lamports: lamports}
+	}
+	return nil
+}
+
+func (s *stakeSimulation) initialize(instruction sdktypes.Instruction, staker, withdrawer common.PublicKey, unixTimestamp int64, epoch uint64, custodian common.PublicKey) error {
+	address, account, err := s.stakeAccount(instruction, 0)
+	if err != nil {
+		return err
+	}
+	if state, _ := account.GetState(); state != types.StakeStateUninitialized || account.Data.Space != types.StakeStateV2Size {
+		return &AccountError{Address: address, Err: xerrors.Errorf("%w: state: %s, space: %d", ErrNotStakeAccount, account.Data.Parsed.Type, account.Data.Space)}
+	}
+	if account.Lamports < s.req.Limits.RentExemptReserve {
+		return &AccountError{Address: address, Err: xerrors.Errorf("%w: lamports: %d, rent-exempt reserve: %d", ErrInsufficientFunds, account.Lamports, s.req.Limits.RentExemptReserve)}
+	}
+
+	account.Data.Parsed.Type = string(types.StakeStateInitialized)
+	meta := &account.Data.Parsed.Info.Meta
+	meta.RentExemptReserve = strconv.FormatUint(s.req.Limits.RentExemptReserve, 10)
+	meta.Authorized.Staker = staker.ToBase58()
+	meta.Authorized.Withdrawer = withdrawer.ToBase58()
+	meta.Lockup.UnixTimestamp = uint64(unixTimestamp)
+	meta.Lockup.Epoch = epoch
+	meta.Lockup.Custodian = custodian.ToBase58()
+	return nil
+}
+
+func (s *stakeSimulation) authorize(instruction sdktypes.Instruction, newAuthority common.PublicKey, authorize StakeAuthorize) error {
+	address, account, err := s.stakeAccount(instruction, 0)
+	if err != nil {
+		return err
+	}
+	if !account.HasMeta() {
+		return &AccountError{Address: address, Err: ErrNoMeta}
+	}
+
+	switch authorize {
+	case StakeAuthorizeStaker:
+		account.Data.Parsed.Info.Meta.Authorized.Staker = newAuthority.ToBase58()
+	case StakeAuthorizeWithdrawer:
+		account.Data.Parsed.Info.Meta.Authorized.Withdrawer = newAuthority.ToBase58()
+	default:
+		return xerrors.Errorf("%w: stake authorize: %d", ErrUnsupportedInstruction, authorize)
+	}
+	return nil
+}
+
+// https://github.com/anza-xyz/agave/blob/v2.0.0/programs/stake/src/stake_state.rs#L575
+func (s *stakeSimulation) delegate(instruction sdktypes.Instruction) error {
+	address, account, err := s.stakeAccount(instruction, 0)
+	if err != nil {
+		return err
+	}
+	voter, err := accountAddress(instruction, 1)
+	if err != nil {
+		return err
+	}
+	if err := s.req.Limits.ValidateDelegate(account); err != nil {
+		return &AccountError{Address: address, Err: err}
+	}
+	rentExemptReserve, err := account.GetRentExemptReserve()
+	if err != nil {
+		return &AccountError{Address: address, Err: err}
+	}
+	delegation := types.Delegation{
+		Voter:              voter,
+		Stake:              account.Lamports - rentExemptReserve,
+		ActivationEpoch:    s.req.Epoch,
+		DeactivationEpoch:  types.MaxEpoch,
+		WarmupCooldownRate: defaultWarmupCooldownRate,
+	}
+
+	stakeInfo, err := account.GetInfoStake()
+	if err != nil {
+		account.Data.Parsed.Type = string(types.StakeStateStake)
+		account.Data.Parsed.Info.Stake = &types.StakeAccountInfoStake{Delegation: delegation}
+		return nil
+	}
+
+	// Redelegate
+	// https://github.com/anza-xyz/agave/blob/v2.0.0/programs/stake/src/stake_state.rs#L70
+	effective, _, _, err := getSolanaStakeActivatingAndDeactivating(address, account, s.req.Epoch, s.req.StakeHistory)
+	if err != nil {
+		return &AccountError{Address: address, Epoch: s.req.Epoch, Err: err}
+	}
+	if effective != 0 {
+		if stakeInfo.Delegation.Voter == voter && stakeInfo.Delegation.DeactivationEpoch == s.req.Epoch {
+			stakeInfo.Delegation.DeactivationEpoch = types.MaxEpoch
+			return nil
+		}
+		return &AccountError{Address: address, Epoch: s.req.Epoch, Err: ErrTooSoonToRedelegate}
+	}
+	stakeInfo.Delegation = delegation
+	return nil
+}
+
+// https://github.com/anza-xyz/agave/blob/v2.0.0/programs/stake/src/stake_state.rs#L715
+func (s *stakeSimulation) split(instruction sdktypes.Instruction, lamports uint64) error {
+	address, source, err := s.stakeAccount(instruction, 0)
+	if err != nil {
+		return err
+	}
+	destinationAddress, destination, err := s.stakeAccount(instruction, 1)
+	if err != nil {
+		return err
+	}
+	if state, _ := destination.GetState(); state != types.StakeStateUninitialized || destination.Data.Space != types.StakeStateV2Size {
+		return &AccountError{Address: destinationAddress, Err: xerrors.Errorf("%w: state: %s, space: %d", ErrNotStakeAccount, destination.Data.Parsed.Type, destination.Data.Space)}
+	}
+
+	res, err := CheckSplit(s.req.Epoch, source, s.req.StakeHistory, s.req.Limits.SplitRequest(lamports, destination.Lamports))
+	if err != nil {
+		return &AccountError{Address: address, Epoch: s.req.Epoch, Err: err}
+	}
+
+	if source.HasMeta() {
+		destination.Data.Parsed.Type = source.Data.Parsed.Type
+		destination.Data.Parsed.Info.Meta = source.Data.Parsed.Info.Meta
+		destination.Data.Parsed.Info.Meta.RentExemptReserve = strconv.FormatUint(s.req.Limits.RentExemptReserve, 10)
+	}
+	if stakeInfo, err := source.GetInfoStake(); err == nil {
+		splitStake := *stakeInfo
+		splitStake.Delegation.Stake = res.DestinationStake
+		destination.Data.Parsed.Info.Stake = &splitStake
+		stakeInfo.Delegation.Stake = res.SourceStake
+	}
+	source.Lamports = res.SourceLamports
+	destination.Lamports = res.DestinationLamports
+	if source.Lamports == 0 {
+		s.accounts[address] = nil
+	}
+	return nil
+}
+
+// https://github.com/anza-xyz/agave/blob/v2.0.0/programs/stake/src/stake_state.rs#L1000
+func (s *stakeSimulation) withdraw(instruction sdktypes.Instruction, lamports uint64) error {
+	address, account, err := s.stakeAccount(instruction, 0)
+	if err != nil {
+		return err
+	}
+
+	var reserve uint64
+	isStaked := false
+	if account.HasMeta() {
+		meta := account.GetInfoMeta()
+		// The custodian may sign to withdraw during the lockup.
+		custodian, err := accountAddress(instruction, 5)
+		if isLockupInForce(s.req.Epoch, s.req.UnixTimestamp, meta) && (err != nil || custodian != meta.Lockup.Custodian) {
+			return &AccountError{Address: address, Epoch: s.req.Epoch, Err: ErrLockupInForce}
+		}
+		if reserve, err = account.GetRentExemptReserve(); err != nil {
+			return &AccountError{Address: address, Err: err}
+		}
+	}
+	if stakeInfo, err := account.GetInfoStake(); err == nil {
+		// The full stake is assumed before deactivation, since the effective stake might still grow by warmup.
+		staked := stakeInfo.Delegation.Stake
+		if s.req.Epoch >= stakeInfo.Delegation.DeactivationEpoch {
+			if staked, _, _, err = getSolanaStakeActivatingAndDeactivating(address, account, s.req.Epoch, s.req.StakeHistory); err != nil {
+				return &AccountError{Address: address, Epoch: s.req.Epoch, Err: err}
+			}
+		}
+		reserve += staked
+		isStaked = staked != 0
+	}
+
+	lamportsAndReserve := lamports + reserve
+	if (isStaked || lamports != account.Lamports) && lamportsAndReserve > account.Lamports {
+		return &AccountError{Address: address, Epoch: s.req.Epoch, Err: xerrors.Errorf("%w: lamports: %d, withdraw: %d, reserve: %d", ErrInsufficientFunds, account.Lamports, lamports, reserve)}
+	}
+
+	if err := s.transfer(instruction, 0, 1, lamports); err != nil {
+		return err
+	}
+	if account.Lamports == 0 {
+		s.accounts[address] = nil
+	}
+	return nil
+}
+
+func (s *stakeSimulation) deactivate(instruction sdktypes.Instruction) error {
+	address, account, err := s.stakeAccount(instruction, 0)
+	if err != nil {
+		return err
+	}
+	if err := CheckDeactivate(address, s.req.Epoch, account, s.req.StakeHistory); err != nil {
+		return err
+	}
+	account.Data.Parsed.Info.Stake.Delegation.DeactivationEpoch = s.req.Epoch
+	return nil
+}
+
+func (s *stakeSimulation) setLockup(instruction sdktypes.Instruction, unixTimestamp *int64, epoch *uint64, custodian *common.PublicKey) error {
+	address, account, err := s.stakeAccount(instruction, 0)
+	if err != nil {
+		return err
+	}
+	if !account.HasMeta() {
+		return &AccountError{Address: address, Err: ErrNoMeta}
+	}
+
+	lockup := &account.Data.Parsed.Info.Meta.Lockup
+	if unixTimestamp != nil {
+		lockup.UnixTimestamp = uint64(*unixTimestamp)
+	}
+	if epoch != nil {
+		lockup.Epoch = *epoch
+	}
+	if custodian != nil {
+		lockup.Custodian = custodian.ToBase58()
+	}
+	return nil
+}
+
+// https://github.com/anza-xyz/agave/blob/v2.0.0/programs/stake/src/stake_state.rs#L1418
+func (s *stakeSimulation) merge(instruction sdktypes.Instruction) error {
+	destinationAddress, destination, err := s.stakeAccount(instruction, 0)
+	if err != nil {
+		return err
+	}
+	sourceAddress, source, err := s.stakeAccount(instruction, 1)
+	if err != nil {
+		return err
+	}
+	if destinationAddress == sourceAddress {
+		return &AccountError{Address: sourceAddress, Err: xerrors.Errorf("%w: cannot merge a stake account into itself", ErrMergeMismatch)}
+	}
+
+	res, err := CheckMerge(s.req.Epoch, s.req.UnixTimestamp, destination, source, s.req.StakeHistory)
+	if err != nil {
+		return &AccountError{Address: sourceAddress, Epoch: s.req.Epoch, Err: err}
+	}
+
+	switch {
+	case res.DestinationKind == MergeKindActivationEpoch && res.SourceKind == MergeKindInactive:
+		destination.Data.Parsed.Info.Stake.Delegation.Stake += source.Lamports
+	case res.DestinationKind == MergeKindActivationEpoch && res.SourceKind == MergeKindActivationEpoch:
+		sourceRentExemptReserve, err := source.GetRentExemptReserve()
+		if err != nil {
+			return &AccountError{Address: sourceAddress, Err: err}
+		}
+		destination.Data.Parsed.Info.Stake.Delegation.Stake += sourceRentExemptReserve + source.Data.Parsed.Info.Stake.Delegation.Stake
+	case res.DestinationKind == MergeKindFullyActive && res.SourceKind == MergeKindFullyActive:
+		destination.Data.Parsed.Info.Stake.Delegation.Stake += source.Data.Parsed.Info.Stake.Delegation.Stake
+	}
+
+	destination.Lamports += source.Lamports
+	s.accounts[sourceAddress] = nil
+	return nil
+}
+
+// stakeAccount returns the tracked account at index of the instruction, which must be owned by the stake program.
+func (s *stakeSimulation) stakeAccount(instruction sdktypes.Instruction, index int) (string, *types.StakeAccount, error) {
+	address, err := accountAddress(instruction, index)
+	if err != nil {
+		return "", nil, err
+	}
+	account := s.accounts[address]
+	if account == nil {
+		return "", nil, &AccountError{Address: address, Err: ErrAccountNotFound}
+	}
+	if account.Owner != StakeProgramAddress {
+		return "", nil, &AccountError{Address: address, Err: ErrWrongOwner}
+	}
+	return address, account, nil
+}
+
+func accountAddress(instruction sdktypes.Instruction, index int) (string, error) {
+	if index >= len(instruction.Accounts) {
+		return "", xerrors.Errorf("%w: missing account: %d", ErrUnsupportedInstruction, index)
+	}
+	return instruction.Accounts[index].PubKey.ToBase58(), nil
+}
+
+func newUninitializedStakeAccount(lamports uint64, space uint64) *types.StakeAccount {
+	account := &types.StakeAccount{Lamports: lamports, Owner: StakeProgramAddress}
+	account.Data.Program = "stake"
+	account.Data.Space = space
+	account.Data.Parsed.Type = string(types.StakeStateUninitialized)
+	return account
+}
+
+func copyStakeAccount(account *types.StakeAccount) *types.StakeAccount {
+	if account == nil {
+		return nil
+	}
+	c := *account
+	if account.Data.Parsed.Info.Stake != nil {
+		stakeInfo := *account.Data.Parsed.Info.Stake
+		c.Data.Parsed.Info.Stake = &stakeInfo
+	}
+	return &c
+}
+
+// instructionDataReader decodes bincode instruction data and keeps the first error.
+type instructionDataReader struct {
+	data []byte
+	err  error
+}
+
+func (r *instructionDataReader) next(n int) []byte {
+	if r.err != nil {
+		return make([]byte, n)
+	}
+	if len(r.data) < n {
+		r.err = xerrors.Errorf("%w: instruction data is too short", ErrUnsupportedInstruction)
+		return make([]byte, n)
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *instructionDataReader) uint32() uint32 {
+	return binary.LittleEndian.Uint32(r.next(4))
+}
+
+func (r *instructionDataReader) uint64() uint64 {
+	return binary.LittleEndian.Uint64(r.next(8))
+}
+
+func (r *instructionDataReader) int64() int64 {
+	return int64(r.uint64())
+}
+
+func (r *instructionDataReader) publicKey() common.PublicKey {
+	return common.PublicKeyFromBytes(r.next(common.PublicKeyLength))
+}
+
+func (r *instructionDataReader) string() string {
+	n := r.uint64()
+	if n > uint64(len(r.data)) {
+		r.next(len(r.data) + 1)
+		return ""
+	}
+	return string(r.next(int(n)))
+}
+
+func (r *instructionDataReader) some() bool {
+	return r.next(1)[0] == 1
+}
+
+func (r *instructionDataReader) optionalInt64() *int64 {
+	if !r.some() {
+		return nil
+	}
+	v := r.int64()
+	return &v
+}
+
+func (r *instructionDataReader) optionalUint64() *uint64 {
+	if !r.some() {
+		return nil
+	}
+	v := r.uint64()
+	return &v
+}
+
+func (r *instructionDataReader) optionalPublicKey() *common.PublicKey {
+	if !r.some() {
+		return nil
+	}
+	v := r.publicKey()
+	return &v
+}
@@ -0,0 +1,261 @@
+package client
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/blocto/solana-go-sdk/common"
+	"github.com/blocto/solana-go-sdk/program/system"
+	sdktypes "github.com/blocto/solana-go-sdk/types"
+	"github.com/skport/solana-rpc-client-extensions-go/types"
+)
+
+type simulatedBalance struct {
+	Lamports uint64
+	Stake    uint64
+}
+
+func TestSimulateStakeTransaction(t *testing.T) {
+	// Without stake history, a delegation is fully active from the epoch after its activation.
+	history := genStakeHistory(rand.New(rand.NewSource(0)), 10, 0)
+	limits := StakeAmountLimits{RentExemptReserve: testRentExemptReserve, MinimumDelegation: 1000000000}
+
+	active := newTestStakeAccount(10002282880, 10000000000, 0, types.MaxEpoch)
+	deactivating := newTestStakeAccount(10002282880, 10000000000, 0, 10)
+	cooledDown := newTestStakeAccount(10002282880, 10000000000, 0, 5)
+	lockedUp := newTestStakeAccount(10002282880, 10000000000, 0, 5)
+	lockedUp.Data.Parsed.Info.Meta.Lockup.Epoch = 20
+
+	splitStakeAddress, err := CreateStakeAccountWithSeedAddress(testAddress3, "stake:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mustInstruction := func(instruction sdktypes.Instruction, err error) []sdktypes.Instruction {
+		if err != nil {
+			t.Fatal(err)
+		}
+		return []sdktypes.Instruction{instruction}
+	}
+	mustInstructions := func(instructions []sdktypes.Instruction, err error) []sdktypes.Instruction {
+		if err != nil {
+			t.Fatal(err)
+		}
+		return instructions
+	}
+	activation := func(active uint64, inactive uint64, state string) *GetStakeActivationResponse {
+		return &GetStakeActivationResponse{Active: active, Inactive: inactive, State: state}
+	}
+
+	tests := []struct {
+		name            string
+		accounts        map[string]*types.StakeAccount
+		instructions    []sdktypes.Instruction
+		wantBalances    map[string]*simulatedBalance
+		wantActivations map[string][]*GetStakeActivationResponse
+		wantErr         error
+	}{
+		{
+			name:         "deactivate",
+			accounts:     map[string]*types.StakeAccount{testAddress2: active},
+			instructions: mustInstruction(DeactivateStakeInstruction(DeactivateStakeParam{Stake: testAddress2, Staker: testAddress3})),
+			wantBalances: map[string]*simulatedBalance{testAddress2: {Lamports: 10002282880, Stake: 10000000000}},
+			wantActivations: map[string][]*GetStakeActivationResponse{
+				testAddress2: {activation(10000000000, 0, "deactivating"), activation(0, 10000000000, "inactive")},
+			},
+		},
+		{
+			name:     "create account and delegate stake",
+			accounts: map[string]*types.StakeAccount{},
+			instructions: mustInstructions(CreateStakeAccountInstructions(CreateStakeAccountParam{
+				From:       testAddress1,
+				Stake:      testAddress2,
+				Staker:     testAddress3,
+				Withdrawer: testAddress3,
+				Lamports:   10002282880,
+				Voter:      testAddress5,
+			})),
+			wantBalances: map[string]*simulatedBalance{testAddress2: {Lamports: 10002282880, Stake: 10000000000}},
+			wantActivations: map[string][]*GetStakeActivationResponse{
+				testAddress2: {activation(0, 10000000000, "activating"), activation(10000000000, 0, "active")},
+			},
+		},
+		{
+			name:     "prefund and split with seed",
+			accounts: map[string]*types.StakeAccount{testAddress2: active},
+			instructions: append(
+				[]sdktypes.Instruction{system.Transfer(system.TransferParam{
+					From:   common.PublicKeyFromString(testAddress1),
+					To:     common.PublicKeyFromString(splitStakeAddress),
+					Amount: testRentExemptReserve,
+				})},
+				mustInstructions(SplitStakeWithSeedInstructions(SplitStakeWithSeedParam{Stake: testAddress2, Staker: testAddress3, Base: testAddress3, Seed: "stake:1", Lamports: 4000000000}))...,
+			),
+			wantBalances: map[string]*simulatedBalance{
+				testAddress2:      {Lamports: 6002282880, Stake: 6000000000},
+				splitStakeAddress: {Lamports: 4002282880, Stake: 4000000000},
+			},
+			wantActivations: map[string][]*GetStakeActivationResponse{
+				testAddress2:      {activation(6000000000, 0, "active"), activation(6000000000, 0, "active")},
+				splitStakeAddress: {activation(4000000000, 0, "active"), activation(4000000000, 0, "active")},
+			},
+		},
+		{
+			name:         "merge",
+			accounts:     map[string]*types.StakeAccount{testAddress2: active, testAddress7: active},
+			instructions: mustInstruction(MergeStakeInstruction(MergeStakeParam{Destination: testAddress2, Source: testAddress7, Staker: testAddress3})),
+			wantBalances: map[string]*simulatedBalance{
+				testAddress2: {Lamports: 20004565760, Stake: 20000000000},
+				testAddress7: nil,
+			},
+			wantActivations: map[string][]*GetStakeActivationResponse{
+				testAddress2: {activation(20000000000, 2282880, "active"), activation(20000000000, 2282880, "active")},
+			},
+		},
+		{
+			name:         "withdraw all",
+			accounts:     map[string]*types.StakeAccount{testAddress2: cooledDown},
+			instructions: mustInstruction(WithdrawStakeInstruction(WithdrawStakeParam{Stake: testAddress2, Withdrawer: testAddress3, To: testAddress7, Lamports: 10002282880})),
+			wantBalances: map[string]*simulatedBalance{testAddress2: nil},
+		},
+		{
+			name:         "error: already deactivated",
+			accounts:     map[string]*types.StakeAccount{testAddress2: deactivating},
+			instructions: mustInstruction(DeactivateStakeInstruction(DeactivateStakeParam{Stake: testAddress2, Staker: testAddress3})),
+			wantErr:      ErrAlreadyDeactivated,
+		},
+		{
+			name:         "error: withdraw staked lamports",
+			accounts:     map[string]*types.StakeAccount{testAddress2: active},
+			instructions: mustInstruction(WithdrawStakeInstruction(WithdrawStakeParam{Stake: testAddress2, Withdrawer: testAddress3, To: testAddress7, Lamports: 1})),
+			wantErr:      ErrInsufficientFunds,
+		},
+		{
+			name:         "error: withdraw in lockup",
+			accounts:     map[string]*types.StakeAccount{testAddress2: lockedUp},
+			instructions: mustInstruction(WithdrawStakeInstruction(WithdrawStakeParam{Stake: testAddress2, Withdrawer: testAddress3, To: testAddress7, Lamports: 1})),
+			wantErr:      ErrLockupInForce,
+		},
+		{
+			name:     "error: delegate below minimum delegation",
+			accounts: map[string]*types.StakeAccount{},
+			instructions: mustInstructions(CreateStakeAccountInstructions(CreateStakeAccountParam{
+				From:       testAddress1,
+				Stake:      testAddress2,
+				Staker:     testAddress3,
+				Withdrawer: testAddress3,
+				Lamports:   testRentExemptReserve + 1,
+				Voter:      testAddress5,
+			})),
+			wantErr: ErrInsufficientDelegation,
+		},
+		{
+			name:         "error: redelegate active stake",
+			accounts:     map[string]*types.StakeAccount{testAddress2: active},
+			instructions: mustInstruction(DelegateStakeInstruction(DelegateStakeParam{Stake: testAddress2, Staker: testAddress3, Voter: testAddress5})),
+			wantErr:      ErrTooSoonToRedelegate,
+		},
+		{
+			name:         "error: split without prefunded destination",
+			accounts:     map[string]*types.StakeAccount{testAddress2: active},
+			instructions: mustInstructions(SplitStakeInstructions(SplitStakeParam{Stake: testAddress2, Staker: testAddress3, SplitStake: testAddress7, Lamports: 4000000000})),
+			wantErr:      ErrInsufficientFunds,
+		},
+		{
+			name:         "error: account not found",
+			accounts:     map[string]*types.StakeAccount{},
+			instructions: mustInstruction(DeactivateStakeInstruction(DeactivateStakeParam{Stake: testAddress2, Staker: testAddress3})),
+			wantErr:      ErrAccountNotFound,
+		},
+		{
+			name:     "error: unsupported instruction",
+			accounts: map[string]*types.StakeAccount{testAddress2: active},
+			// Redelegate
+			instructions: []sdktypes.Instruction{{ProgramID: common.StakeProgramID, Data: []byte{14, 0, 0, 0}}},
+			wantErr:      ErrUnsupportedInstruction,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &SimulateStakeTransactionRequest{
+				Epoch:        10,
+				Accounts:     tt.accounts,
+				StakeHistory: history,
+				Limits:       limits,
+				FutureEpochs: 1,
+			}
+			got, err := SimulateStakeTransaction(req, tt.instructions)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SimulateStakeTransaction error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			balances := map[string]*simulatedBalance{}
+			for address, account := range got.Accounts {
+				if account == nil {
+					balances[address] = nil
+					continue
+				}
+				stake, _ := account.GetDelegationStake()
+				balances[address] = &simulatedBalance{Lamports: account.Lamports, Stake: stake}
+			}
+			if !reflect.DeepEqual(balances, tt.wantBalances) {
+				t.Errorf("Accounts = %+v, want %+v", balances, tt.wantBalances)
+			}
+			if len(got.Activations) != 0 || len(tt.wantActivations) != 0 {
+				if !reflect.DeepEqual(got.Activations, tt.wantActivations) {
+					t.Errorf("Activations = %+v, want %+v", got.Activations, tt.wantActivations)
+				}
+			}
+		})
+	}
+
+	// The accounts of the request are not modified.
+	if active.Data.Parsed.Info.Stake.Delegation.DeactivationEpoch != types.MaxEpoch || active.Lamports != 10002282880 {
+		t.Errorf("request account was modified: %+v", active)
+	}
+}
+
+func TestSimulateStakeTransaction_ProjectStakeHistory(t *testing.T) {
+	history := genStakeHistory(rand.New(rand.NewSource(0)), 10, 5)
+	// The rest of the cluster already has as much activating stake as fits in a u64.
+	history.Data.Parsed.Info[0].StakeHistory.Activating = math.MaxUint64
+
+	initialized := newTestStakeAccount(10002282880, 0, 0, 0)
+	initialized.Data.Parsed.Type = "initialized"
+	initialized.Data.Parsed.Info.Stake = nil
+
+	instructions, err := CreateStakeAccountInstructions(CreateStakeAccountParam{
+		From:       testAddress1,
+		Stake:      testAddress2,
+		Staker:     testAddress3,
+		Withdrawer: testAddress3,
+		Lamports:   10002282880,
+		Voter:      testAddress5,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := &SimulateStakeTransactionRequest{
+		Epoch:        10,
+		Accounts:     map[string]*types.StakeAccount{testAddress7: initialized},
+		StakeHistory: history,
+		Limits:       StakeAmountLimits{RentExemptReserve: testRentExemptReserve, MinimumDelegation: 1000000000},
+		FutureEpochs: 2,
+	}
+
+	_, err = SimulateStakeTransaction(req, instructions)
+	if !errors.Is(err, ErrArithmeticOverflow) {
+		t.Fatalf("SimulateStakeTransaction error = %v, want %v", err, ErrArithmeticOverflow)
+	}
+	var accountErr *AccountError
+	if !errors.As(err, &accountErr) || accountErr.Address != testAddress2 || accountErr.Epoch != 10 {
+		t.Errorf("error = %#v, want *AccountError of %s at epoch 10", err, testAddress2)
+	}
+}
@@ -0,0 +1,373 @@
+package client
+
+import (
+	"github.com/blocto/solana-go-sdk/common"
+	"github.com/blocto/solana-go-sdk/program/stake"
+	"github.com/blocto/solana-go-sdk/program/system"
+	sdktypes "github.com/blocto/solana-go-sdk/types"
+	"github.com/skport/solana-rpc-client-extensions-go/types"
+
+	"golang.org/x/xerrors"
+)
+
+// Instruction builders for the stake program, following solana_program::stake::instruction.
+// https://github.com/anza-xyz/agave/blob/v2.0.0/sdk/program/src/stake/instruction.rs
+//
+// Addresses are base58 strings. An empty optional address (e.g. Custodian) is omitted.
+
+// StakeAuthorize selects the authority changed by Authorize.
+type StakeAuthorize = stake.StakeAuthorizationType
+
+const (
+	StakeAuthorizeStaker     = stake.StakeAuthorizationTypeStaker
+	StakeAuthorizeWithdrawer = stake.StakeAuthorizationTypeWithdrawer
+)
+
+// StakeLockup is the lockup of a new stake account. The zero value is no lockup.
+type StakeLockup struct {
+	UnixTimestamp int64
+	Epoch         uint64
+	Custodian     string
+}
+
+type CreateStakeAccountParam struct {
+	// From funds the new account and pays for the rent-exempt reserve.
+	From       string
+	Stake      string
+	Staker     string
+	Withdrawer string
+	Lockup     StakeLockup
+	Lamports   uint64
+	// Voter is optional. If set, the new stake is delegated to it in the same transaction.
+	Voter string
+}
+
+type CreateStakeAccountWithSeedParam struct {
+	From       string
+	Base       string
+	Seed       string
+	Staker     string
+	Withdrawer string
+	Lockup     StakeLockup
+	Lamports   uint64
+	Voter      string
+}
+
+type DelegateStakeParam struct {
+	Stake  string
+	Staker string
+	Voter  string
+}
+
+type DeactivateStakeParam struct {
+	Stake  string
+	Staker string
+}
+
+type WithdrawStakeParam struct {
+	Stake      string
+	Withdrawer string
+	To         string
+	Lamports   uint64
+	// Custodian must sign if the lockup is in force.
+	Custodian string
+}
+
+type SplitStakeParam struct {
+	Stake      string
+	Staker     string
+	SplitStake string
+	Lamports   uint64
+}
+
+type SplitStakeWithSeedParam struct {
+	Stake    string
+	Staker   string
+	Base     string
+	Seed     string
+	Lamports uint64
+}
+
+type MergeStakeParam struct {
+	Destination string
+	Source      string
+	Staker      string
+}
+
+type AuthorizeStakeParam struct {
+	Stake        string
+	Authority    string
+	NewAuthority string
+	Type         StakeAuthorize
+	Custodian    string
+}
+
+type AuthorizeStakeWithSeedParam struct {
+	Stake string
+	// AuthorityBase, AuthoritySeed and AuthorityOwner derive the current authority with CreateWithSeedAddress.
+	AuthorityBase  string
+	AuthoritySeed  string
+	AuthorityOwner string
+	NewAuthority   string
+	Type           StakeAuthorize
+	Custodian      string
+}
+
+type SetStakeLockupParam struct {
+	Stake string
+	// Custodian signs the instruction. It is the withdrawer if the lockup is not in force.
+	Custodian string
+	// Nil fields are left unchanged.
+	UnixTimestamp *int64
+	Epoch         *uint64
+	NewCustodian  string
+}
+
+// CreateStakeAccountInstructions returns create_account and initialize, followed by delegate_stake if Voter is set.
+func CreateStakeAccountInstructions(param CreateStakeAccountParam) ([]sdktypes.Instruction, error) {
+	var k publicKeys
+	from := k.decode("from", param.From)
+	stakeKey := k.decode("stake", param.Stake)
+	initialize := k.initialize(stakeKey, param.Staker, param.Withdrawer, param.Lockup)
+	if k.err != nil {
+		return nil, k.err
+	}
+
+	instructions := []sdktypes.Instruction{
+		system.CreateAccount(system.CreateAccountParam{
+			From:     from,
+			New:      stakeKey,
+			Owner:    common.StakeProgramID,
+			Lamports: param.Lamports,
+			Space:    types.StakeStateV2Size,
+		}),
+		initialize,
+	}
+	return appendDelegate(instructions, param.Stake, param.Staker, param.Voter)
+}
+
+// CreateStakeAccountWithSeedInstructions returns create_account_with_seed and initialize, followed by delegate_stake if Voter is set.
+// The stake account address is CreateStakeAccountWithSeedAddress(Base, Seed).
+func CreateStakeAccountWithSeedInstructions(param CreateStakeAccountWithSeedParam) ([]sdktypes.Instruction, error) {
+	stakeAddress, err := CreateStakeAccountWithSeedAddress(param.Base, param.Seed)
+	if err != nil {
+		return nil, xerrors.Errorf("wrap: %w", err)
+	}
+
+	var k publicKeys
+	from := k.decode("from", param.From)
+	base := k.decode("base", param.Base)
+	stakeKey := k.decode("stake", stakeAddress)
+	initialize := k.initialize(stakeKey, param.Staker, param.Withdrawer, param.Lockup)
+	if k.err != nil {
+		return nil, k.err
+	}
+
+	instructions := []sdktypes.Instruction{
+		system.CreateAccountWithSeed(system.CreateAccountWithSeedParam{
+			From:     from,
+			New:      stakeKey,
+			Base:     base,
+			Owner:    common.StakeProgramID,
+			Seed:     param.Seed,
+			Lamports: param.Lamports,
+			Space:    types.StakeStateV2Size,
+		}),
+		initialize,
+	}
+	return appendDelegate(instructions, stakeAddress, param.Staker, param.Voter)
+}
+
+func DelegateStakeInstruction(param DelegateStakeParam) (sdktypes.Instruction, error) {
+	var k publicKeys
+	instruction := stake.DelegateStake(stake.DelegateStakeParam{
+		Stake: k.decode("stake", param.Stake),
+		Auth:  k.decode("staker", param.Staker),
+		Vote:  k.decode("voter", param.Voter),
+	})
+	return k.result(instruction)
+}
+
+func DeactivateStakeInstruction(param DeactivateStakeParam) (sdktypes.Instruction, error) {
+	var k publicKeys
+	instruction := stake.Deactivate(stake.DeactivateParam{
+		Stake: k.decode("stake", param.Stake),
+		Auth:  k.decode("staker", param.Staker),
+	})
+	return k.result(instruction)
+}
+
+func WithdrawStakeInstruction(param WithdrawStakeParam) (sdktypes.Instruction, error) {
+	var k publicKeys
+	instruction := stake.Withdraw(stake.WithdrawParam{
+		Stake:     k.decode("stake", param.Stake),
+		Auth:      k.decode("withdrawer", param.Withdrawer),
+		To:        k.decode("to", param.To),
+		Lamports:  param.Lamports,
+		Custodian: k.optional("custodian", param.Custodian),
+	})
+	return k.result(instruction)
+}
+
+// SplitStakeInstructions returns allocate and assign for SplitStake, which must sign, followed by split.
+// Splitting part of an active stake requires SplitStake to be prefunded with the rent-exempt reserve, see CheckSplit.
+func SplitStakeInstructions(param SplitStakeParam) ([]sdktypes.Instruction, error) {
+	var k publicKeys
+	stakeKey := k.decode("stake", param.Stake)
+	staker := k.decode("staker", param.Staker)
+	splitStake := k.decode("splitStake", param.SplitStake)
+	if k.err != nil {
+		return nil, k.err
+	}
+
+	return []sdktypes.Instruction{
+		system.Allocate(system.AllocateParam{Account: splitStake, Space: types.StakeStateV2Size}),
+		system.Assign(system.AssignParam{From: splitStake, Owner: common.StakeProgramID}),
+		stake.Split(stake.SplitParam{Stake: stakeKey, Auth: staker, SplitStake: splitStake, Lamports: param.Lamports}),
+	}, nil
+}
+
+// SplitStakeWithSeedInstructions returns allocate_with_seed and split.
+// The split stake account address is CreateStakeAccountWithSeedAddress(Base, Seed).
+func SplitStakeWithSeedInstructions(param SplitStakeWithSeedParam) ([]sdktypes.Instruction, error) {
+	splitStakeAddress, err := CreateStakeAccountWithSeedAddress(param.Base, param.Seed)
+	if err != nil {
+		return nil, xerrors.Errorf("wrap: %w", err)
+	}
+
+	var k publicKeys
+	stakeKey := k.decode("stake", param.Stake)
+	staker := k.decode("staker", param.Staker)
+	base := k.decode("base", param.Base)
+	splitStake := k.decode("splitStake", splitStakeAddress)
+	if k.err != nil {
+		return nil, k.err
+	}
+
+	return []sdktypes.Instruction{
+		system.AllocateWithSeed(system.AllocateWithSeedParam{
+			Account: splitStake,
+			Base:    base,
+			Owner:   common.StakeProgramID,
+			Seed:    param.Seed,
+			Space:   types.StakeStateV2Size,
+		}),
+		stake.Split(stake.SplitParam{Stake: stakeKey, Auth: staker, SplitStake: splitStake, Lamports: param.Lamports}),
+	}, nil
+}
+
+// MergeStakeInstruction merges Source into Destination. Check the accounts with CheckMerge beforehand.
+func MergeStakeInstruction(param MergeStakeParam) (sdktypes.Instruction, error) {
+	var k publicKeys
+	instruction := stake.Merge(stake.MergeParam{
+		To:   k.decode("destination", param.Destination),
+		From: k.decode("source", param.Source),
+		Auth: k.decode("staker", param.Staker),
+	})
+	return k.result(instruction)
+}
+
+func AuthorizeStakeInstruction(param AuthorizeStakeParam) (sdktypes.Instruction, error) {
+	var k publicKeys
+	instruction := stake.Authorize(stake.AuthorizeParam{
+		Stake:     k.decode("stake", param.Stake),
+		Auth:      k.decode("authority", param.Authority),
+		NewAuth:   k.decode("newAuthority", param.NewAuthority),
+		AuthType:  param.Type,
+		Custodian: k.optional("custodian", param.Custodian),
+	})
+	return k.result(instruction)
+}
+
+func AuthorizeStakeWithSeedInstruction(param AuthorizeStakeWithSeedParam) (sdktypes.Instruction, error) {
+	if len(param.AuthoritySeed) > common.MaxSeedLength {
+		return sdktypes.Instruction{}, xerrors.Errorf("authoritySeed is too long, seed: %s, length: %d, max: %d", param.AuthoritySeed, len(param.AuthoritySeed), common.MaxSeedLength)
+	}
+
+	var k publicKeys
+	instruction := stake.AuthorizeWithSeed(stake.AuthorizeWithSeedParam{
+		Stake:     k.decode("stake", param.Stake),
+		AuthBase:  k.decode("authorityBase", param.AuthorityBase),
+		AuthSeed:  param.AuthoritySeed,
+		AuthOwner: k.decode("authorityOwner", param.AuthorityOwner),
+		NewAuth:   k.decode("newAuthority", param.NewAuthority),
+		AuthType:  param.Type,
+		Custodian: k.optional("custodian", param.Custodian),
+	})
+	return k.result(instruction)
+}
+
+func SetStakeLockupInstruction(param SetStakeLockupParam) (sdktypes.Instruction, error) {
+	var k publicKeys
+	instruction := stake.SetLockup(stake.SetLockupParam{
+		Stake: k.decode("stake", param.Stake),
+		Auth:  k.decode("custodian", param.Custodian),
+		Lockup: stake.LockupParam{
+			UnixTimestamp: param.UnixTimestamp,
+			Epoch:         param.Epoch,
+			Cusodian:      k.optional("newCustodian", param.NewCustodian),
+		},
+	})
+	return k.result(instruction)
+}
+
+func appendDelegate(instructions []sdktypes.Instruction, stakeAddress string, staker string, voter string) ([]sdktypes.Instruction, error) {
+	if voter == "" {
+		return instructions, nil
+	}
+	delegate, err := DelegateStakeInstruction(DelegateStakeParam{Stake: stakeAddress, Staker: staker, Voter: voter})
+	if err != nil {
+		return nil, xerrors.Errorf("wrap: %w", err)
+	}
+	return append(instructions, delegate), nil
+}
+
+// publicKeys decodes the addresses of an instruction and keeps the first error.
+type publicKeys struct {
+	err error
+}
+
+func (k *publicKeys) decode(name string, address string) common.PublicKey {
+	if k.err != nil {
+		return common.PublicKey{}
+	}
+	key, err := decodePublicKey(address)
+	if err != nil {
+		k.err = xerrors.Errorf("%s: %s, wrap: %w", name, address, err)
+	}
+	return key
+}
+
+func (k *publicKeys) result(instruction sdktypes.Instruction) (sdktypes.Instruction, error) {
+	if k.err != nil {
+		return sdktypes.Instruction{}, k.err
+	}
+	return instruction, nil
+}
+
+func (k *publicKeys) optional(name string, address string) *common.PublicKey {
+	if address == "" {
+		return nil
+	}
+	key := k.decode(name, address)
+	return &key
+}
+
+func (k *publicKeys) initialize(stakeKey common.PublicKey, staker string, withdrawer string, lockup StakeLockup) sdktypes.Instruction {
+	var custodian common.PublicKey
+	if lockup.Custodian != "" {
+		custodian = k.decode("custodian", lockup.Custodian)
+	}
+	return stake.Initialize(stake.InitializeParam{
+		Stake: stakeKey,
+		Auth: stake.Authorized{
+			Staker:     k.decode("staker", staker),
+			Withdrawer: k.decode("withdrawer", withdrawer),
+		},
+		Lockup: stake.Lockup{
+			UnixTimestamp: lockup.UnixTimestamp,
+			Epoch:         lockup.Epoch,
+			Cusodian:      custodian,
+		},
+	})
+}
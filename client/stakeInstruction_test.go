@@ -0,0 +1,471 @@
+package client
+
+import (
+	"encoding/hex"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/blocto/solana-go-sdk/common"
+	sdktypes "github.com/blocto/solana-go-sdk/types"
+)
+
+// Addresses whose public key bytes are 32 repetitions of 1, 2, ...
+const (
+	testAddress1 = "4vJ9JU1bJJE96FWSJKvHsmmFADCg4gpZQff4P3bkLKi"
+	testAddress2 = "8qbHbw2BbbTHBW1sbeqakYXVKRQM8Ne7pLK7m6CVfeR"
+	testAddress3 = "CktRuQ2mttgRGkXJtyksdKHjUdc2C4TgDzyB98oEzy8"
+	testAddress4 = "GgBaCs3NCBuZN12kCJgAW63ydqohFkHEdfdEXBPzLHq"
+	testAddress5 = "LbUiWL3xVV8hTFYBVdbTNrpDo41NKS6o3LHHuDzjfcY"
+	testAddress6 = "QWmroo4YnnMqYW3cnxWkFdaTxGD3P7vMSzwMHGbUzwF"
+	testAddress7 = "US517G5965aydkZ46HS38QLi7UQiSojurfbQfKCELFx"
+	testAddress8 = "YMN9Qj5jPNp7j14VPcML1B6xGgcPWVZUGLFU3Mnyfaf"
+)
+
+// Program and sysvar ids as raw bytes.
+const (
+	testSystemProgramHex = "0000000000000000000000000000000000000000000000000000000000000000"
+	testStakeProgramHex  = "06a1d8179137542a983437bdfe2a7ab2557f535c8a78722b68a49dc000000000"
+	testStakeConfigHex   = "06a1d817a502050b680791e6ce6db88e1e5b7150f61fc6790a4eb4d100000000"
+	testClockHex         = "06a7d51718c774c928566398691d5eb68b5eb8a39b4b6d5c73555b2100000000"
+	testStakeHistoryHex  = "06a7d517193584d0feed9bb3431d13206be544281b57b8566cc5375ff4000000"
+	testRentHex          = "06a7d517192c5c51218cc94c3d4af17f58daee089ba1fd44e3dbd98a00000000"
+)
+
+// testInstruction is an instruction written out as hex, in the wire format of the runtime.
+type testInstruction struct {
+	programID string
+	accounts  []testAccountMeta
+	data      string
+}
+
+type testAccountMeta struct {
+	key      string
+	signer   bool
+	writable bool
+}
+
+func testKeyHex(b byte) string {
+	return strings.Repeat(hex.EncodeToString([]byte{b}), 32)
+}
+
+func addressHex(t *testing.T, address string) string {
+	t.Helper()
+	key, err := decodePublicKey(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hex.EncodeToString(key[:])
+}
+
+func toTestInstructions(instructions []sdktypes.Instruction) []testInstruction {
+	got := make([]testInstruction, 0, len(instructions))
+	for _, instruction := range instructions {
+		accounts := make([]testAccountMeta, 0, len(instruction.Accounts))
+		for _, account := range instruction.Accounts {
+			accounts = append(accounts, testAccountMeta{key: hex.EncodeToString(account.PubKey[:]), signer: account.IsSigner, writable: account.IsWritable})
+		}
+		got = append(got, testInstruction{
+			programID: hex.EncodeToString(instruction.ProgramID[:]),
+			accounts:  accounts,
+			data:      hex.EncodeToString(instruction.Data),
+		})
+	}
+	return got
+}
+
+// Expected bytes are written out from the bincode layout of StakeInstruction and SystemInstruction:
+// a u32 variant, u64 amounts, i64 timestamps, raw 32-byte keys, strings prefixed with a u64 length,
+// and options prefixed with a 0 or 1 byte.
+// https://github.com/anza-xyz/agave/blob/v2.0.0/sdk/program/src/stake/instruction.rs
+func TestStakeInstructions(t *testing.T) {
+	const (
+		oneSol     = "00ca9a3b00000000" // 1000000000
+		space      = "c800000000000000" // 200
+		noLockup   = "0000000000000000" + "0000000000000000" + testSystemProgramHex
+		seed0      = "0700000000000000" + "7374616b653a30" // "stake:0"
+		seed1      = "0700000000000000" + "7374616b653a31" // "stake:1"
+		epoch500   = "f401000000000000"
+		timestamp  = "00f1536500000000" // 1700000000
+		withSeed0  = "BXX47Vig9Ma2QT7VMgNPFsHT428zon1MBC2iB7ugbGoB"
+		withSeed1  = "FVhcJf2fmVrzj8oY7ieBcYgGGcKiJzNF6yvGxiyXVui"
+		signer     = true
+		notSigner  = false
+		isWritable = true
+		readonly   = false
+	)
+	key := testKeyHex
+	epoch := uint64(500)
+
+	delegate := testInstruction{
+		programID: testStakeProgramHex,
+		accounts: []testAccountMeta{
+			{key(2), notSigner, isWritable},
+			{key(5), notSigner, readonly},
+			{testClockHex, notSigner, readonly},
+			{testStakeHistoryHex, notSigner, readonly},
+			{testStakeConfigHex, notSigner, readonly},
+			{key(3), signer, readonly},
+		},
+		data: "02000000",
+	}
+	split := func(splitStake string) testInstruction {
+		return testInstruction{
+			programID: testStakeProgramHex,
+			accounts: []testAccountMeta{
+				{key(2), notSigner, isWritable},
+				{splitStake, notSigner, isWritable},
+				{key(3), signer, readonly},
+			},
+			data: "03000000" + oneSol,
+		}
+	}
+
+	tests := []struct {
+		name    string
+		build   func() ([]sdktypes.Instruction, error)
+		want    func(t *testing.T) []testInstruction
+		wantErr bool
+	}{
+		{
+			name: "create account and delegate stake",
+			build: func() ([]sdktypes.Instruction, error) {
+				return CreateStakeAccountInstructions(CreateStakeAccountParam{
+					From:       testAddress1,
+					Stake:      testAddress2,
+					Staker:     testAddress3,
+					Withdrawer: testAddress4,
+					Lockup:     StakeLockup{UnixTimestamp: 1700000000, Epoch: 500, Custodian: testAddress6},
+					Lamports:   1000000000,
+					Voter:      testAddress5,
+				})
+			},
+			want: func(t *testing.T) []testInstruction {
+				return []testInstruction{
+					{
+						programID: testSystemProgramHex,
+						accounts:  []testAccountMeta{{key(1), signer, isWritable}, {key(2), signer, isWritable}},
+						data:      "00000000" + oneSol + space + testStakeProgramHex,
+					},
+					{
+						programID: testStakeProgramHex,
+						accounts:  []testAccountMeta{{key(2), notSigner, isWritable}, {testRentHex, notSigner, readonly}},
+						data:      "00000000" + key(3) + key(4) + timestamp + epoch500 + key(6),
+					},
+					delegate,
+				}
+			},
+		},
+		{
+			name: "create account with seed",
+			build: func() ([]sdktypes.Instruction, error) {
+				return CreateStakeAccountWithSeedInstructions(CreateStakeAccountWithSeedParam{
+					From:       testAddress1,
+					Base:       testAddress3,
+					Seed:       "stake:0",
+					Staker:     testAddress3,
+					Withdrawer: testAddress4,
+					Lamports:   1000000000,
+				})
+			},
+			want: func(t *testing.T) []testInstruction {
+				stakeHex := addressHex(t, withSeed0)
+				return []testInstruction{
+					{
+						programID: testSystemProgramHex,
+						accounts: []testAccountMeta{
+							{key(1), signer, isWritable},
+							{stakeHex, notSigner, isWritable},
+							{key(3), signer, readonly},
+						},
+						data: "03000000" + key(3) + seed0 + oneSol + space + testStakeProgramHex,
+					},
+					{
+						programID: testStakeProgramHex,
+						accounts:  []testAccountMeta{{stakeHex, notSigner, isWritable}, {testRentHex, notSigner, readonly}},
+						data:      "00000000" + key(3) + key(4) + noLockup,
+					},
+				}
+			},
+		},
+		{
+			name: "create account with seed from base and delegate stake",
+			build: func() ([]sdktypes.Instruction, error) {
+				return CreateStakeAccountWithSeedInstructions(CreateStakeAccountWithSeedParam{
+					From:       testAddress3,
+					Base:       testAddress3,
+					Seed:       "stake:0",
+					Staker:     testAddress3,
+					Withdrawer: testAddress3,
+					Lamports:   1000000000,
+					Voter:      testAddress5,
+				})
+			},
+			want: func(t *testing.T) []testInstruction {
+				stakeHex := addressHex(t, withSeed0)
+				delegateWithSeed := delegate
+				delegateWithSeed.accounts = append([]testAccountMeta{{stakeHex, notSigner, isWritable}}, delegate.accounts[1:]...)
+				return []testInstruction{
+					{
+						programID: testSystemProgramHex,
+						accounts:  []testAccountMeta{{key(3), signer, isWritable}, {stakeHex, notSigner, isWritable}},
+						data:      "03000000" + key(3) + seed0 + oneSol + space + testStakeProgramHex,
+					},
+					{
+						programID: testStakeProgramHex,
+						accounts:  []testAccountMeta{{stakeHex, notSigner, isWritable}, {testRentHex, notSigner, readonly}},
+						data:      "00000000" + key(3) + key(3) + noLockup,
+					},
+					delegateWithSeed,
+				}
+			},
+		},
+		{
+			name: "deactivate",
+			build: func() ([]sdktypes.Instruction, error) {
+				instruction, err := DeactivateStakeInstruction(DeactivateStakeParam{Stake: testAddress2, Staker: testAddress3})
+				return []sdktypes.Instruction{instruction}, err
+			},
+			want: func(t *testing.T) []testInstruction {
+				return []testInstruction{{
+					programID: testStakeProgramHex,
+					accounts:  []testAccountMeta{{key(2), notSigner, isWritable}, {testClockHex, notSigner, readonly}, {key(3), signer, readonly}},
+					data:      "05000000",
+				}}
+			},
+		},
+		{
+			name: "withdraw with custodian",
+			build: func() ([]sdktypes.Instruction, error) {
+				instruction, err := WithdrawStakeInstruction(WithdrawStakeParam{
+					Stake:      testAddress2,
+					Withdrawer: testAddress4,
+					To:         testAddress7,
+					Lamports:   1000000000,
+					Custodian:  testAddress6,
+				})
+				return []sdktypes.Instruction{instruction}, err
+			},
+			want: func(t *testing.T) []testInstruction {
+				return []testInstruction{{
+					programID: testStakeProgramHex,
+					accounts: []testAccountMeta{
+						{key(2), notSigner, isWritable},
+						{key(7), notSigner, isWritable},
+						{testClockHex, notSigner, readonly},
+						{testStakeHistoryHex, notSigner, readonly},
+						{key(4), signer, readonly},
+						{key(6), signer, readonly},
+					},
+					data: "04000000" + oneSol,
+				}}
+			},
+		},
+		{
+			name: "split",
+			build: func() ([]sdktypes.Instruction, error) {
+				return SplitStakeInstructions(SplitStakeParam{Stake: testAddress2, Staker: testAddress3, SplitStake: testAddress7, Lamports: 1000000000})
+			},
+			want: func(t *testing.T) []testInstruction {
+				return []testInstruction{
+					{
+						programID: testSystemProgramHex,
+						accounts:  []testAccountMeta{{key(7), signer, isWritable}},
+						data:      "08000000" + space,
+					},
+					{
+						programID: testSystemProgramHex,
+						accounts:  []testAccountMeta{{key(7), signer, isWritable}},
+						data:      "01000000" + testStakeProgramHex,
+					},
+					split(key(7)),
+				}
+			},
+		},
+		{
+			name: "split with seed",
+			build: func() ([]sdktypes.Instruction, error) {
+				return SplitStakeWithSeedInstructions(SplitStakeWithSeedParam{Stake: testAddress2, Staker: testAddress3, Base: testAddress3, Seed: "stake:1", Lamports: 1000000000})
+			},
+			want: func(t *testing.T) []testInstruction {
+				splitStakeHex := addressHex(t, withSeed1)
+				return []testInstruction{
+					{
+						programID: testSystemProgramHex,
+						accounts:  []testAccountMeta{{splitStakeHex, notSigner, isWritable}, {key(3), signer, readonly}},
+						data:      "09000000" + key(3) + seed1 + space + testStakeProgramHex,
+					},
+					split(splitStakeHex),
+				}
+			},
+		},
+		{
+			name: "merge",
+			build: func() ([]sdktypes.Instruction, error) {
+				instruction, err := MergeStakeInstruction(MergeStakeParam{Destination: testAddress2, Source: testAddress7, Staker: testAddress3})
+				return []sdktypes.Instruction{instruction}, err
+			},
+			want: func(t *testing.T) []testInstruction {
+				return []testInstruction{{
+					programID: testStakeProgramHex,
+					accounts: []testAccountMeta{
+						{key(2), notSigner, isWritable},
+						{key(7), notSigner, isWritable},
+						{testClockHex, notSigner, readonly},
+						{testStakeHistoryHex, notSigner, readonly},
+						{key(3), signer, readonly},
+					},
+					data: "07000000",
+				}}
+			},
+		},
+		{
+			name: "authorize withdrawer with custodian",
+			build: func() ([]sdktypes.Instruction, error) {
+				instruction, err := AuthorizeStakeInstruction(AuthorizeStakeParam{
+					Stake:        testAddress2,
+					Authority:    testAddress4,
+					NewAuthority: testAddress8,
+					Type:         StakeAuthorizeWithdrawer,
+					Custodian:    testAddress6,
+				})
+				return []sdktypes.Instruction{instruction}, err
+			},
+			want: func(t *testing.T) []testInstruction {
+				return []testInstruction{{
+					programID: testStakeProgramHex,
+					accounts: []testAccountMeta{
+						{key(2), notSigner, isWritable},
+						{testClockHex, notSigner, readonly},
+						{key(4), signer, readonly},
+						{key(6), signer, readonly},
+					},
+					data: "01000000" + key(8) + "01000000",
+				}}
+			},
+		},
+		{
+			name: "authorize staker with seed",
+			build: func() ([]sdktypes.Instruction, error) {
+				instruction, err := AuthorizeStakeWithSeedInstruction(AuthorizeStakeWithSeedParam{
+					Stake:          testAddress2,
+					AuthorityBase:  testAddress3,
+					AuthoritySeed:  "stake:0",
+					AuthorityOwner: testAddress1,
+					NewAuthority:   testAddress8,
+					Type:           StakeAuthorizeStaker,
+				})
+				return []sdktypes.Instruction{instruction}, err
+			},
+			want: func(t *testing.T) []testInstruction {
+				return []testInstruction{{
+					programID: testStakeProgramHex,
+					accounts: []testAccountMeta{
+						{key(2), notSigner, isWritable},
+						{key(3), signer, readonly},
+						{testClockHex, notSigner, readonly},
+					},
+					data: "08000000" + key(8) + "00000000" + seed0 + key(1),
+				}}
+			},
+		},
+		{
+			name: "set lockup epoch and custodian",
+			build: func() ([]sdktypes.Instruction, error) {
+				instruction, err := SetStakeLockupInstruction(SetStakeLockupParam{
+					Stake:        testAddress2,
+					Custodian:    testAddress6,
+					Epoch:        &epoch,
+					NewCustodian: testAddress8,
+				})
+				return []sdktypes.Instruction{instruction}, err
+			},
+			want: func(t *testing.T) []testInstruction {
+				return []testInstruction{{
+					programID: testStakeProgramHex,
+					accounts:  []testAccountMeta{{key(2), notSigner, isWritable}, {key(6), signer, readonly}},
+					data:      "06000000" + "00" + "01" + epoch500 + "01" + key(8),
+				}}
+			},
+		},
+		{
+			name: "error: invalid voter",
+			build: func() ([]sdktypes.Instruction, error) {
+				return CreateStakeAccountInstructions(CreateStakeAccountParam{
+					From:       testAddress1,
+					Stake:      testAddress2,
+					Staker:     testAddress3,
+					Withdrawer: testAddress4,
+					Lamports:   1000000000,
+					Voter:      "invalid",
+				})
+			},
+			wantErr: true,
+		},
+		{
+			name: "error: seed is too long",
+			build: func() ([]sdktypes.Instruction, error) {
+				return SplitStakeWithSeedInstructions(SplitStakeWithSeedParam{Stake: testAddress2, Staker: testAddress3, Base: testAddress3, Seed: strings.Repeat("s", 33)})
+			},
+			wantErr: true,
+		},
+		{
+			name: "error: invalid custodian",
+			build: func() ([]sdktypes.Instruction, error) {
+				instruction, err := WithdrawStakeInstruction(WithdrawStakeParam{Stake: testAddress2, Withdrawer: testAddress4, To: testAddress7, Custodian: testAddress1[1:]})
+				return []sdktypes.Instruction{instruction}, err
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instructions, err := tt.build()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("build error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			got := toTestInstructions(instructions)
+			if want := tt.want(t); !reflect.DeepEqual(got, want) {
+				t.Errorf("instructions = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+// TestStakeInstructions_Message compiles the instructions into a legacy message, which is what wallets sign.
+func TestStakeInstructions_Message(t *testing.T) {
+	instructions, err := CreateStakeAccountInstructions(CreateStakeAccountParam{
+		From:       testAddress1,
+		Stake:      testAddress2,
+		Staker:     testAddress1,
+		Withdrawer: testAddress1,
+		Lamports:   1000000000,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := sdktypes.NewMessage(sdktypes.NewMessageParam{
+		FeePayer:        common.PublicKeyFromString(testAddress1),
+		RecentBlockhash: testAddress8,
+		Instructions:    instructions,
+	})
+	got, err := message.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Accounts are ordered as by solana_program::message::Message::new: writable signers, then readonly non-signers sorted by key.
+	want := "020003" + // 2 signatures, 0 readonly signed, 3 readonly unsigned accounts
+		"05" + testKeyHex(1) + testKeyHex(2) + testSystemProgramHex + testStakeProgramHex + testRentHex +
+		testKeyHex(8) + // recent blockhash
+		"02" +
+		"02" + "020001" + "34" + "00000000" + "00ca9a3b00000000" + "c800000000000000" + testStakeProgramHex +
+		"03" + "020104" + "74" + "00000000" + testKeyHex(1) + testKeyHex(1) + strings.Repeat("00", 48)
+	if hex.EncodeToString(got) != want {
+		t.Errorf("message = %x, want %s", got, want)
+	}
+}
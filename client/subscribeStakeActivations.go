@@ -0,0 +1,400 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	sdkRpc "github.com/blocto/solana-go-sdk/rpc"
+	"github.com/gorilla/websocket"
+	"github.com/skport/solana-rpc-client-extensions-go/types"
+
+	"golang.org/x/xerrors"
+)
+
+// SubscribeOption configures SubscribeStakeActivations.
+type SubscribeOption func(*subscribeOptions)
+
+type subscribeOptions struct {
+	encoding   sdkRpc.AccountEncoding
+	commitment sdkRpc.Commitment
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	dialer     *websocket.Dialer
+}
+
+// WithSubscribeEncoding sets the encoding of the stake account notifications, jsonParsed (default) or base64.
+// Only base64 includes the stake flags. The StakeHistory sysvar is always subscribed with jsonParsed.
+func WithSubscribeEncoding(encoding sdkRpc.AccountEncoding) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.encoding = encoding
+	}
+}
+
+// WithSubscribeCommitment sets the commitment of the account notifications, finalized by default.
+// It should match the commitment of the AccountSource.
+func WithSubscribeCommitment(commitment sdkRpc.Commitment) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.commitment = commitment
+	}
+}
+
+// WithReconnectBackoff sets the delay before reconnecting, which doubles from min up to max while reconnecting fails.
+func WithReconnectBackoff(min time.Duration, max time.Duration) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.minBackoff = min
+		o.maxBackoff = max
+	}
+}
+
+// StakeActivationUpdate is delivered by SubscribeStakeActivations.
+type StakeActivationUpdate struct {
+	// Address is the stake account, or empty for an update about the connection.
+	Address string
+	// Epoch is the epoch of the calculation.
+	Epoch uint64
+	// Slot is the slot of the latest notification, or 0 before the first notification.
+	Slot       uint64
+	Activation *GetStakeActivationResponse
+	// Err is an *AccountError if the activation of Address can't be calculated, e.g. the account was closed,
+	// or wraps ErrDisconnected if the connection was lost. The subscription reconnects by itself.
+	Err error
+}
+
+// SubscribeStakeActivations watches the stake accounts at addresses with accountSubscribe on the websocket endpoint,
+// e.g. "wss://api.mainnet-beta.solana.com", and delivers their activation whenever it changes.
+//
+// The activation is recalculated when a stake account or the StakeHistory sysvar changes, and when slotSubscribe reports a new epoch.
+// source is used to fetch the EpochSchedule sysvar, and a snapshot of the accounts after each (re)connection,
+// since accountSubscribe doesn't notify the current value.
+//
+// The returned channel is closed after ctx is canceled.
+func SubscribeStakeActivations(ctx context.Context, endpoint string, source AccountSource, addresses []string, opts ...SubscribeOption) (<-chan *StakeActivationUpdate, error) {
+	o := &subscribeOptions{
+		encoding:   sdkRpc.AccountEncodingJsonParsed,
+		commitment: sdkRpc.CommitmentFinalized,
+		minBackoff: time.Second,
+		maxBackoff: time.Minute,
+		dialer:     websocket.DefaultDialer,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.encoding != sdkRpc.AccountEncodingJsonParsed && o.encoding != sdkRpc.AccountEncodingBase64 {
+		return nil, fmt.Errorf("unsupported encoding: %s", o.encoding)
+	}
+
+	epochScheduleAccount, err := GetEpochScheduleAccount(ctx, source)
+	if err != nil {
+		return nil, xerrors.Errorf("wrap: %w", err)
+	}
+
+	s := &stakeActivationSubscription{
+		endpoint:    endpoint,
+		source:      source,
+		addresses:   addresses,
+		o:           o,
+		updates:     make(chan *StakeActivationUpdate, len(addresses)),
+		schedule:    epochScheduleAccount.Data.Parsed.Info,
+		accounts:    map[string]*types.StakeAccount{},
+		accountErrs: map[string]error{},
+		last:        map[string]*StakeActivationUpdate{},
+	}
+
+	// The first connection is made synchronously to report a wrong endpoint.
+	conn, err := s.connect(ctx)
+	if err != nil {
+		return nil, xerrors.Errorf("wrap: %w", err)
+	}
+	go s.run(ctx, conn)
+	return s.updates, nil
+}
+
+type stakeActivationSubscription struct {
+	endpoint  string
+	source    AccountSource
+	addresses []string
+	o         *subscribeOptions
+	updates   chan *StakeActivationUpdate
+
+	schedule     types.EpochSchedule
+	epoch        uint64
+	slot         uint64
+	stakeHistory *types.StakeHistoryAccount
+	accounts     map[string]*types.StakeAccount
+	accountErrs  map[string]error
+	last         map[string]*StakeActivationUpdate
+
+	// subscriptions maps the subscription ids of the current connection to the account addresses, or "" for slotSubscribe.
+	subscriptions map[uint64]string
+}
+
+type accountSubscribeConfig struct {
+	Encoding   sdkRpc.AccountEncoding `json:"encoding"`
+	Commitment sdkRpc.Commitment      `json:"commitment"`
+}
+
+type subscriptionMessage struct {
+	Id     *uint64                  `json:"id"`
+	Result json.RawMessage          `json:"result"`
+	Error  *sdkRpc.JsonRpcError     `json:"error"`
+	Method string                   `json:"method"`
+	Params subscriptionNotification `json:"params"`
+}
+
+type subscriptionNotification struct {
+	Result       json.RawMessage `json:"result"`
+	Subscription uint64          `json:"subscription"`
+}
+
+type slotNotification struct {
+	Parent uint64 `json:"parent"`
+	Root   uint64 `json:"root"`
+	Slot   uint64 `json:"slot"`
+}
+
+func (s *stakeActivationSubscription) run(ctx context.Context, conn *websocket.Conn) {
+	defer close(s.updates)
+
+	backoff := s.o.minBackoff
+	for {
+		err := s.serve(ctx, conn)
+		conn.Close()
+		if ctx.Err() != nil {
+			return
+		}
+		s.send(ctx, &StakeActivationUpdate{Epoch: s.epoch, Slot: s.slot, Err: xerrors.Errorf("%w: %v", ErrDisconnected, err)})
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			if conn, err = s.connect(ctx); err == nil {
+				backoff = s.o.minBackoff
+				break
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			s.send(ctx, &StakeActivationUpdate{Epoch: s.epoch, Slot: s.slot, Err: xerrors.Errorf("%w: %v", ErrDisconnected, err)})
+			if backoff *= 2; backoff > s.o.maxBackoff {
+				backoff = s.o.maxBackoff
+			}
+		}
+	}
+}
+
+// connect subscribes to the accounts and slots, and delivers the activations of a snapshot from source.
+// The snapshot is taken after the subscriptions are confirmed so that no change is missed.
+func (s *stakeActivationSubscription) connect(ctx context.Context) (*websocket.Conn, error) {
+	conn, _, err := s.o.dialer.DialContext(ctx, s.endpoint, nil)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to dial: %w", err)
+	}
+	stop := closeOnDone(ctx, conn)
+	if err := s.subscribe(conn); err != nil {
+		stop()
+		conn.Close()
+		return nil, err
+	}
+	if err := s.snapshot(ctx); err != nil {
+		stop()
+		conn.Close()
+		return nil, err
+	}
+	stop()
+	return conn, nil
+}
+
+func (s *stakeActivationSubscription) subscribe(conn *websocket.Conn) error {
+	// Request ids are the index of the address, and len(addresses) + 1 for slotSubscribe.
+	targets := append(append([]string{}, s.addresses...), StakeHistoryAccountAddress, "")
+	for i, target := range targets {
+		req := sdkRpc.JsonRpcRequest{JsonRpc: "2.0", Id: uint64(i), Method: "slotSubscribe"}
+		if target != "" {
+			config := accountSubscribeConfig{Encoding: s.o.encoding, Commitment: s.o.commitment}
+			if target == StakeHistoryAccountAddress {
+				config.Encoding = sdkRpc.AccountEncodingJsonParsed
+			}
+			req.Method = "accountSubscribe"
+			req.Params = []any{target, config}
+		}
+		if err := conn.WriteJSON(req); err != nil {
+			return xerrors.Errorf("failed to write %s: %w", req.Method, err)
+		}
+	}
+
+	s.subscriptions = map[uint64]string{}
+	for confirmed := 0; confirmed < len(targets); {
+		var msg subscriptionMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			return xerrors.Errorf("failed to read subscription: %w", err)
+		}
+		// Notifications before the snapshot are superseded by it.
+		if msg.Id == nil {
+			continue
+		}
+		if msg.Error != nil {
+			return xerrors.Errorf("failed to subscribe: %w", msg.Error)
+		}
+		if *msg.Id >= uint64(len(targets)) {
+			return fmt.Errorf("unexpected response id: %d", *msg.Id)
+		}
+		var subscription uint64
+		if err := json.Unmarshal(msg.Result, &subscription); err != nil {
+			return xerrors.Errorf("failed to unmarshal subscription id: %w", err)
+		}
+		s.subscriptions[subscription] = targets[*msg.Id]
+		confirmed++
+	}
+	return nil
+}
+
+func (s *stakeActivationSubscription) snapshot(ctx context.Context) error {
+	epoch, err := s.source.GetEpoch(ctx)
+	if err != nil {
+		return xerrors.Errorf("failed to get epoch: %w", err)
+	}
+	if epoch > s.epoch {
+		s.epoch = epoch
+	}
+	stakeHistoryAccount, err := GetStakeHistoryAccount(ctx, s.source)
+	if err != nil {
+		return xerrors.Errorf("wrap: %w", err)
+	}
+	s.stakeHistory = stakeHistoryAccount
+
+	if len(s.addresses) > 0 {
+		raws, err := s.source.GetMultipleAccounts(ctx, s.addresses)
+		if err != nil {
+			return xerrors.Errorf("wrap: %w", err)
+		}
+		for i, raw := range raws {
+			s.setStakeAccount(s.addresses[i], raw)
+		}
+	}
+
+	s.deliver(ctx, s.addresses...)
+	return nil
+}
+
+func (s *stakeActivationSubscription) serve(ctx context.Context, conn *websocket.Conn) error {
+	stop := closeOnDone(ctx, conn)
+	defer stop()
+
+	for {
+		var msg subscriptionMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			return xerrors.Errorf("failed to read notification: %w", err)
+		}
+		target, ok := s.subscriptions[msg.Params.Subscription]
+		if msg.Id != nil || !ok {
+			continue
+		}
+
+		switch msg.Method {
+		case "slotNotification":
+			var notification slotNotification
+			if err := json.Unmarshal(msg.Params.Result, &notification); err != nil {
+				return xerrors.Errorf("failed to unmarshal slotNotification: %w", err)
+			}
+			// The root slot is finalized, the notified slot is only processed.
+			slot := notification.Slot
+			if s.o.commitment == sdkRpc.CommitmentFinalized {
+				slot = notification.Root
+			}
+			s.slot = slot
+			if epoch, _ := s.schedule.GetEpochAndSlotIndex(slot); epoch > s.epoch {
+				s.epoch = epoch
+				s.deliver(ctx, s.addresses...)
+			}
+
+		case "accountNotification":
+			var notification sdkRpc.ValueWithContext[json.RawMessage]
+			if err := json.Unmarshal(msg.Params.Result, &notification); err != nil {
+				return xerrors.Errorf("failed to unmarshal accountNotification: %w", err)
+			}
+			if notification.Context.Slot > s.slot {
+				s.slot = notification.Context.Slot
+			}
+			if target == StakeHistoryAccountAddress {
+				stakeHistoryAccount, err := types.DecodeStakeHistoryAccount(nullToNil(notification.Value))
+				if err != nil {
+					return xerrors.Errorf("wrap: %w", err)
+				}
+				s.stakeHistory = stakeHistoryAccount
+				s.deliver(ctx, s.addresses...)
+				continue
+			}
+			s.setStakeAccount(target, nullToNil(notification.Value))
+			s.deliver(ctx, target)
+		}
+	}
+}
+
+func (s *stakeActivationSubscription) setStakeAccount(address string, raw json.RawMessage) {
+	delete(s.accounts, address)
+	delete(s.accountErrs, address)
+	if raw == nil {
+		s.accountErrs[address] = &AccountError{Address: address, Err: ErrAccountNotFound}
+		return
+	}
+	stakeAccount, err := types.DecodeStakeAccount(raw)
+	if err != nil {
+		s.accountErrs[address] = &AccountError{Address: address, Err: err}
+		return
+	}
+	s.accounts[address] = stakeAccount
+}
+
+// deliver recalculates the activations of addresses, and sends the ones which changed.
+func (s *stakeActivationSubscription) deliver(ctx context.Context, addresses ...string) {
+	for _, address := range addresses {
+		update := &StakeActivationUpdate{Address: address, Epoch: s.epoch, Slot: s.slot, Err: s.accountErrs[address]}
+		if update.Err == nil {
+			update.Activation, update.Err = GetStakeActivation(address, s.epoch, s.accounts[address], s.stakeHistory, WithCurrentEpoch())
+			// The StakeHistory sysvar of the new epoch is notified after the slot of the epoch boundary.
+			if errors.Is(update.Err, ErrMissingStakeHistoryEntry) {
+				continue
+			}
+		}
+
+		if last, ok := s.last[address]; ok && reflect.DeepEqual(last.Activation, update.Activation) && errorString(last.Err) == errorString(update.Err) {
+			continue
+		}
+		s.last[address] = update
+		s.send(ctx, update)
+	}
+}
+
+func (s *stakeActivationSubscription) send(ctx context.Context, update *StakeActivationUpdate) {
+	select {
+	case s.updates <- update:
+	case <-ctx.Done():
+	}
+}
+
+// closeOnDone closes conn when ctx is canceled, to unblock a read, until stop is called.
+func closeOnDone(ctx context.Context, conn *websocket.Conn) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+	return func() { close(done) }
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -0,0 +1,224 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	sdkRpc "github.com/blocto/solana-go-sdk/rpc"
+	"github.com/gorilla/websocket"
+	"github.com/skport/solana-rpc-client-extensions-go/types"
+)
+
+const testSlotsPerEpoch = 32
+
+// testWebsocketServer stands in for the websocket endpoint of a validator.
+// Each connection is delivered on conns after its subscriptions are confirmed.
+type testWebsocketServer struct {
+	t        *testing.T
+	requests int
+	conns    chan *testWebsocketConn
+}
+
+type testWebsocketConn struct {
+	t    *testing.T
+	conn *websocket.Conn
+	// subscriptions maps the subscribed accounts, or "" for slotSubscribe, to the subscription ids.
+	subscriptions map[string]uint64
+	encodings     map[string]sdkRpc.AccountEncoding
+}
+
+func newTestWebsocketServer(t *testing.T, requests int) (*testWebsocketServer, string) {
+	s := &testWebsocketServer{t: t, requests: requests, conns: make(chan *testWebsocketConn, 1)}
+	server := httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	t.Cleanup(server.Close)
+	return s, "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+func (s *testWebsocketServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+	if err != nil {
+		s.t.Errorf("failed to upgrade: %v", err)
+		return
+	}
+
+	c := &testWebsocketConn{t: s.t, conn: conn, subscriptions: map[string]uint64{}, encodings: map[string]sdkRpc.AccountEncoding{}}
+	for i := 0; i < s.requests; i++ {
+		var req struct {
+			Id     uint64            `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		if err := conn.ReadJSON(&req); err != nil {
+			s.t.Errorf("failed to read request: %v", err)
+			return
+		}
+
+		target := ""
+		if req.Method == "accountSubscribe" {
+			var config accountSubscribeConfig
+			if len(req.Params) != 2 || json.Unmarshal(req.Params[0], &target) != nil || json.Unmarshal(req.Params[1], &config) != nil {
+				s.t.Errorf("unexpected accountSubscribe params: %s", req.Params)
+			}
+			c.encodings[target] = config.Encoding
+		}
+		c.subscriptions[target] = 100 + req.Id
+		if err := conn.WriteJSON(map[string]any{"jsonrpc": "2.0", "id": req.Id, "result": c.subscriptions[target]}); err != nil {
+			s.t.Errorf("failed to write response: %v", err)
+			return
+		}
+	}
+	s.conns <- c
+}
+
+func (c *testWebsocketConn) notify(method string, subscription uint64, result any) {
+	c.t.Helper()
+	err := c.conn.WriteJSON(map[string]any{
+		"jsonrpc": "2.0",
+		"method":  method,
+		"params":  map[string]any{"result": result, "subscription": subscription},
+	})
+	if err != nil {
+		c.t.Fatalf("failed to write %s: %v", method, err)
+	}
+}
+
+func (c *testWebsocketConn) notifyAccount(address string, slot uint64, account any) {
+	c.t.Helper()
+	c.notify("accountNotification", c.subscriptions[address], map[string]any{"context": map[string]any{"slot": slot}, "value": account})
+}
+
+func (c *testWebsocketConn) notifySlot(slot uint64) {
+	c.t.Helper()
+	c.notify("slotNotification", c.subscriptions[""], map[string]any{"parent": slot - 1, "root": slot, "slot": slot})
+}
+
+// newTestStakeHistory returns the StakeHistory sysvar of epoch, in which stake activating in the previous epoch fully activates.
+func newTestStakeHistory(epoch uint64, activating uint64) *types.StakeHistoryAccount {
+	h := &types.StakeHistoryAccount{Owner: SysvarProgramAddress}
+	h.Data.Program = "sysvar"
+	h.Data.Parsed.Type = "stakeHistory"
+	entry := types.StakeHistoryAccountInfo{Epoch: int(epoch) - 1}
+	entry.StakeHistory.Effective = 1000000000000000
+	entry.StakeHistory.Activating = activating
+	h.Data.Parsed.Info = []types.StakeHistoryAccountInfo{entry}
+	return h
+}
+
+func newTestEpochScheduleAccount() *types.EpochScheduleAccount {
+	a := &types.EpochScheduleAccount{Owner: SysvarProgramAddress}
+	a.Data.Program = "sysvar"
+	a.Data.Parsed.Type = "epochSchedule"
+	a.Data.Parsed.Info = types.EpochSchedule{LeaderScheduleSlotOffset: testSlotsPerEpoch, SlotsPerEpoch: testSlotsPerEpoch}
+	return a
+}
+
+func nextStakeActivationUpdate(t *testing.T, updates <-chan *StakeActivationUpdate) *StakeActivationUpdate {
+	t.Helper()
+	select {
+	case update, ok := <-updates:
+		if !ok {
+			t.Fatal("updates closed")
+		}
+		return update
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for an update")
+	}
+	return nil
+}
+
+func TestSubscribeStakeActivations(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const stake = 10000000000
+	activating := newTestStakeAccount(10002282880, stake, 10, types.MaxEpoch)
+	deactivating := newTestStakeAccount(10002282880, stake, 10, 11)
+
+	source := NewMemoryAccountSource(10)
+	source.SetAccount(EpochScheduleAccountAddress, newTestEpochScheduleAccount())
+	source.SetAccount(StakeHistoryAccountAddress, newTestStakeHistory(10, 0))
+	source.SetAccount(testAddress2, activating)
+
+	server, endpoint := newTestWebsocketServer(t, 3)
+	updates, err := SubscribeStakeActivations(ctx, endpoint, source, []string{testAddress2},
+		WithSubscribeEncoding(sdkRpc.AccountEncodingBase64),
+		WithReconnectBackoff(10*time.Millisecond, 100*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("SubscribeStakeActivations error = %v", err)
+	}
+	conn := <-server.conns
+	if conn.encodings[testAddress2] != sdkRpc.AccountEncodingBase64 || conn.encodings[StakeHistoryAccountAddress] != sdkRpc.AccountEncodingJsonParsed {
+		t.Errorf("encodings = %v", conn.encodings)
+	}
+
+	check := func(update *StakeActivationUpdate, epoch uint64, state string, wantErr error) {
+		t.Helper()
+		if !errors.Is(update.Err, wantErr) {
+			t.Fatalf("update error = %v, wantErr %v", update.Err, wantErr)
+		}
+		if wantErr != nil {
+			return
+		}
+		if update.Address != testAddress2 || update.Epoch != epoch || update.Activation.State != state {
+			t.Fatalf("update = %+v, activation = %+v, want epoch %d, state %s", update, update.Activation, epoch, state)
+		}
+	}
+
+	// The snapshot
+	check(nextStakeActivationUpdate(t, updates), 10, "activating", nil)
+
+	// The new epoch is calculated after the StakeHistory sysvar of the epoch is notified.
+	conn.notifySlot(11 * testSlotsPerEpoch)
+	conn.notifyAccount(StakeHistoryAccountAddress, 11*testSlotsPerEpoch, newTestStakeHistory(11, stake))
+	update := nextStakeActivationUpdate(t, updates)
+	check(update, 11, "active", nil)
+	if update.Slot != 11*testSlotsPerEpoch {
+		t.Errorf("update slot = %d, want %d", update.Slot, 11*testSlotsPerEpoch)
+	}
+
+	// An unchanged account is not delivered again.
+	conn.notifyAccount(testAddress2, 11*testSlotsPerEpoch+1, newTestBase64StakeAccount(activating))
+	conn.notifyAccount(testAddress2, 11*testSlotsPerEpoch+2, newTestBase64StakeAccount(deactivating))
+	check(nextStakeActivationUpdate(t, updates), 11, "deactivating", nil)
+
+	conn.notifyAccount(testAddress2, 11*testSlotsPerEpoch+3, nil)
+	check(nextStakeActivationUpdate(t, updates), 11, "", ErrAccountNotFound)
+
+	// After reconnecting, the activation is delivered from a new snapshot.
+	source.SetEpoch(11)
+	source.SetAccount(StakeHistoryAccountAddress, newTestStakeHistory(11, stake))
+	conn.conn.Close()
+	if update := nextStakeActivationUpdate(t, updates); update.Address != "" || !errors.Is(update.Err, ErrDisconnected) {
+		t.Fatalf("update = %+v, want ErrDisconnected", update)
+	}
+	<-server.conns
+	check(nextStakeActivationUpdate(t, updates), 11, "active", nil)
+
+	cancel()
+	for range updates {
+	}
+}
+
+func TestSubscribeStakeActivations_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	source := NewMemoryAccountSource(10)
+	if _, err := SubscribeStakeActivations(ctx, "ws://127.0.0.1:0", source, nil); !errors.Is(err, ErrAccountNotFound) {
+		t.Errorf("SubscribeStakeActivations without EpochSchedule error = %v, want %v", err, ErrAccountNotFound)
+	}
+
+	source.SetAccount(EpochScheduleAccountAddress, newTestEpochScheduleAccount())
+	if _, err := SubscribeStakeActivations(ctx, "ws://127.0.0.1:0", source, nil, WithSubscribeEncoding(sdkRpc.AccountEncodingBase58)); err == nil {
+		t.Errorf("SubscribeStakeActivations with base58 succeeded")
+	}
+	if _, err := SubscribeStakeActivations(ctx, "ws://127.0.0.1:0", source, nil); err == nil {
+		t.Errorf("SubscribeStakeActivations with a wrong endpoint succeeded")
+	}
+}
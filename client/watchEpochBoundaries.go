@@ -0,0 +1,141 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/skport/solana-rpc-client-extensions-go/types"
+
+	"golang.org/x/xerrors"
+)
+
+// WatchOption configures WatchEpochBoundaries.
+type WatchOption func(*watchOptions)
+
+type watchOptions struct {
+	interval  time.Duration
+	lastEpoch *uint64
+}
+
+// WithPollInterval sets how often the epoch is polled, 10 seconds by default.
+func WithPollInterval(interval time.Duration) WatchOption {
+	return func(o *watchOptions) {
+		o.interval = interval
+	}
+}
+
+// WithLastEpoch resumes watching after epoch, the last epoch which was handled, e.g. by a restarted job.
+// The boundaries of the epochs after it up to the current epoch are delivered first.
+// By default, the watch starts at the current epoch without an event for it.
+func WithLastEpoch(epoch uint64) WatchOption {
+	return func(o *watchOptions) {
+		o.lastEpoch = &epoch
+	}
+}
+
+// EpochBoundaryEvent is delivered by WatchEpochBoundaries once for each new epoch.
+type EpochBoundaryEvent struct {
+	// Epoch is the epoch which began.
+	Epoch uint64
+	// Effective, Activating and Deactivating are the cluster totals of the epoch which ended, Epoch-1,
+	// from the new entry of the StakeHistory sysvar.
+	Effective    uint64
+	Activating   uint64
+	Deactivating uint64
+	// MissingEntry is set when the StakeHistory sysvar has no entry of Epoch-1, e.g. because the sysvar only keeps the newest 512 epochs
+	// and WithLastEpoch resumed before them. The totals are then unknown, and 0.
+	MissingEntry bool
+	// StakeHistory is the StakeHistory sysvar, which includes the entry of Epoch-1.
+	StakeHistory *types.StakeHistoryAccount
+	// Err is set when polling failed, and Epoch is 0. Polling is retried at the next interval.
+	Err error
+}
+
+// WatchEpochBoundaries polls source and delivers an event when a new epoch begins and the StakeHistory sysvar has the entry of the epoch which ended.
+// Events are delivered in order and exactly once per epoch, even if several epochs passed between polls.
+//
+// The returned channel is closed after ctx is canceled.
+func WatchEpochBoundaries(ctx context.Context, source AccountSource, opts ...WatchOption) (<-chan *EpochBoundaryEvent, error) {
+	o := &watchOptions{interval: 10 * time.Second}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	lastEpoch, err := source.GetEpoch(ctx)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to get epoch: %w", err)
+	}
+	if o.lastEpoch != nil && *o.lastEpoch < lastEpoch {
+		lastEpoch = *o.lastEpoch
+	}
+
+	events := make(chan *EpochBoundaryEvent)
+	go func() {
+		defer close(events)
+
+		ticker := time.NewTicker(o.interval)
+		defer ticker.Stop()
+		for {
+			var err error
+			if lastEpoch, err = pollEpochBoundaries(ctx, source, lastEpoch, events); err != nil && ctx.Err() == nil {
+				select {
+				case events <- &EpochBoundaryEvent{Err: err}:
+				case <-ctx.Done():
+				}
+			}
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events, nil
+}
+
+// pollEpochBoundaries delivers the events of the epochs after lastEpoch, and returns the last delivered epoch.
+func pollEpochBoundaries(ctx context.Context, source AccountSource, lastEpoch uint64, events chan<- *EpochBoundaryEvent) (uint64, error) {
+	epoch, err := source.GetEpoch(ctx)
+	if err != nil {
+		return lastEpoch, xerrors.Errorf("failed to get epoch: %w", err)
+	}
+	if epoch <= lastEpoch {
+		return lastEpoch, nil
+	}
+
+	stakeHistoryAccount, err := GetStakeHistoryAccount(ctx, source)
+	if err != nil {
+		return lastEpoch, xerrors.Errorf("wrap: %w", err)
+	}
+	// The StakeHistory sysvar may be fetched from a node which has not reached the epoch yet,
+	// or before the entry of the epoch which ended is added. It's polled again at the next interval.
+	var newest int
+	for _, entry := range stakeHistoryAccount.Data.Parsed.Info {
+		if entry.Epoch > newest {
+			newest = entry.Epoch
+		}
+	}
+	if len(stakeHistoryAccount.Data.Parsed.Info) == 0 || uint64(newest) < epoch-1 {
+		return lastEpoch, nil
+	}
+
+	for e := lastEpoch + 1; e <= epoch; e++ {
+		event := &EpochBoundaryEvent{Epoch: e, StakeHistory: stakeHistoryAccount}
+		if entry := getSolanaStakeHistoryEntry(stakeHistoryAccount, e-1); entry != nil {
+			event.Effective = entry.StakeHistory.Effective
+			event.Activating = entry.StakeHistory.Activating
+			event.Deactivating = entry.StakeHistory.Deactivating
+		} else {
+			event.MissingEntry = true
+		}
+
+		select {
+		case events <- event:
+			lastEpoch = e
+		case <-ctx.Done():
+			return lastEpoch, ctx.Err()
+		}
+	}
+	return lastEpoch, nil
+}
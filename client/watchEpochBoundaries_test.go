@@ -0,0 +1,170 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/skport/solana-rpc-client-extensions-go/types"
+)
+
+// newTestStakeHistoryEntries returns the StakeHistory sysvar of the epoch after newest, with an entry for each epoch from oldest to newest.
+func newTestStakeHistoryEntries(oldest uint64, newest uint64) *types.StakeHistoryAccount {
+	h := &types.StakeHistoryAccount{Owner: SysvarProgramAddress}
+	h.Data.Program = "sysvar"
+	h.Data.Parsed.Type = "stakeHistory"
+	h.Data.Parsed.Info = []types.StakeHistoryAccountInfo{}
+	for e := newest; e >= oldest && e <= newest; e-- {
+		entry := types.StakeHistoryAccountInfo{Epoch: int(e)}
+		entry.StakeHistory.Effective = e * 1000
+		entry.StakeHistory.Activating = e * 100
+		entry.StakeHistory.Deactivating = e * 10
+		h.Data.Parsed.Info = append(h.Data.Parsed.Info, entry)
+	}
+	return h
+}
+
+type testEpochBoundary struct {
+	Epoch                               uint64
+	Effective, Activating, Deactivating uint64
+	MissingEntry                        bool
+}
+
+func nextEpochBoundaries(t *testing.T, events <-chan *EpochBoundaryEvent, n int) []testEpochBoundary {
+	t.Helper()
+	var got []testEpochBoundary
+	for len(got) < n {
+		select {
+		case event := <-events:
+			if event.Err != nil {
+				t.Fatalf("event error = %v", event.Err)
+			}
+			got = append(got, testEpochBoundary{event.Epoch, event.Effective, event.Activating, event.Deactivating, event.MissingEntry})
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for events, got %+v", got)
+		}
+	}
+	return got
+}
+
+func assertNoEpochBoundary(t *testing.T, events <-chan *EpochBoundaryEvent) {
+	t.Helper()
+	select {
+	case event := <-events:
+		t.Fatalf("unexpected event: %+v", event)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestWatchEpochBoundaries(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	source := NewMemoryAccountSource(10)
+	source.SetAccount(StakeHistoryAccountAddress, newTestStakeHistoryEntries(0, 9))
+
+	events, err := WatchEpochBoundaries(ctx, source, WithPollInterval(time.Millisecond))
+	if err != nil {
+		t.Fatalf("WatchEpochBoundaries error = %v", err)
+	}
+	assertNoEpochBoundary(t, events)
+
+	// The event waits for the entry of the epoch which ended.
+	source.SetEpoch(11)
+	assertNoEpochBoundary(t, events)
+	source.SetAccount(StakeHistoryAccountAddress, newTestStakeHistoryEntries(0, 10))
+	want := []testEpochBoundary{{11, 10000, 1000, 100, false}}
+	if got := nextEpochBoundaries(t, events, 1); !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %+v, want %+v", got, want)
+	}
+	assertNoEpochBoundary(t, events)
+
+	// Epochs passed between polls are delivered in order.
+	source.SetEpoch(14)
+	source.SetAccount(StakeHistoryAccountAddress, newTestStakeHistoryEntries(0, 13))
+	want = []testEpochBoundary{{12, 11000, 1100, 110, false}, {13, 12000, 1200, 120, false}, {14, 13000, 1300, 130, false}}
+	if got := nextEpochBoundaries(t, events, 3); !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %+v, want %+v", got, want)
+	}
+	assertNoEpochBoundary(t, events)
+
+	cancel()
+	for range events {
+	}
+}
+
+func TestWatchEpochBoundaries_LastEpoch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	source := NewMemoryAccountSource(10)
+	source.SetAccount(StakeHistoryAccountAddress, newTestStakeHistoryEntries(0, 9))
+
+	events, err := WatchEpochBoundaries(ctx, source, WithPollInterval(time.Millisecond), WithLastEpoch(8))
+	if err != nil {
+		t.Fatalf("WatchEpochBoundaries error = %v", err)
+	}
+	want := []testEpochBoundary{{9, 8000, 800, 80, false}, {10, 9000, 900, 90, false}}
+	if got := nextEpochBoundaries(t, events, 2); !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %+v, want %+v", got, want)
+	}
+	assertNoEpochBoundary(t, events)
+}
+
+func TestWatchEpochBoundaries_MissingEntry(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The sysvar has dropped the entries before epoch 8.
+	source := NewMemoryAccountSource(10)
+	source.SetAccount(StakeHistoryAccountAddress, newTestStakeHistoryEntries(8, 9))
+
+	events, err := WatchEpochBoundaries(ctx, source, WithPollInterval(time.Millisecond), WithLastEpoch(6))
+	if err != nil {
+		t.Fatalf("WatchEpochBoundaries error = %v", err)
+	}
+	want := []testEpochBoundary{{7, 0, 0, 0, true}, {8, 0, 0, 0, true}, {9, 8000, 800, 80, false}, {10, 9000, 900, 90, false}}
+	if got := nextEpochBoundaries(t, events, 4); !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %+v, want %+v", got, want)
+	}
+	assertNoEpochBoundary(t, events)
+}
+
+func TestWatchEpochBoundaries_Errors(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The StakeHistory sysvar is missing.
+	source := NewMemoryAccountSource(10)
+	events, err := WatchEpochBoundaries(ctx, source, WithPollInterval(time.Millisecond), WithLastEpoch(9))
+	if err != nil {
+		t.Fatalf("WatchEpochBoundaries error = %v", err)
+	}
+	select {
+	case event := <-events:
+		if !errors.Is(event.Err, ErrAccountNotFound) {
+			t.Errorf("event error = %v, want %v", event.Err, ErrAccountNotFound)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for an event")
+	}
+
+	// The polling recovers.
+	source.SetAccount(StakeHistoryAccountAddress, newTestStakeHistoryEntries(0, 9))
+	for {
+		select {
+		case event := <-events:
+			if event.Err != nil {
+				continue
+			}
+			if event.Epoch != 10 {
+				t.Fatalf("event epoch = %d, want %d", event.Epoch, 10)
+			}
+			return
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for an event")
+		}
+	}
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/skport/solana-rpc-client-extensions-go/client"
+	"github.com/skport/solana-rpc-client-extensions-go/types"
+
+	"golang.org/x/xerrors"
+)
+
+const namespace = "solana"
+
+var (
+	stakeLabels = []string{"stake_account"}
+
+	epochDesc = prometheus.NewDesc(namespace+"_epoch", "Current epoch.", nil, nil)
+
+	clusterEffectiveDesc    = prometheus.NewDesc(namespace+"_cluster_stake_effective_lamports", "Effective stake of the cluster in the previous epoch, from the StakeHistory sysvar.", nil, nil)
+	clusterActivatingDesc   = prometheus.NewDesc(namespace+"_cluster_stake_activating_lamports", "Activating stake of the cluster in the previous epoch, from the StakeHistory sysvar.", nil, nil)
+	clusterDeactivatingDesc = prometheus.NewDesc(namespace+"_cluster_stake_deactivating_lamports", "Deactivating stake of the cluster in the previous epoch, from the StakeHistory sysvar.", nil, nil)
+
+	stakeActiveDesc       = prometheus.NewDesc(namespace+"_stake_active_lamports", "Active stake of the stake account.", stakeLabels, nil)
+	stakeInactiveDesc     = prometheus.NewDesc(namespace+"_stake_inactive_lamports", "Lamports of the stake account which are not active, excluding the rent-exempt reserve.", stakeLabels, nil)
+	stakeActivatingDesc   = prometheus.NewDesc(namespace+"_stake_activating_lamports", "Stake of the stake account in warmup.", stakeLabels, nil)
+	stakeDeactivatingDesc = prometheus.NewDesc(namespace+"_stake_deactivating_lamports", "Stake of the stake account in cooldown.", stakeLabels, nil)
+	stakeStateDesc        = prometheus.NewDesc(namespace+"_stake_state", "State of the stake account, 1 for the current state.", []string{"stake_account", "state"}, nil)
+	stakeEpochsDesc       = prometheus.NewDesc(namespace+"_stake_epochs_to_full_activation", "Predicted number of epochs until the stake account is fully active, +Inf beyond the prediction horizon, absent unless it's activating.", stakeLabels, nil)
+	stakeDelegationDesc   = prometheus.NewDesc(namespace+"_stake_delegation_info", "Vote account the stake account is delegated to.", []string{"stake_account", "vote_account"}, nil)
+	stakeErrorDesc        = prometheus.NewDesc(namespace+"_stake_error", "1 if the activation of the stake account can't be calculated, e.g. the account was closed.", stakeLabels, nil)
+
+	voteActivatedStakeDesc = prometheus.NewDesc(namespace+"_vote_account_activated_stake_lamports", "Stake activated for the vote account in the current epoch.", []string{"vote_account"}, nil)
+	voteDelinquentDesc     = prometheus.NewDesc(namespace+"_vote_account_delinquent", "1 if the vote account is delinquent, absent if it's unknown to the cluster.", []string{"vote_account"}, nil)
+
+	lastRefreshDesc   = prometheus.NewDesc(namespace+"_stake_exporter_last_refresh_timestamp_seconds", "Time of the last successful refresh.", nil, nil)
+	refreshErrorsDesc = prometheus.NewDesc(namespace+"_stake_exporter_refresh_errors_total", "Number of failed refreshes.", nil, nil)
+
+	stakeStates = []string{"inactive", "activating", "active", "deactivating"}
+)
+
+// exporter refreshes the metrics periodically, and serves the last refreshed metrics on scrapes,
+// so that scrapes neither hit the RPC endpoint nor see a partially refreshed state.
+type exporter struct {
+	source        client.SlotAccountSource
+	voteSource    client.VoteAccountsSource
+	stakeAccounts []string
+	voteAccounts  []string
+	// horizon is the number of future epochs predicted for the epochs to full activation.
+	horizon uint64
+
+	mu            sync.Mutex
+	metrics       []prometheus.Metric
+	lastRefresh   time.Time
+	refreshErrors uint64
+}
+
+func (e *exporter) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{
+		epochDesc,
+		clusterEffectiveDesc, clusterActivatingDesc, clusterDeactivatingDesc,
+		stakeActiveDesc, stakeInactiveDesc, stakeActivatingDesc, stakeDeactivatingDesc,
+		stakeStateDesc, stakeEpochsDesc, stakeDelegationDesc, stakeErrorDesc,
+		voteActivatedStakeDesc, voteDelinquentDesc,
+		lastRefreshDesc, refreshErrorsDesc,
+	} {
+		ch <- desc
+	}
+}
+
+func (e *exporter) Collect(ch chan<- prometheus.Metric) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, m := range e.metrics {
+		ch <- m
+	}
+	if !e.lastRefresh.IsZero() {
+		ch <- prometheus.MustNewConstMetric(lastRefreshDesc, prometheus.GaugeValue, float64(e.lastRefresh.UnixNano())/1e9)
+	}
+	ch <- prometheus.MustNewConstMetric(refreshErrorsDesc, prometheus.CounterValue, float64(e.refreshErrors))
+}
+
+// run refreshes the metrics every interval until ctx is canceled.
+func (e *exporter) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := e.refresh(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("refresh error: %v", err)
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// refresh replaces the metrics, or keeps the previous ones if the cluster can't be read.
+func (e *exporter) refresh(ctx context.Context) error {
+	metrics, err := e.collect(ctx)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err != nil {
+		e.refreshErrors++
+		return err
+	}
+	e.metrics = metrics
+	e.lastRefresh = time.Now()
+	return nil
+}
+
+func (e *exporter) collect(ctx context.Context) ([]prometheus.Metric, error) {
+	var metrics []prometheus.Metric
+	// Each snapshot reads the sysvars and its stake accounts at one slot. The epoch and the cluster stake are those of the first one.
+	for start := 0; start == 0 || start < len(e.stakeAccounts); start += client.MaxSnapshotStakeAccounts {
+		end := start + client.MaxSnapshotStakeAccounts
+		if end > len(e.stakeAccounts) {
+			end = len(e.stakeAccounts)
+		}
+		addresses := e.stakeAccounts[start:end]
+		snapshot, err := client.GetStakeActivationSnapshot(ctx, e.source, addresses)
+		if err != nil {
+			return nil, xerrors.Errorf("wrap: %w", err)
+		}
+		if start == 0 {
+			metrics = append(metrics, prometheus.MustNewConstMetric(epochDesc, prometheus.GaugeValue, float64(snapshot.Epoch)))
+			metrics = append(metrics, clusterMetrics(snapshot.StakeHistory, snapshot.Epoch)...)
+		}
+
+		for i, address := range addresses {
+			var stakeMetrics []prometheus.Metric
+			err := snapshot.Errors[i]
+			if err == nil {
+				stakeMetrics, err = e.stakeMetrics(address, snapshot.StakeAccounts[i], snapshot.Epoch, snapshot.StakeHistory)
+			}
+			var accountErr *client.AccountError
+			if errors.As(err, &accountErr) {
+				// A closed or broken account doesn't hide the others.
+				log.Printf("stake account %s: %v", address, err)
+				stakeMetrics = []prometheus.Metric{prometheus.MustNewConstMetric(stakeErrorDesc, prometheus.GaugeValue, 1, address)}
+			} else if err != nil {
+				return nil, xerrors.Errorf("wrap: %w", err)
+			}
+			metrics = append(metrics, stakeMetrics...)
+		}
+	}
+
+	if len(e.voteAccounts) > 0 && e.voteSource != nil {
+		voteMetrics, err := e.voteMetrics(ctx)
+		if err != nil {
+			return nil, xerrors.Errorf("wrap: %w", err)
+		}
+		metrics = append(metrics, voteMetrics...)
+	}
+	return metrics, nil
+}
+
+func clusterMetrics(stakeHistoryAccount *types.StakeHistoryAccount, epoch uint64) []prometheus.Metric {
+	for _, entry := range stakeHistoryAccount.Data.Parsed.Info {
+		if uint64(entry.Epoch)+1 == epoch {
+			return []prometheus.Metric{
+				prometheus.MustNewConstMetric(clusterEffectiveDesc, prometheus.GaugeValue, float64(entry.StakeHistory.Effective)),
+				prometheus.MustNewConstMetric(clusterActivatingDesc, prometheus.GaugeValue, float64(entry.StakeHistory.Activating)),
+				prometheus.MustNewConstMetric(clusterDeactivatingDesc, prometheus.GaugeValue, float64(entry.StakeHistory.Deactivating)),
+			}
+		}
+	}
+	return nil
+}
+
+// stakeMetrics returns the metrics of the stake account at address.
+func (e *exporter) stakeMetrics(address string, stakeAccount *types.StakeAccount, epoch uint64, stakeHistoryAccount *types.StakeHistoryAccount) ([]prometheus.Metric, error) {
+	status, err := client.GetStakeActivationStatus(address, epoch, stakeAccount, stakeHistoryAccount, client.WithCurrentEpoch())
+	if err != nil {
+		return nil, err
+	}
+	inactive, err := status.InactiveStake(stakeAccount)
+	if err != nil {
+		return nil, &client.AccountError{Address: address, Epoch: epoch, Err: err}
+	}
+	state := status.State()
+
+	metrics := []prometheus.Metric{
+		prometheus.MustNewConstMetric(stakeActiveDesc, prometheus.GaugeValue, float64(status.Effective), address),
+		prometheus.MustNewConstMetric(stakeInactiveDesc, prometheus.GaugeValue, float64(inactive), address),
+		prometheus.MustNewConstMetric(stakeActivatingDesc, prometheus.GaugeValue, float64(status.Activating), address),
+		prometheus.MustNewConstMetric(stakeDeactivatingDesc, prometheus.GaugeValue, float64(status.Deactivating), address),
+		prometheus.MustNewConstMetric(stakeErrorDesc, prometheus.GaugeValue, 0, address),
+	}
+	if state == "activating" {
+		epochs, err := e.epochsToFullActivation(address, epoch, stakeAccount, stakeHistoryAccount)
+		if err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, prometheus.MustNewConstMetric(stakeEpochsDesc, prometheus.GaugeValue, epochs, address))
+	}
+	for _, s := range stakeStates {
+		value := 0.0
+		if s == state {
+			value = 1
+		}
+		metrics = append(metrics, prometheus.MustNewConstMetric(stakeStateDesc, prometheus.GaugeValue, value, address, s))
+	}
+	if stake, err := stakeAccount.GetInfoStake(); err == nil && stake != nil {
+		metrics = append(metrics, prometheus.MustNewConstMetric(stakeDelegationDesc, prometheus.GaugeValue, 1, address, stake.Delegation.Voter))
+	}
+	return metrics, nil
+}
+
+// epochsToFullActivation predicts the warmup of activating stake with the cluster staying as in the newest StakeHistory entry.
+func (e *exporter) epochsToFullActivation(address string, epoch uint64, stakeAccount *types.StakeAccount, stakeHistoryAccount *types.StakeHistoryAccount) (float64, error) {
+	res, err := client.SimulateStakeTransaction(&client.SimulateStakeTransactionRequest{
+		Epoch:        epoch,
+		Accounts:     map[string]*types.StakeAccount{address: stakeAccount},
+		StakeHistory: stakeHistoryAccount,
+		FutureEpochs: e.horizon,
+	}, nil)
+	if err != nil {
+		return 0, xerrors.Errorf("wrap: %w", err)
+	}
+	for i, activation := range res.Activations[address] {
+		if activation.State != "activating" {
+			return float64(i), nil
+		}
+	}
+	return math.Inf(1), nil
+}
+
+func (e *exporter) voteMetrics(ctx context.Context) ([]prometheus.Metric, error) {
+	voteAccounts, err := e.voteSource.GetVoteAccounts(ctx)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to get vote accounts: %w", err)
+	}
+
+	delinquent := map[string]bool{}
+	activatedStake := map[string]uint64{}
+	for _, account := range voteAccounts.Current {
+		activatedStake[account.VotePubkey] = account.ActivatedStake
+	}
+	// The SDK misspells Delinquent.
+	for _, account := range voteAccounts.Deliquent {
+		activatedStake[account.VotePubkey] = account.ActivatedStake
+		delinquent[account.VotePubkey] = true
+	}
+
+	var metrics []prometheus.Metric
+	for _, address := range e.voteAccounts {
+		stake, ok := activatedStake[address]
+		if !ok {
+			continue
+		}
+		delinquentValue := 0.0
+		if delinquent[address] {
+			delinquentValue = 1
+		}
+		metrics = append(metrics,
+			prometheus.MustNewConstMetric(voteActivatedStakeDesc, prometheus.GaugeValue, float64(stake), address),
+			prometheus.MustNewConstMetric(voteDelinquentDesc, prometheus.GaugeValue, delinquentValue, address),
+		)
+	}
+	return metrics, nil
+}
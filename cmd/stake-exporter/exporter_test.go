@@ -0,0 +1,265 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	sdkRpc "github.com/blocto/solana-go-sdk/rpc"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/skport/solana-rpc-client-extensions-go/client"
+	"github.com/skport/solana-rpc-client-extensions-go/types"
+)
+
+const (
+	testActivatingAddress   = "4vJ9JU1bJJE96FWSJKvHsmmFADCg4gpZQff4P3bkLKi"
+	testDeactivatingAddress = "8qbHbw2BbbTHBW1sbeqakYXVKRQM8Ne7pLK7m6CVfeR"
+	testClosedAddress       = "CktRuQ2mttgRGkXJtyksdKHjUdc2C4TgDzyB98oEzy8"
+	testVoteAddress         = "GgBaCs3NCBuZN12kCJgAW63ydqohFkHEdfdEXBPzLHq"
+	testDelinquentAddress   = "LbUiWL3xVV8hTFYBVdbTNrpDo41NKS6o3LHHuDzjfcY"
+)
+
+func newTestStakeAccount(stake uint64, activationEpoch uint64, deactivationEpoch uint64) *types.StakeAccount {
+	a := &types.StakeAccount{
+		Lamports: stake + 2282880,
+		Owner:    client.StakeProgramAddress,
+	}
+	a.Data.Program = "stake"
+	a.Data.Space = 200
+	a.Data.Parsed.Type = "delegated"
+	a.Data.Parsed.Info.Meta.RentExemptReserve = strconv.FormatUint(2282880, 10)
+
+	s := &types.StakeAccountInfoStake{}
+	s.Delegation.ActivationEpoch = activationEpoch
+	s.Delegation.DeactivationEpoch = deactivationEpoch
+	s.Delegation.Stake = stake
+	s.Delegation.Voter = testVoteAddress
+	a.Data.Parsed.Info.Stake = s
+	return a
+}
+
+func newTestStakeHistory(epoch uint64) *types.StakeHistoryAccount {
+	h := &types.StakeHistoryAccount{Owner: client.SysvarProgramAddress}
+	h.Data.Program = "sysvar"
+	h.Data.Parsed.Type = "stakeHistory"
+	entry := types.StakeHistoryAccountInfo{Epoch: int(epoch) - 1}
+	entry.StakeHistory.Effective = 1000000000000000
+	entry.StakeHistory.Activating = 20000000000
+	entry.StakeHistory.Deactivating = 30000000000
+	h.Data.Parsed.Info = []types.StakeHistoryAccountInfo{entry}
+	return h
+}
+
+func newTestEpochSchedule() *types.EpochScheduleAccount {
+	a := &types.EpochScheduleAccount{Owner: client.SysvarProgramAddress}
+	a.Data.Program = "sysvar"
+	a.Data.Parsed.Type = "epochSchedule"
+	a.Data.Parsed.Info = types.EpochSchedule{LeaderScheduleSlotOffset: 432000, SlotsPerEpoch: 432000}
+	return a
+}
+
+// failingSource serves the accounts at slot, fails while err is set, and counts the requests for accounts.
+type failingSource struct {
+	*client.MemoryAccountSource
+	slot uint64
+	err  error
+
+	requests int
+}
+
+func (s *failingSource) GetAccountWithSlot(ctx context.Context, address string, minContextSlot uint64) (json.RawMessage, uint64, error) {
+	accounts, slot, err := s.GetMultipleAccountsWithSlot(ctx, []string{address}, minContextSlot)
+	if err != nil {
+		return nil, 0, err
+	}
+	return accounts[0], slot, nil
+}
+
+func (s *failingSource) GetMultipleAccountsWithSlot(ctx context.Context, addresses []string, minContextSlot uint64) ([]json.RawMessage, uint64, error) {
+	s.requests++
+	if s.err != nil {
+		return nil, 0, s.err
+	}
+	accounts, err := s.MemoryAccountSource.GetMultipleAccounts(ctx, addresses)
+	return accounts, s.slot, err
+}
+
+func (s *failingSource) GetEpochWithSlot(ctx context.Context, minContextSlot uint64) (uint64, uint64, error) {
+	s.requests++
+	if s.err != nil {
+		return 0, 0, s.err
+	}
+	epoch, err := s.MemoryAccountSource.GetEpoch(ctx)
+	return epoch, s.slot, err
+}
+
+func TestExporter(t *testing.T) {
+	ctx := context.Background()
+
+	memory := client.NewMemoryAccountSource(10)
+	memory.SetAccount(client.EpochScheduleAccountAddress, newTestEpochSchedule())
+	memory.SetAccount(client.StakeHistoryAccountAddress, newTestStakeHistory(10))
+	memory.SetAccount(testActivatingAddress, newTestStakeAccount(20000000000, 10, types.MaxEpoch))
+	memory.SetAccount(testDeactivatingAddress, newTestStakeAccount(30000000000, 0, 10))
+	memory.SetVoteAccounts(sdkRpc.GetVoteAccounts{
+		Current:   sdkRpc.VoteAccounts{{VotePubkey: testVoteAddress, ActivatedStake: 50000000000}},
+		Deliquent: sdkRpc.VoteAccounts{{VotePubkey: testDelinquentAddress, ActivatedStake: 70000000000}},
+	})
+	// The first slot of epoch 10.
+	source := &failingSource{MemoryAccountSource: memory, slot: 4320000}
+
+	e := &exporter{
+		source:        source,
+		voteSource:    memory,
+		stakeAccounts: []string{testActivatingAddress, testDeactivatingAddress, testClosedAddress},
+		voteAccounts:  []string{testVoteAddress, testDelinquentAddress},
+		horizon:       4,
+	}
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(e)
+	if err := e.refresh(ctx); err != nil {
+		t.Fatalf("refresh error = %v", err)
+	}
+
+	want := `
+# HELP solana_cluster_stake_effective_lamports Effective stake of the cluster in the previous epoch, from the StakeHistory sysvar.
+# TYPE solana_cluster_stake_effective_lamports gauge
+solana_cluster_stake_effective_lamports 1e+15
+# HELP solana_epoch Current epoch.
+# TYPE solana_epoch gauge
+solana_epoch 10
+# HELP solana_stake_activating_lamports Stake of the stake account in warmup.
+# TYPE solana_stake_activating_lamports gauge
+solana_stake_activating_lamports{stake_account="4vJ9JU1bJJE96FWSJKvHsmmFADCg4gpZQff4P3bkLKi"} 2e+10
+solana_stake_activating_lamports{stake_account="8qbHbw2BbbTHBW1sbeqakYXVKRQM8Ne7pLK7m6CVfeR"} 0
+# HELP solana_stake_active_lamports Active stake of the stake account.
+# TYPE solana_stake_active_lamports gauge
+solana_stake_active_lamports{stake_account="4vJ9JU1bJJE96FWSJKvHsmmFADCg4gpZQff4P3bkLKi"} 0
+solana_stake_active_lamports{stake_account="8qbHbw2BbbTHBW1sbeqakYXVKRQM8Ne7pLK7m6CVfeR"} 3e+10
+# HELP solana_stake_deactivating_lamports Stake of the stake account in cooldown.
+# TYPE solana_stake_deactivating_lamports gauge
+solana_stake_deactivating_lamports{stake_account="4vJ9JU1bJJE96FWSJKvHsmmFADCg4gpZQff4P3bkLKi"} 0
+solana_stake_deactivating_lamports{stake_account="8qbHbw2BbbTHBW1sbeqakYXVKRQM8Ne7pLK7m6CVfeR"} 3e+10
+# HELP solana_stake_delegation_info Vote account the stake account is delegated to.
+# TYPE solana_stake_delegation_info gauge
+solana_stake_delegation_info{stake_account="4vJ9JU1bJJE96FWSJKvHsmmFADCg4gpZQff4P3bkLKi",vote_account="GgBaCs3NCBuZN12kCJgAW63ydqohFkHEdfdEXBPzLHq"} 1
+solana_stake_delegation_info{stake_account="8qbHbw2BbbTHBW1sbeqakYXVKRQM8Ne7pLK7m6CVfeR",vote_account="GgBaCs3NCBuZN12kCJgAW63ydqohFkHEdfdEXBPzLHq"} 1
+# HELP solana_stake_epochs_to_full_activation Predicted number of epochs until the stake account is fully active, +Inf beyond the prediction horizon, absent unless it's activating.
+# TYPE solana_stake_epochs_to_full_activation gauge
+solana_stake_epochs_to_full_activation{stake_account="4vJ9JU1bJJE96FWSJKvHsmmFADCg4gpZQff4P3bkLKi"} 1
+# HELP solana_stake_error 1 if the activation of the stake account can't be calculated, e.g. the account was closed.
+# TYPE solana_stake_error gauge
+solana_stake_error{stake_account="4vJ9JU1bJJE96FWSJKvHsmmFADCg4gpZQff4P3bkLKi"} 0
+solana_stake_error{stake_account="8qbHbw2BbbTHBW1sbeqakYXVKRQM8Ne7pLK7m6CVfeR"} 0
+solana_stake_error{stake_account="CktRuQ2mttgRGkXJtyksdKHjUdc2C4TgDzyB98oEzy8"} 1
+# HELP solana_stake_state State of the stake account, 1 for the current state.
+# TYPE solana_stake_state gauge
+solana_stake_state{stake_account="4vJ9JU1bJJE96FWSJKvHsmmFADCg4gpZQff4P3bkLKi",state="activating"} 1
+solana_stake_state{stake_account="4vJ9JU1bJJE96FWSJKvHsmmFADCg4gpZQff4P3bkLKi",state="active"} 0
+solana_stake_state{stake_account="4vJ9JU1bJJE96FWSJKvHsmmFADCg4gpZQff4P3bkLKi",state="deactivating"} 0
+solana_stake_state{stake_account="4vJ9JU1bJJE96FWSJKvHsmmFADCg4gpZQff4P3bkLKi",state="inactive"} 0
+solana_stake_state{stake_account="8qbHbw2BbbTHBW1sbeqakYXVKRQM8Ne7pLK7m6CVfeR",state="activating"} 0
+solana_stake_state{stake_account="8qbHbw2BbbTHBW1sbeqakYXVKRQM8Ne7pLK7m6CVfeR",state="active"} 0
+solana_stake_state{stake_account="8qbHbw2BbbTHBW1sbeqakYXVKRQM8Ne7pLK7m6CVfeR",state="deactivating"} 1
+solana_stake_state{stake_account="8qbHbw2BbbTHBW1sbeqakYXVKRQM8Ne7pLK7m6CVfeR",state="inactive"} 0
+# HELP solana_vote_account_activated_stake_lamports Stake activated for the vote account in the current epoch.
+# TYPE solana_vote_account_activated_stake_lamports gauge
+solana_vote_account_activated_stake_lamports{vote_account="GgBaCs3NCBuZN12kCJgAW63ydqohFkHEdfdEXBPzLHq"} 5e+10
+solana_vote_account_activated_stake_lamports{vote_account="LbUiWL3xVV8hTFYBVdbTNrpDo41NKS6o3LHHuDzjfcY"} 7e+10
+# HELP solana_vote_account_delinquent 1 if the vote account is delinquent, absent if it's unknown to the cluster.
+# TYPE solana_vote_account_delinquent gauge
+solana_vote_account_delinquent{vote_account="GgBaCs3NCBuZN12kCJgAW63ydqohFkHEdfdEXBPzLHq"} 0
+solana_vote_account_delinquent{vote_account="LbUiWL3xVV8hTFYBVdbTNrpDo41NKS6o3LHHuDzjfcY"} 1
+`
+	names := []string{
+		"solana_cluster_stake_effective_lamports",
+		"solana_epoch",
+		"solana_stake_activating_lamports",
+		"solana_stake_active_lamports",
+		"solana_stake_deactivating_lamports",
+		"solana_stake_delegation_info",
+		"solana_stake_epochs_to_full_activation",
+		"solana_stake_error",
+		"solana_stake_state",
+		"solana_vote_account_activated_stake_lamports",
+		"solana_vote_account_delinquent",
+	}
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(want), names...); err != nil {
+		t.Fatal(err)
+	}
+	// The sysvars and the stake accounts in one request, so that they are from the same slot.
+	if source.requests != 1 {
+		t.Errorf("requests = %d, want 1", source.requests)
+	}
+
+	// A failed refresh keeps serving the previous metrics.
+	source.err = errors.New("rpc error")
+	if err := e.refresh(ctx); err == nil {
+		t.Fatal("refresh succeeded")
+	}
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(want), names...); err != nil {
+		t.Fatal(err)
+	}
+	wantErrors := `
+# HELP solana_stake_exporter_refresh_errors_total Number of failed refreshes.
+# TYPE solana_stake_exporter_refresh_errors_total counter
+solana_stake_exporter_refresh_errors_total 1
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(wantErrors), "solana_stake_exporter_refresh_errors_total"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExporter_Snapshots(t *testing.T) {
+	ctx := context.Background()
+
+	memory := client.NewMemoryAccountSource(10)
+	memory.SetAccount(client.EpochScheduleAccountAddress, newTestEpochSchedule())
+	memory.SetAccount(client.StakeHistoryAccountAddress, newTestStakeHistory(10))
+	memory.SetAccount(testActivatingAddress, newTestStakeAccount(20000000000, 10, types.MaxEpoch))
+
+	tests := []struct {
+		name          string
+		stakeAccounts int
+		wantRequests  int
+	}{
+		{name: "no stake accounts", stakeAccounts: 0, wantRequests: 1},
+		{name: "one request", stakeAccounts: client.MaxSnapshotStakeAccounts, wantRequests: 1},
+		{name: "two requests", stakeAccounts: client.MaxSnapshotStakeAccounts + 1, wantRequests: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := &failingSource{MemoryAccountSource: memory, slot: 4320000}
+			stakeAccounts := make([]string, tt.stakeAccounts)
+			for i := range stakeAccounts {
+				stakeAccounts[i] = testActivatingAddress
+			}
+			e := &exporter{source: source, stakeAccounts: stakeAccounts, horizon: 4}
+			metrics, err := e.collect(ctx)
+			if err != nil {
+				t.Fatalf("collect error = %v", err)
+			}
+			if source.requests != tt.wantRequests {
+				t.Errorf("requests = %d, want %d", source.requests, tt.wantRequests)
+			}
+			// The epoch, the 3 cluster stake metrics, and the 11 metrics of each activating stake account.
+			if len(metrics) != 4+tt.stakeAccounts*11 {
+				t.Errorf("metrics = %d, want %d", len(metrics), 4+tt.stakeAccounts*11)
+			}
+		})
+	}
+}
+
+func TestSplitAddresses(t *testing.T) {
+	got := splitAddresses(" a, ,b,")
+	if len(got) != 2 || got[0] != "a" || got[1] != "b" {
+		t.Errorf("splitAddresses = %q", got)
+	}
+	if got := splitAddresses(""); len(got) != 0 {
+		t.Errorf("splitAddresses(\"\") = %q", got)
+	}
+}
@@ -0,0 +1,31 @@
+module github.com/skport/solana-rpc-client-extensions-go/cmd/stake-exporter
+
+go 1.19
+
+require (
+	github.com/blocto/solana-go-sdk v1.30.0
+	github.com/prometheus/client_golang v1.14.0
+	github.com/skport/solana-rpc-client-extensions-go v0.0.0-00010101000000-000000000000
+	golang.org/x/xerrors v0.0.0-20240903120638-7835f813f4da
+)
+
+require (
+	filippo.io/edwards25519 v1.0.0-rc.1 // indirect
+	github.com/beorn7/perks v1.0.1 // indirect
+	github.com/cespare/xxhash/v2 v2.1.2 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/golang/protobuf v1.5.2 // indirect
+	github.com/gorilla/websocket v1.5.3 // indirect
+	github.com/matttproud/golang_protobuf_extensions v1.0.1 // indirect
+	github.com/mr-tron/base58 v1.2.0 // indirect
+	github.com/prometheus/client_model v0.3.0 // indirect
+	github.com/prometheus/common v0.37.0 // indirect
+	github.com/prometheus/procfs v0.8.0 // indirect
+	golang.org/x/sync v0.10.0 // indirect
+	golang.org/x/sys v0.0.0-20220520151302-bc2c85ada10a // indirect
+	golang.org/x/time v0.8.0 // indirect
+	google.golang.org/protobuf v1.28.1 // indirect
+)
+
+// The exporter is built against this checkout of the main module.
+replace github.com/skport/solana-rpc-client-extensions-go => ../..
@@ -0,0 +1,80 @@
+// Command stake-exporter exposes the activation of stake accounts, the stake of vote accounts and the cluster stake history as Prometheus metrics.
+//
+//	stake-exporter -rpc https://api.mainnet-beta.solana.com,<fallback> -stake <address>,<address> -vote <address>
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	sdkRpc "github.com/blocto/solana-go-sdk/rpc"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/skport/solana-rpc-client-extensions-go/client"
+)
+
+func main() {
+	var (
+		endpoints     = flag.String("rpc", sdkRpc.MainnetRPCEndpoint, "comma-separated JSON-RPC endpoints in order of preference")
+		rateLimit     = flag.Float64("rate-limit", 0, "maximum requests per second to each endpoint, or 0 for no limit")
+		stakeAccounts = flag.String("stake", "", "comma-separated stake account addresses")
+		voteAccounts  = flag.String("vote", "", "comma-separated vote account addresses")
+		listen        = flag.String("listen", ":9184", "address to serve /metrics on")
+		interval      = flag.Duration("interval", time.Minute, "refresh interval")
+		horizon       = flag.Uint64("horizon", 32, "number of epochs predicted for the epochs to full activation")
+	)
+	flag.Parse()
+
+	var failoverOpts []client.FailoverOption
+	if *rateLimit > 0 {
+		failoverOpts = append(failoverOpts, client.WithRateLimit(*rateLimit, 1))
+	}
+	source := client.NewFailoverAccountSource(splitAddresses(*endpoints), failoverOpts...)
+	e := &exporter{
+		// The sysvars are fetched with the stake accounts on every refresh, so that they are from the same slot.
+		source:        source,
+		voteSource:    source,
+		stakeAccounts: splitAddresses(*stakeAccounts),
+		voteAccounts:  splitAddresses(*voteAccounts),
+		horizon:       *horizon,
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(e)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go e.run(ctx, *interval)
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	server := &http.Server{
+		Addr:              *listen,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+	log.Printf("serving metrics on %s", *listen)
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Fatalf("ListenAndServe error: %v", err)
+	}
+}
+
+func splitAddresses(s string) []string {
+	var addresses []string
+	for _, address := range strings.Split(s, ",") {
+		if address = strings.TrimSpace(address); address != "" {
+			addresses = append(addresses, address)
+		}
+	}
+	return addresses
+}
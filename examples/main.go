@@ -15,53 +15,24 @@ const (
 )
 
 func main() {
-	rpc := sdkRpc.NewRpcClient(sdkRpc.DevnetRPCEndpoint)
 	ctx := context.Background()
 
-	// GetEpochInfo
-	epochInfo, err := rpc.GetEpochInfo(ctx)
-	if err != nil {
-		log.Panicf("GetEpochInfo error: %v", err)
-	}
+	// Requests are retried on 429s and transient errors, and fail over to the next endpoint while one is unhealthy.
+	// Any client.SlotAccountSource can be used here.
+	source := client.NewFailoverAccountSource([]string{sdkRpc.DevnetRPCEndpoint}, client.WithRateLimit(4, 1))
 
-	// GetAccountInfo for stakeHistoryAccount
-	stakeHistoryAccountInfo, err := rpc.GetAccountInfoWithConfig(ctx, client.StakeHistoryAccountAddress,
-		sdkRpc.GetAccountInfoConfig{
-			Commitment: sdkRpc.CommitmentFinalized,
-			Encoding:   sdkRpc.AccountEncodingJsonParsed,
-		},
-	)
-	if err != nil {
-		log.Panicf("GetAccountInfo error for stakeHistoryAccount: %v", err)
-	}
-	stakeHistoryAccount, err := client.ConvertStakeHistoryAccountInfo(stakeHistoryAccountInfo)
+	// GetMultipleAccounts for the StakeHistory and EpochSchedule sysvars and the stake account,
+	// so that the epoch and the accounts are from the same slot.
+	snapshot, err := client.GetStakeActivationSnapshot(ctx, source, []string{stakeAccountAddress})
 	if err != nil {
-		log.Panicf("ConvertStakeHistoryAccountInfo error: %v", err)
+		log.Fatalf("GetStakeActivationSnapshot error: %v", err)
 	}
-
-	// GetAccountInfo for stakeAccount
-	stakeAccountInfo, err := rpc.GetAccountInfoWithConfig(ctx, stakeAccountAddress,
-		sdkRpc.GetAccountInfoConfig{
-			Commitment: sdkRpc.CommitmentFinalized,
-			Encoding:   sdkRpc.AccountEncodingJsonParsed,
-		},
-	)
-	if err != nil {
-		log.Panicf("GetAccountInfo error for stakeAccount: %v", err)
-	}
-	stakeAccount, err := client.ConvertStakeAccountInfo(stakeAccountInfo)
-	if err != nil {
-		log.Panicf("ConvertStakeAccountInfo error: %v", err)
-	}
-
-	// GetStakeActivation
-	r, err := client.GetStakeActivation(stakeAccountAddress, epochInfo.Result.Epoch, stakeAccount, stakeHistoryAccount)
-	if err != nil {
-		log.Panicf("GetStakeActivation error: %v", err)
+	if err := snapshot.Errors[0]; err != nil {
+		log.Fatalf("GetStakeActivation error: %v", err)
 	}
-	b, err := json.MarshalIndent(r, "", "  ")
+	b, err := json.MarshalIndent(snapshot.Activations[0], "", "  ")
 	if err != nil {
-		log.Panicf("failed to marshal JSON: %v", err)
+		log.Fatalf("failed to marshal JSON: %v", err)
 	}
-	fmt.Printf("GetStakeActivation: %s", string(b))
+	fmt.Printf("GetStakeActivation at slot %d, epoch %d: %s", snapshot.Slot, snapshot.Epoch, string(b))
 }
@@ -4,5 +4,11 @@ go 1.19
 
 require (
 	github.com/blocto/solana-go-sdk v1.30.0
+	github.com/gorilla/websocket v1.5.3
+	github.com/mr-tron/base58 v1.2.0
+	golang.org/x/sync v0.10.0
+	golang.org/x/time v0.8.0
 	golang.org/x/xerrors v0.0.0-20240903120638-7835f813f4da
 )
+
+require filippo.io/edwards25519 v1.0.0-rc.1 // indirect
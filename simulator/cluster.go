@@ -0,0 +1,458 @@
+package simulator
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/skport/solana-rpc-client-extensions-go/types"
+)
+
+const (
+	// Epoch value used by the runtime for "not set" (u64::MAX), e.g. the deactivation epoch of a stake that is not deactivating.
+	MaxEpoch = types.MaxEpoch
+
+	// https://github.com/anza-xyz/agave/blob/v2.0.0/sdk/program/src/stake/state.rs#L30
+	DefaultRentExemptReserve uint64 = 2282880
+
+	// https://github.com/anza-xyz/agave/blob/v2.0.0/sdk/program/src/stake/state.rs#L27
+	stakeWarmupCooldownRate = 0.09
+	// The StakeHistory sysvar keeps the last 512 epochs.
+	maxStakeHistoryEntries = 512
+
+	stakeProgramAddress = "Stake11111111111111111111111111111111111111"
+	sysvarOwnerAddress  = "Sysvar1111111111111111111111111111111111111"
+)
+
+// Cluster models the stake program and the StakeHistory sysvar of a cluster over epochs.
+// It is not safe for concurrent use.
+type Cluster struct {
+	epoch             uint64
+	rentExemptReserve uint64
+
+	accounts  map[string]*stakeAccount
+	bootstrap []*delegation
+
+	history map[uint64]types.StakeHistoryAccountInfo
+}
+
+type stakeAccount struct {
+	lamports          uint64
+	rentExemptReserve uint64
+	staker            string
+	withdrawer        string
+	delegation        *delegation
+}
+
+type delegation struct {
+	voter             string
+	stake             uint64
+	activationEpoch   uint64
+	deactivationEpoch uint64
+
+	// status is the status in the current epoch, which AdvanceEpoch moves forward.
+	status Status
+	// undeactivated is the status before a deactivation in the current epoch, which is restored when the deactivation is rescinded.
+	undeactivated Status
+}
+
+// Status is the ground truth of a delegation at an epoch.
+type Status struct {
+	Effective    uint64
+	Activating   uint64
+	Deactivating uint64
+}
+
+// NewCluster creates a cluster at epoch with bootstrapStake of genesis stake, which is effective from the beginning.
+// Without effective stake, no stake can ever warm up, so bootstrapStake should be much larger than the delegations.
+func NewCluster(epoch uint64, bootstrapStake uint64) *Cluster {
+	c := &Cluster{
+		epoch:             epoch,
+		rentExemptReserve: DefaultRentExemptReserve,
+		accounts:          map[string]*stakeAccount{},
+		history:           map[uint64]types.StakeHistoryAccountInfo{},
+	}
+	if bootstrapStake > 0 {
+		c.bootstrap = append(c.bootstrap, &delegation{
+			stake:             bootstrapStake,
+			activationEpoch:   MaxEpoch,
+			deactivationEpoch: MaxEpoch,
+			status:            Status{Effective: bootstrapStake},
+		})
+	}
+	return c
+}
+
+func (c *Cluster) Epoch() uint64 {
+	return c.epoch
+}
+
+// Addresses returns the addresses of all stake accounts in lexical order.
+func (c *Cluster) Addresses() []string {
+	addresses := make([]string, 0, len(c.accounts))
+	for address := range c.accounts {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+	return addresses
+}
+
+// CreateStakeAccount creates an initialized (not delegated) stake account.
+func (c *Cluster) CreateStakeAccount(address string, lamports uint64, authority string) error {
+	if _, ok := c.accounts[address]; ok {
+		return fmt.Errorf("account already exists, address: %s", address)
+	}
+	if lamports < c.rentExemptReserve {
+		return fmt.Errorf("insufficient funds for rent, address: %s, lamports: %d", address, lamports)
+	}
+
+	c.accounts[address] = &stakeAccount{
+		lamports:          lamports,
+		rentExemptReserve: c.rentExemptReserve,
+		staker:            authority,
+		withdrawer:        authority,
+	}
+	return nil
+}
+
+// CreateBootstrapStakeAccount creates a stake account delegated to voter in the genesis config.
+// Its stake is effective from the beginning, like the stake passed to NewCluster.
+func (c *Cluster) CreateBootstrapStakeAccount(address string, lamports uint64, authority string, voter string) error {
+	if err := c.CreateStakeAccount(address, lamports, authority); err != nil {
+		return err
+	}
+	stake := lamports - c.rentExemptReserve
+	c.accounts[address].delegation = &delegation{
+		voter:             voter,
+		stake:             stake,
+		activationEpoch:   MaxEpoch,
+		deactivationEpoch: MaxEpoch,
+		status:            Status{Effective: stake},
+	}
+	return nil
+}
+
+// Delegate delegates all lamports above the rent-exempt reserve to voter.
+// A fully deactivated stake is redelegated, and a stake deactivated in the current epoch is reactivated
+// when delegated to the same voter, like the stake program does.
+func (c *Cluster) Delegate(address string, voter string) error {
+	a, err := c.account(address)
+	if err != nil {
+		return err
+	}
+	stake := a.lamports - a.rentExemptReserve
+
+	if a.delegation == nil {
+		a.delegation = &delegation{
+			voter:             voter,
+			stake:             stake,
+			activationEpoch:   c.epoch,
+			deactivationEpoch: MaxEpoch,
+			status:            Status{Activating: stake},
+		}
+		return nil
+	}
+
+	// https://github.com/anza-xyz/agave/blob/v2.0.0/programs/stake/src/stake_state.rs#L70
+	if a.delegation.status.Effective != 0 {
+		if a.delegation.voter == voter && a.delegation.deactivationEpoch == c.epoch {
+			a.delegation.deactivationEpoch = MaxEpoch
+			a.delegation.status = a.delegation.undeactivated
+			return nil
+		}
+		return fmt.Errorf("too soon to redelegate, address: %s", address)
+	}
+	*a.delegation = delegation{
+		voter:             voter,
+		stake:             stake,
+		activationEpoch:   c.epoch,
+		deactivationEpoch: MaxEpoch,
+		status:            Status{Activating: stake},
+	}
+	return nil
+}
+
+// Deactivate starts the cooldown of the stake in the current epoch.
+func (c *Cluster) Deactivate(address string) error {
+	a, err := c.account(address)
+	if err != nil {
+		return err
+	}
+	if a.delegation == nil {
+		return fmt.Errorf("stake account is not delegated, address: %s", address)
+	}
+	if a.delegation.deactivationEpoch != MaxEpoch {
+		return fmt.Errorf("stake already deactivated, address: %s", address)
+	}
+
+	// Only the effective stake cools down, and the stake still activating is dropped.
+	// A stake deactivated in its activation epoch has no effective stake and is inactive at once.
+	d := a.delegation
+	d.deactivationEpoch = c.epoch
+	d.undeactivated = d.status
+	d.status = Status{Effective: d.status.Effective, Deactivating: d.status.Effective}
+	return nil
+}
+
+// Split moves lamports from a stake account to a new stake account.
+// The delegation of the source is copied to the destination and the delegated stake is moved along with the lamports.
+// Splitting a stake in a transient state is not modeled, because the runtime derives the status of each part by replaying
+// the StakeHistory with the split stake, and it returns an error.
+func (c *Cluster) Split(from string, to string, lamports uint64) error {
+	src, err := c.account(from)
+	if err != nil {
+		return err
+	}
+	if _, ok := c.accounts[to]; ok {
+		return fmt.Errorf("account already exists, address: %s", to)
+	}
+	if lamports < c.rentExemptReserve {
+		return fmt.Errorf("insufficient funds for rent, address: %s, lamports: %d", to, lamports)
+	}
+	if src.lamports < lamports || src.lamports-lamports < src.rentExemptReserve {
+		return fmt.Errorf("insufficient funds, address: %s, lamports: %d, split: %d", from, src.lamports, lamports)
+	}
+
+	dst := &stakeAccount{
+		lamports:          lamports,
+		rentExemptReserve: c.rentExemptReserve,
+		staker:            src.staker,
+		withdrawer:        src.withdrawer,
+	}
+	if src.delegation != nil {
+		splitStake := lamports - dst.rentExemptReserve
+		if src.delegation.stake < lamports {
+			return fmt.Errorf("insufficient stake, address: %s, stake: %d, split: %d", from, src.delegation.stake, lamports)
+		}
+		if _, err := c.mergeKind(src); err != nil {
+			return fmt.Errorf("address: %s, %v", from, err)
+		}
+
+		d := *src.delegation
+		d.setStake(splitStake)
+		dst.delegation = &d
+		src.delegation.setStake(src.delegation.stake - lamports)
+	}
+
+	src.lamports -= lamports
+	c.accounts[to] = dst
+	return nil
+}
+
+// Merge merges the source stake account into the destination and removes the source.
+// https://github.com/anza-xyz/agave/blob/v2.0.0/programs/stake/src/stake_state.rs#L1260
+func (c *Cluster) Merge(to string, from string) error {
+	dst, err := c.account(to)
+	if err != nil {
+		return err
+	}
+	src, err := c.account(from)
+	if err != nil {
+		return err
+	}
+	if to == from {
+		return fmt.Errorf("cannot merge a stake account into itself, address: %s", to)
+	}
+	if dst.staker != src.staker || dst.withdrawer != src.withdrawer {
+		return fmt.Errorf("merge mismatch: authorities differ, destination: %s, source: %s", to, from)
+	}
+
+	dstKind, err := c.mergeKind(dst)
+	if err != nil {
+		return fmt.Errorf("destination: %s, %v", to, err)
+	}
+	srcKind, err := c.mergeKind(src)
+	if err != nil {
+		return fmt.Errorf("source: %s, %v", from, err)
+	}
+
+	switch {
+	case dstKind == mergeKindInactive && srcKind == mergeKindInactive:
+	case dstKind == mergeKindInactive && srcKind == mergeKindActivationEpoch:
+	case dstKind == mergeKindActivationEpoch && srcKind == mergeKindInactive:
+		dst.delegation.setStake(dst.delegation.stake + src.lamports)
+	case dstKind == mergeKindActivationEpoch && srcKind == mergeKindActivationEpoch:
+		if dst.delegation.voter != src.delegation.voter {
+			return fmt.Errorf("merge mismatch: voters differ, destination: %s, source: %s", to, from)
+		}
+		dst.delegation.setStake(dst.delegation.stake + src.rentExemptReserve + src.delegation.stake)
+	case dstKind == mergeKindFullyActive && srcKind == mergeKindFullyActive:
+		if dst.delegation.voter != src.delegation.voter {
+			return fmt.Errorf("merge mismatch: voters differ, destination: %s, source: %s", to, from)
+		}
+		dst.delegation.setStake(dst.delegation.stake + src.delegation.stake)
+	default:
+		return fmt.Errorf("merge mismatch, destination: %s, source: %s", to, from)
+	}
+
+	dst.lamports += src.lamports
+	delete(c.accounts, from)
+	return nil
+}
+
+type mergeKind int
+
+const (
+	mergeKindInactive mergeKind = iota
+	mergeKindActivationEpoch
+	mergeKindFullyActive
+)
+
+func (c *Cluster) mergeKind(a *stakeAccount) (mergeKind, error) {
+	if a.delegation == nil {
+		return mergeKindInactive, nil
+	}
+
+	s := a.delegation.status
+	switch {
+	case s.Effective == 0 && s.Activating == 0 && s.Deactivating == 0:
+		return mergeKindInactive, nil
+	case s.Effective == 0:
+		return mergeKindActivationEpoch, nil
+	case s.Activating == 0 && s.Deactivating == 0:
+		return mergeKindFullyActive, nil
+	default:
+		return 0, fmt.Errorf("stake is in transient state")
+	}
+}
+
+// AdvanceEpoch ends the current epoch. The stake of the cluster in the ending epoch is recorded in the StakeHistory sysvar,
+// and each delegation warms up or cools down by its share of it.
+func (c *Cluster) AdvanceEpoch() {
+	delegations := c.delegations()
+	var total Status
+	for _, d := range delegations {
+		total.Effective += d.status.Effective
+		total.Activating += d.status.Activating
+		total.Deactivating += d.status.Deactivating
+	}
+
+	entry := types.StakeHistoryAccountInfo{Epoch: int(c.epoch)}
+	entry.StakeHistory.Effective = total.Effective
+	entry.StakeHistory.Activating = total.Activating
+	entry.StakeHistory.Deactivating = total.Deactivating
+	c.history[c.epoch] = entry
+
+	for _, d := range delegations {
+		d.status = d.status.next(total)
+	}
+	c.epoch++
+}
+
+func (c *Cluster) AdvanceEpochs(n int) {
+	for i := 0; i < n; i++ {
+		c.AdvanceEpoch()
+	}
+}
+
+// Status returns the ground truth of the stake account at the current epoch.
+func (c *Cluster) Status(address string) (Status, error) {
+	a, err := c.account(address)
+	if err != nil {
+		return Status{}, err
+	}
+	if a.delegation == nil {
+		return Status{}, nil
+	}
+	return a.delegation.status, nil
+}
+
+// Lamports returns the balance of the stake account.
+func (c *Cluster) Lamports(address string) (uint64, error) {
+	a, err := c.account(address)
+	if err != nil {
+		return 0, err
+	}
+	return a.lamports, nil
+}
+
+// StakeAccount returns the stake account as returned by getAccountInfo with jsonParsed encoding.
+func (c *Cluster) StakeAccount(address string) (*types.StakeAccount, error) {
+	a, err := c.account(address)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &types.StakeAccount{
+		Lamports:  a.lamports,
+		Owner:     stakeProgramAddress,
+		RentEpoch: math.MaxUint64,
+	}
+	r.Data.Program = "stake"
+	r.Data.Space = 200
+	r.Data.Parsed.Type = "initialized"
+
+	meta := &r.Data.Parsed.Info.Meta
+	meta.Authorized.Staker = a.staker
+	meta.Authorized.Withdrawer = a.withdrawer
+	meta.Lockup.Custodian = "11111111111111111111111111111111"
+	meta.RentExemptReserve = strconv.FormatUint(a.rentExemptReserve, 10)
+
+	if a.delegation != nil {
+		r.Data.Parsed.Type = "delegated"
+
+		s := &types.StakeAccountInfoStake{}
+		s.Delegation.ActivationEpoch = a.delegation.activationEpoch
+		s.Delegation.DeactivationEpoch = a.delegation.deactivationEpoch
+		s.Delegation.Stake = a.delegation.stake
+		s.Delegation.Voter = a.delegation.voter
+		s.Delegation.WarmupCooldownRate = 0.25
+		r.Data.Parsed.Info.Stake = s
+	}
+
+	return r, nil
+}
+
+// StakeHistoryAccount returns the StakeHistory sysvar as returned by getAccountInfo with jsonParsed encoding.
+// Like the sysvar, entries are ordered from the newest epoch and only the last 512 epochs are kept.
+func (c *Cluster) StakeHistoryAccount() *types.StakeHistoryAccount {
+	r := &types.StakeHistoryAccount{
+		Lamports:  114979200,
+		Owner:     sysvarOwnerAddress,
+		RentEpoch: math.MaxUint64,
+		Space:     16392,
+	}
+	r.Data.Program = "sysvar"
+	r.Data.Space = 16392
+	r.Data.Parsed.Type = "stakeHistory"
+	r.Data.Parsed.Info = []types.StakeHistoryAccountInfo{}
+
+	for epoch := c.epoch; epoch > 0 && len(r.Data.Parsed.Info) < maxStakeHistoryEntries; epoch-- {
+		entry, ok := c.history[epoch-1]
+		if !ok {
+			break
+		}
+		r.Data.Parsed.Info = append(r.Data.Parsed.Info, entry)
+	}
+	return r
+}
+
+func (c *Cluster) account(address string) (*stakeAccount, error) {
+	a, ok := c.accounts[address]
+	if !ok {
+		return nil, fmt.Errorf("account not found, address: %s", address)
+	}
+	return a, nil
+}
+
+// setStake changes the delegated stake, which is fully activating, fully effective or inactive.
+func (d *delegation) setStake(stake uint64) {
+	switch {
+	case d.status.Activating > 0:
+		d.status = Status{Activating: stake}
+	case d.status.Effective > 0:
+		d.status = Status{Effective: stake}
+	}
+	d.stake = stake
+}
+
+func (c *Cluster) delegations() []*delegation {
+	delegations := append([]*delegation{}, c.bootstrap...)
+	for _, address := range c.Addresses() {
+		if d := c.accounts[address].delegation; d != nil {
+			delegations = append(delegations, d)
+		}
+	}
+	return delegations
+}
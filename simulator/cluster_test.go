@@ -0,0 +1,461 @@
+package simulator
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	sdktypes "github.com/blocto/solana-go-sdk/types"
+	"github.com/skport/solana-rpc-client-extensions-go/client"
+	"github.com/skport/solana-rpc-client-extensions-go/types"
+)
+
+const (
+	testAuthority = "3oexKwZRXJNwJjaaLCrqYVMauS4EQAk7zzhScuqTQD77"
+	testVoter1    = "FwR3PbjS5iyqzLiLugrBqKSa5EKZ4vK9SKs7eQXtT59f"
+	testVoter2    = "J2nUHEAgZFRyuJbFjdqPrAa9gyWDuc7hErtDQHPhsYRp"
+)
+
+func TestCluster_Lifecycle(t *testing.T) {
+	c := NewCluster(100, 1_000_000_000_000_000)
+	stake := uint64(1_000_000_000)
+	if err := c.CreateStakeAccount("a", stake+DefaultRentExemptReserve, testAuthority); err != nil {
+		t.Fatalf("CreateStakeAccount error: %v", err)
+	}
+
+	steps := []struct {
+		name string
+		do   func() error
+		want Status
+	}{
+		{name: "initialized", do: func() error { return nil }, want: Status{}},
+		{name: "activating", do: func() error { return c.Delegate("a", testVoter1) }, want: Status{Activating: stake}},
+		{name: "active", do: func() error { c.AdvanceEpoch(); return nil }, want: Status{Effective: stake}},
+		{name: "deactivating", do: func() error { return c.Deactivate("a") }, want: Status{Effective: stake, Deactivating: stake}},
+		{name: "inactive", do: func() error { c.AdvanceEpoch(); return nil }, want: Status{}},
+		{name: "redelegated", do: func() error { return c.Delegate("a", testVoter2) }, want: Status{Activating: stake}},
+	}
+
+	for _, step := range steps {
+		if err := step.do(); err != nil {
+			t.Fatalf("%s: error: %v", step.name, err)
+		}
+		got, err := c.Status("a")
+		if err != nil {
+			t.Fatalf("%s: Status error: %v", step.name, err)
+		}
+		if got != step.want {
+			t.Errorf("%s: Status = %+v, want %+v", step.name, got, step.want)
+		}
+	}
+
+	history := c.StakeHistoryAccount().Data.Parsed.Info
+	if len(history) != 2 || history[0].Epoch != 101 || history[1].Epoch != 100 {
+		t.Fatalf("StakeHistory = %+v", history)
+	}
+	if history[1].StakeHistory.Activating != stake || history[0].StakeHistory.Deactivating != stake {
+		t.Errorf("StakeHistory = %+v", history)
+	}
+}
+
+func TestCluster_Warmup(t *testing.T) {
+	// The delegation is larger than 9% of the effective stake of the cluster, so it takes several epochs to warm up.
+	c := NewCluster(0, 1_000_000_000_000)
+	if err := c.CreateStakeAccount("a", 500_000_000_000+DefaultRentExemptReserve, testAuthority); err != nil {
+		t.Fatalf("CreateStakeAccount error: %v", err)
+	}
+	if err := c.Delegate("a", testVoter1); err != nil {
+		t.Fatalf("Delegate error: %v", err)
+	}
+
+	var prev Status
+	for i := 0; i < 10; i++ {
+		c.AdvanceEpoch()
+		s, _ := c.Status("a")
+		if s.Effective+s.Activating != 500_000_000_000 {
+			t.Errorf("epoch %d: Status = %+v", c.Epoch(), s)
+		}
+		if s.Effective < prev.Effective {
+			t.Errorf("epoch %d: effective stake decreased, %d -> %d", c.Epoch(), prev.Effective, s.Effective)
+		}
+		if i == 0 && s.Activating == 0 {
+			t.Errorf("epoch %d: Status = %+v, want still activating", c.Epoch(), s)
+		}
+		prev = s
+	}
+	if prev.Effective != 500_000_000_000 {
+		t.Errorf("Status = %+v, want fully effective", prev)
+	}
+}
+
+// TestCluster_Vectors checks the epoch by epoch bookkeeping of the simulator against values worked out by hand
+// from the warmup/cooldown rules of the stake program.
+// The inputs are chosen so that no newly effective stake is within 0.1 lamports of an integer, where float64 and exact arithmetic
+// could truncate differently.
+func TestCluster_Vectors(t *testing.T) {
+	c := NewCluster(0, 1_000_000)
+	for _, a := range []struct {
+		address string
+		stake   uint64
+	}{{"a", 100_003}, {"b", 300_007}} {
+		if err := c.CreateStakeAccount(a.address, a.stake+DefaultRentExemptReserve, testAuthority); err != nil {
+			t.Fatalf("CreateStakeAccount error: %v", err)
+		}
+		if err := c.Delegate(a.address, testVoter1); err != nil {
+			t.Fatalf("Delegate error: %v", err)
+		}
+	}
+
+	epochs := []struct {
+		a, b    Status
+		history [3]uint64 // effective, activating and deactivating stake of the cluster in the epoch
+	}{
+		// epoch 0: both delegations are activating.
+		{a: Status{Activating: 100_003}, b: Status{Activating: 300_007}, history: [3]uint64{1_000_000, 400_010, 0}},
+		// epoch 1: 1_000_000 * 0.09 = 90_000 newly effective, of which
+		// a: 100_003 / 400_010 * 90_000 = 22_500.11 and b: 300_007 / 400_010 * 90_000 = 67_499.89.
+		{a: Status{Effective: 22_500, Activating: 77_503}, b: Status{Effective: 67_499, Activating: 232_508}, history: [3]uint64{1_089_999, 310_011, 0}},
+		// epoch 2: 1_089_999 * 0.09 = 98_099.91 newly effective, of which
+		// a: 77_503 / 310_011 * 98_099.91 = 24_525.06 and b: 232_508 / 310_011 * 98_099.91 = 73_574.85.
+		{a: Status{Effective: 47_025, Activating: 52_978}, b: Status{Effective: 141_073, Activating: 158_934}, history: [3]uint64{1_188_098, 211_912, 0}},
+		// epoch 3: 1_188_098 * 0.09 = 106_928.82 newly effective, of which
+		// a: 52_978 / 211_912 * 106_928.82 = 26_732.20 and b: 158_934 / 211_912 * 106_928.82 = 80_196.61.
+		// a is deactivated in this epoch, so its effective stake starts cooling down and the rest never activates.
+		{a: Status{Effective: 73_757, Deactivating: 73_757}, b: Status{Effective: 221_269, Activating: 78_738}, history: [3]uint64{1_295_026, 78_738, 73_757}},
+		// epoch 4: 1_295_026 * 0.09 = 116_552.34 newly (not) effective, which exceeds both the deactivating stake of a and the activating stake of b.
+		{a: Status{}, b: Status{Effective: 300_007}, history: [3]uint64{1_300_007, 0, 0}},
+	}
+
+	for epoch, want := range epochs {
+		if epoch == 3 {
+			if err := c.Deactivate("a"); err != nil {
+				t.Fatalf("Deactivate error: %v", err)
+			}
+		}
+		a, _ := c.Status("a")
+		b, _ := c.Status("b")
+		if a != want.a || b != want.b {
+			t.Errorf("epoch %d: Status = %+v, %+v, want %+v, %+v", epoch, a, b, want.a, want.b)
+		}
+
+		c.AdvanceEpoch()
+		entry := c.StakeHistoryAccount().Data.Parsed.Info[0]
+		got := [3]uint64{entry.StakeHistory.Effective, entry.StakeHistory.Activating, entry.StakeHistory.Deactivating}
+		if entry.Epoch != epoch || got != want.history {
+			t.Errorf("epoch %d: StakeHistory entry of epoch %d = %v, want %v", epoch, entry.Epoch, got, want.history)
+		}
+	}
+}
+
+func TestCluster_SplitMerge(t *testing.T) {
+	c := NewCluster(0, 1_000_000_000_000_000)
+	if err := c.CreateStakeAccount("a", 10_000_000_000+DefaultRentExemptReserve, testAuthority); err != nil {
+		t.Fatalf("CreateStakeAccount error: %v", err)
+	}
+	if err := c.Delegate("a", testVoter1); err != nil {
+		t.Fatalf("Delegate error: %v", err)
+	}
+	c.AdvanceEpoch()
+
+	if err := c.Split("a", "b", 4_000_000_000); err != nil {
+		t.Fatalf("Split error: %v", err)
+	}
+	a, _ := c.Status("a")
+	b, _ := c.Status("b")
+	if a.Effective != 6_000_000_000 || b.Effective != 4_000_000_000-DefaultRentExemptReserve {
+		t.Errorf("Status after split = %+v, %+v", a, b)
+	}
+
+	// fully active + fully active
+	if err := c.Merge("a", "b"); err != nil {
+		t.Fatalf("Merge error: %v", err)
+	}
+	a, _ = c.Status("a")
+	if a.Effective != 10_000_000_000-DefaultRentExemptReserve {
+		t.Errorf("Status after merge = %+v", a)
+	}
+
+	// fully active + activating is a mismatch
+	if err := c.CreateStakeAccount("c", 1_000_000_000+DefaultRentExemptReserve, testAuthority); err != nil {
+		t.Fatalf("CreateStakeAccount error: %v", err)
+	}
+	if err := c.Delegate("c", testVoter1); err != nil {
+		t.Fatalf("Delegate error: %v", err)
+	}
+	if err := c.Merge("a", "c"); err == nil {
+		t.Errorf("Merge error = nil, want merge mismatch")
+	}
+
+	// deactivating stake is transient
+	if err := c.Deactivate("a"); err != nil {
+		t.Fatalf("Deactivate error: %v", err)
+	}
+	if err := c.Merge("c", "a"); err == nil {
+		t.Errorf("Merge error = nil, want transient stake")
+	}
+	if err := c.Split("a", "d", 1_000_000_000); err == nil {
+		t.Errorf("Split error = nil, want transient stake")
+	}
+}
+
+// TestCluster_Bootstrap checks client.GetStakeActivation for genesis stake accounts from the first epoch of a cluster.
+func TestCluster_Bootstrap(t *testing.T) {
+	c := NewCluster(0, 0)
+	if err := c.CreateBootstrapStakeAccount("genesis", 500_000_000_000_000+DefaultRentExemptReserve, testAuthority, testVoter1); err != nil {
+		t.Fatalf("CreateBootstrapStakeAccount error: %v", err)
+	}
+	if err := c.CreateBootstrapStakeAccount("bootstrap", 100_000_000_000_000+DefaultRentExemptReserve, testAuthority, testVoter2); err != nil {
+		t.Fatalf("CreateBootstrapStakeAccount error: %v", err)
+	}
+	if err := c.CreateStakeAccount("a", 50_000_000_000_000+DefaultRentExemptReserve, testAuthority); err != nil {
+		t.Fatalf("CreateStakeAccount error: %v", err)
+	}
+
+	for epoch := 0; epoch < 20; epoch++ {
+		switch epoch {
+		case 1:
+			if err := c.Delegate("a", testVoter1); err != nil {
+				t.Fatalf("Delegate error: %v", err)
+			}
+		case 3:
+			if err := c.Deactivate("bootstrap"); err != nil {
+				t.Fatalf("Deactivate error: %v", err)
+			}
+		}
+
+		assertGetStakeActivation(t, c)
+		c.AdvanceEpoch()
+	}
+
+	if s, _ := c.Status("genesis"); s.Effective != 500_000_000_000_000 {
+		t.Errorf("Status = %+v, want fully effective", s)
+	}
+}
+
+// TestCluster_Redelegate checks client.GetStakeActivation for stakes that are deactivated and redelegated,
+// whose activation and deactivation epochs are reset by the redelegation.
+func TestCluster_Redelegate(t *testing.T) {
+	c := NewCluster(0, 1_000_000_000_000)
+	for _, address := range []string{"a", "b"} {
+		if err := c.CreateStakeAccount(address, 200_000_000_000+DefaultRentExemptReserve, testAuthority); err != nil {
+			t.Fatalf("CreateStakeAccount error: %v", err)
+		}
+		if err := c.Delegate(address, testVoter1); err != nil {
+			t.Fatalf("Delegate error: %v", err)
+		}
+	}
+
+	steps := map[int]func() error{
+		// a: deactivated while warming up, and redelegated to another voter once cooled down
+		3:  func() error { return c.Deactivate("a") },
+		12: func() error { return c.Delegate("a", testVoter2) },
+		// b: deactivated and reactivated in the same epoch, which rescinds the deactivation
+		8: func() error {
+			if err := c.Deactivate("b"); err != nil {
+				return err
+			}
+			return c.Delegate("b", testVoter1)
+		},
+	}
+
+	for epoch := 0; epoch < 30; epoch++ {
+		if step, ok := steps[epoch]; ok {
+			if err := step(); err != nil {
+				t.Fatalf("epoch %d: error: %v", epoch, err)
+			}
+		}
+		assertGetStakeActivation(t, c)
+		c.AdvanceEpoch()
+	}
+
+	stakeAccount, _ := c.StakeAccount("a")
+	if d := stakeAccount.Data.Parsed.Info.Stake.Delegation; d.ActivationEpoch != 12 || d.IsDeactivating() {
+		t.Errorf("Delegation = %+v, want redelegated in epoch 12", d)
+	}
+	stakeAccount, _ = c.StakeAccount("b")
+	if d := stakeAccount.Data.Parsed.Info.Stake.Delegation; d.ActivationEpoch != 0 || d.IsDeactivating() {
+		t.Errorf("Delegation = %+v, want deactivation rescinded", d)
+	}
+}
+
+// TestCluster_GetStakeActivation checks client.GetStakeActivation against the ground truth of random scenarios.
+func TestCluster_GetStakeActivation(t *testing.T) {
+	for seed := int64(0); seed < 20; seed++ {
+		t.Run(fmt.Sprintf("seed %d", seed), func(t *testing.T) {
+			r := rand.New(rand.NewSource(seed))
+
+			// A small bootstrap stake, so that delegations warm up and cool down over several epochs.
+			c := NewCluster(500, 2_000_000_000_000)
+			next := 0
+			voters := []string{testVoter1, testVoter2}
+
+			for epoch := 0; epoch < 30; epoch++ {
+				for op := 0; op < 5; op++ {
+					addresses := c.Addresses()
+					switch n := r.Intn(6); {
+					case n == 0 || len(addresses) == 0:
+						address := fmt.Sprintf("account%d", next)
+						next++
+						lamports := DefaultRentExemptReserve + uint64(r.Int63n(300_000_000_000)) + 1
+						if err := c.CreateStakeAccount(address, lamports, testAuthority); err == nil {
+							_ = c.Delegate(address, voters[r.Intn(len(voters))])
+						}
+					case n == 1:
+						_ = c.Deactivate(addresses[r.Intn(len(addresses))])
+					case n == 2:
+						_ = c.Delegate(addresses[r.Intn(len(addresses))], voters[r.Intn(len(voters))])
+					case n == 3:
+						from := addresses[r.Intn(len(addresses))]
+						lamports, _ := c.Lamports(from)
+						_ = c.Split(from, fmt.Sprintf("account%d", next), lamports/2)
+						next++
+					case n == 4:
+						to, from := addresses[r.Intn(len(addresses))], addresses[r.Intn(len(addresses))]
+						if to == from {
+							break
+						}
+						_, checkErr := checkMerge(c, to, from)
+						mergeErr := c.Merge(to, from)
+						if (checkErr == nil) != (mergeErr == nil) {
+							t.Errorf("epoch %d: CheckMerge error = %v, Merge error = %v", c.Epoch(), checkErr, mergeErr)
+						}
+					}
+				}
+				c.AdvanceEpoch()
+
+				assertGetStakeActivation(t, c)
+			}
+		})
+	}
+}
+
+// TestCluster_SimulateStakeTransaction checks the predictions of client.SimulateStakeTransaction against the ground truth.
+// The rest of the cluster is only the bootstrap stake, which is effective throughout, so predictions are exact.
+func TestCluster_SimulateStakeTransaction(t *testing.T) {
+	const (
+		a = "4vJ9JU1bJJE96FWSJKvHsmmFADCg4gpZQff4P3bkLKi"
+		b = "8qbHbw2BbbTHBW1sbeqakYXVKRQM8Ne7pLK7m6CVfeR"
+	)
+	c := NewCluster(0, 1_000_000_000_000)
+	if err := c.CreateStakeAccount(a, 500_000_000_000+DefaultRentExemptReserve, testAuthority); err != nil {
+		t.Fatalf("CreateStakeAccount error: %v", err)
+	}
+	if err := c.Delegate(a, testVoter1); err != nil {
+		t.Fatalf("Delegate error: %v", err)
+	}
+	c.AdvanceEpoch()
+
+	// b is created and delegated while a is still warming up.
+	instructions, err := client.CreateStakeAccountInstructions(client.CreateStakeAccountParam{
+		From:       testAuthority,
+		Stake:      b,
+		Staker:     testAuthority,
+		Withdrawer: testAuthority,
+		Lamports:   200_000_000_000 + DefaultRentExemptReserve,
+		Voter:      testVoter2,
+	})
+	if err != nil {
+		t.Fatalf("CreateStakeAccountInstructions error: %v", err)
+	}
+	assertSimulateStakeTransaction(t, c, instructions, func() error {
+		if err := c.CreateStakeAccount(b, 200_000_000_000+DefaultRentExemptReserve, testAuthority); err != nil {
+			return err
+		}
+		return c.Delegate(b, testVoter2)
+	})
+
+	instruction, err := client.DeactivateStakeInstruction(client.DeactivateStakeParam{Stake: a, Staker: testAuthority})
+	if err != nil {
+		t.Fatalf("DeactivateStakeInstruction error: %v", err)
+	}
+	assertSimulateStakeTransaction(t, c, []sdktypes.Instruction{instruction}, func() error {
+		return c.Deactivate(a)
+	})
+}
+
+// assertSimulateStakeTransaction simulates instructions at the current epoch, applies them to the cluster with apply,
+// and compares the predicted activations with the cluster over the following epochs.
+func assertSimulateStakeTransaction(t *testing.T, c *Cluster, instructions []sdktypes.Instruction, apply func() error) {
+	t.Helper()
+	const futureEpochs = 10
+
+	req := &client.SimulateStakeTransactionRequest{
+		Epoch:        c.Epoch(),
+		Accounts:     map[string]*types.StakeAccount{},
+		StakeHistory: c.StakeHistoryAccount(),
+		Limits:       client.StakeAmountLimits{RentExemptReserve: DefaultRentExemptReserve, MinimumDelegation: 1},
+		FutureEpochs: futureEpochs,
+	}
+	for _, address := range c.Addresses() {
+		req.Accounts[address], _ = c.StakeAccount(address)
+	}
+	res, err := client.SimulateStakeTransaction(req, instructions)
+	if err != nil {
+		t.Fatalf("SimulateStakeTransaction error: %v", err)
+	}
+	if err := apply(); err != nil {
+		t.Fatalf("apply error: %v", err)
+	}
+
+	for i := 0; i <= futureEpochs; i++ {
+		for _, address := range c.Addresses() {
+			want, _ := c.Status(address)
+			activations := res.Activations[address]
+			if len(activations) != futureEpochs+1 {
+				t.Fatalf("%s: Activations = %+v", address, activations)
+			}
+			if got := activations[i]; got.Active != want.Effective {
+				t.Errorf("epoch %d, %s: predicted activation = %+v, want %+v", c.Epoch(), address, got, want)
+			}
+		}
+		c.AdvanceEpoch()
+	}
+}
+
+func checkMerge(c *Cluster, to string, from string) (*client.CheckMergeResponse, error) {
+	destination, err := c.StakeAccount(to)
+	if err != nil {
+		return nil, err
+	}
+	source, err := c.StakeAccount(from)
+	if err != nil {
+		return nil, err
+	}
+	return client.CheckMerge(c.Epoch(), 0, destination, source, c.StakeHistoryAccount())
+}
+
+func assertGetStakeActivation(t *testing.T, c *Cluster) {
+	t.Helper()
+
+	stakeHistoryAccount := c.StakeHistoryAccount()
+	for _, address := range c.Addresses() {
+		stakeAccount, err := c.StakeAccount(address)
+		if err != nil {
+			t.Fatalf("StakeAccount error: %v", err)
+		}
+		want, err := c.Status(address)
+		if err != nil {
+			t.Fatalf("Status error: %v", err)
+		}
+
+		got, err := client.GetStakeActivation(address, c.Epoch(), stakeAccount, stakeHistoryAccount)
+		if err != nil {
+			t.Fatalf("epoch %d, %s: GetStakeActivation error: %v", c.Epoch(), address, err)
+		}
+
+		wantState := "inactive"
+		if want.Deactivating > 0 {
+			wantState = "deactivating"
+		} else if want.Activating > 0 {
+			wantState = "activating"
+		} else if want.Effective > 0 {
+			wantState = "active"
+		}
+		if got.Active != want.Effective || got.State != wantState {
+			t.Errorf("epoch %d, %s: GetStakeActivation = %+v, want %+v", c.Epoch(), address, got, want)
+		}
+		if got.Active+got.Inactive+DefaultRentExemptReserve != stakeAccount.Lamports {
+			t.Errorf("epoch %d, %s: active + inactive + rentExemptReserve = %d, lamports %d", c.Epoch(), address, got.Active+got.Inactive+DefaultRentExemptReserve, stakeAccount.Lamports)
+		}
+	}
+}
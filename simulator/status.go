@@ -0,0 +1,48 @@
+package simulator
+
+import (
+	"math"
+)
+
+// The simulator keeps the status of every delegation in the current epoch and moves it forward one epoch at a time
+// in AdvanceEpoch, from the stake of the cluster in the ending epoch. The runtime and the client instead replay
+// the StakeHistory from the activation epoch of a delegation whenever its status is needed, so comparing the two
+// checks the replay against bookkeeping which never reads the history back.
+// https://github.com/anza-xyz/agave/blob/v2.0.0/sdk/program/src/stake/state.rs#L640
+
+// next returns the status in the next epoch of a delegation whose status in the current epoch is s,
+// where cluster is the total stake of the cluster in the current epoch.
+func (s Status) next(cluster Status) Status {
+	switch {
+	case s.Activating > 0:
+		// The cluster warms up by 9% of its effective stake, which is shared in proportion to the activating stake.
+		newlyEffective := share(s.Activating, cluster.Activating, cluster.Effective)
+		if newlyEffective >= s.Activating {
+			return Status{Effective: s.Effective + s.Activating}
+		}
+		return Status{Effective: s.Effective + newlyEffective, Activating: s.Activating - newlyEffective}
+	case s.Deactivating > 0:
+		// The cluster cools down by 9% of its effective stake, which is shared in proportion to the deactivating stake.
+		newlyNotEffective := share(s.Deactivating, cluster.Deactivating, cluster.Effective)
+		if newlyNotEffective >= s.Deactivating {
+			return Status{}
+		}
+		remaining := s.Deactivating - newlyNotEffective
+		return Status{Effective: remaining, Deactivating: remaining}
+	default:
+		return s
+	}
+}
+
+// share returns the part of the stake of the cluster changing state in an epoch which stake, out of the transient stake of the cluster, is entitled to.
+// Like the runtime, it is truncated to lamports but at least 1 lamport.
+func share(stake uint64, transient uint64, effective uint64) uint64 {
+	x := float64(stake) / float64(transient) * (float64(effective) * stakeWarmupCooldownRate)
+	if math.IsNaN(x) || x < 1 {
+		return 1
+	}
+	if x >= math.MaxUint64 {
+		return math.MaxUint64
+	}
+	return uint64(x)
+}
@@ -1,7 +1,6 @@
 package types
 
 import (
-	"fmt"
 	"strconv"
 )
 
@@ -25,6 +24,18 @@ type StakeAccount struct {
 	RentEpoch  uint64 `json:"rentEpoch"`
 }
 
+// StakeState is the variant of StakeStateV2, as Data.Parsed.Type of a jsonParsed stake account.
+type StakeState string
+
+const (
+	StakeStateUninitialized StakeState = "uninitialized"
+	// Initialized has Meta but no delegation.
+	StakeStateInitialized StakeState = "initialized"
+	// Stake has Meta and a delegation.
+	StakeStateStake       StakeState = "delegated"
+	StakeStateRewardsPool StakeState = "rewardsPool"
+)
+
 type StakeAccountInfoMeta struct {
 	Authorized struct {
 		Staker     string `json:"staker"`
@@ -39,14 +50,25 @@ type StakeAccountInfoMeta struct {
 }
 
 type StakeAccountInfoStake struct {
-	CreditsObserved uint64 `json:"creditsObserved"`
-	Delegation      struct {
-		ActivationEpoch    string  `json:"activationEpoch"`
-		DeactivationEpoch  string  `json:"deactivationEpoch"`
-		Stake              string  `json:"stake"`
-		Voter              string  `json:"voter"`
-		WarmupCooldownRate float64 `json:"warmupCooldownRate"`
-	} `json:"delegation"`
+	CreditsObserved uint64     `json:"creditsObserved"`
+	Delegation      Delegation `json:"delegation"`
+	// Flags is only decoded from binary data, jsonParsed data doesn't include it.
+	Flags StakeFlags `json:"flags,omitempty"`
+}
+
+func (r *StakeAccount) GetState() (StakeState, error) {
+	switch s := StakeState(r.Data.Parsed.Type); s {
+	case StakeStateUninitialized, StakeStateInitialized, StakeStateStake, StakeStateRewardsPool:
+		return s, nil
+	default:
+		return "", &FieldError{Field: "Type", Value: r.Data.Parsed.Type, Err: ErrInvalidField}
+	}
+}
+
+// HasMeta returns false for Uninitialized and RewardsPool accounts, which have no Meta.
+func (r *StakeAccount) HasMeta() bool {
+	s := StakeState(r.Data.Parsed.Type)
+	return s != StakeStateUninitialized && s != StakeStateRewardsPool
 }
 
 func (r *StakeAccount) GetInfoMeta() StakeAccountInfoMeta {
@@ -56,17 +78,17 @@ func (r *StakeAccount) GetInfoMeta() StakeAccountInfoMeta {
 func (r *StakeAccount) GetInfoStake() (*StakeAccountInfoStake, error) {
 	// In the inactive state, r.Value.Data.Parsed.Info.Stake is nil.
 	if r.Data.Parsed.Info.Stake == nil {
-		return nil, fmt.Errorf("r.Value.Data.Parsed.Info.Stake is nil")
+		return nil, ErrNotDelegated
 	}
 	return r.Data.Parsed.Info.Stake, nil
 }
 
 func (r *StakeAccount) GetRentExemptReserve() (uint64, error) {
-	m := r.GetInfoMeta()
-	if m.RentExemptReserve == "" {
-		return 0, fmt.Errorf("RentExemptReserve is empty")
+	if !r.HasMeta() {
+		return 0, ErrNoMeta
 	}
-	return strconv.ParseUint(m.RentExemptReserve, 10, 64)
+	m := r.GetInfoMeta()
+	return parseUint64Field("RentExemptReserve", m.RentExemptReserve)
 }
 
 func (r *StakeAccount) GetDelegationStake() (uint64, error) {
@@ -74,10 +96,7 @@ func (r *StakeAccount) GetDelegationStake() (uint64, error) {
 	if err != nil {
 		return 0, err
 	}
-	if s.Delegation.Stake == "" {
-		return 0, fmt.Errorf("Delegation.Stake is empty")
-	}
-	return strconv.ParseUint(s.Delegation.Stake, 10, 64)
+	return s.Delegation.Stake, nil
 }
 
 func (r *StakeAccount) GetActivationEpoch() (uint64, error) {
@@ -85,10 +104,7 @@ func (r *StakeAccount) GetActivationEpoch() (uint64, error) {
 	if err != nil {
 		return 0, err
 	}
-	if s.Delegation.ActivationEpoch == "" {
-		return 0, fmt.Errorf("Delegation.ActivationEpoch is empty")
-	}
-	return strconv.ParseUint(s.Delegation.ActivationEpoch, 10, 64)
+	return s.Delegation.ActivationEpoch, nil
 }
 
 func (r *StakeAccount) GetDeactivationEpoch() (uint64, error) {
@@ -96,10 +112,18 @@ func (r *StakeAccount) GetDeactivationEpoch() (uint64, error) {
 	if err != nil {
 		return 0, err
 	}
-	if s.Delegation.DeactivationEpoch == "" {
-		return 0, fmt.Errorf("Delegation.DeactivationEpoch is empty")
+	return s.Delegation.DeactivationEpoch, nil
+}
+
+func parseUint64Field(field string, value string) (uint64, error) {
+	if value == "" {
+		return 0, &FieldError{Field: field, Err: ErrMissingField}
+	}
+	n, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, &FieldError{Field: field, Value: value, Err: ErrInvalidField}
 	}
-	return strconv.ParseUint(s.Delegation.DeactivationEpoch, 10, 64)
+	return n, nil
 }
 
 type StakeHistoryAccount struct {
@@ -0,0 +1,131 @@
+package types
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"golang.org/x/xerrors"
+)
+
+// DecodeStakeAccount decodes the `value` of getAccountInfo for a stake account, with jsonParsed or base64 encoding.
+// Only the binary data of base64 encoding includes the stake flags.
+// It returns ErrAccountNotFound for null.
+func DecodeStakeAccount(raw []byte) (*StakeAccount, error) {
+	var account *encodedAccount
+	if err := json.Unmarshal(raw, &account); err != nil {
+		return nil, xerrors.Errorf("%w: failed to unmarshal to StakeAccountInfoResponse: %v", ErrNotStakeAccount, err)
+	}
+	if account == nil {
+		return nil, ErrAccountNotFound
+	}
+
+	stakeAccount := &StakeAccount{
+		Executable: account.Executable,
+		Lamports:   account.Lamports,
+		Owner:      account.Owner,
+		RentEpoch:  account.RentEpoch,
+	}
+	if data, encoding, ok := account.encodedData(); ok {
+		if err := DecodeEncodedData(stakeAccount, data, encoding); err != nil {
+			return nil, err
+		}
+		return stakeAccount, nil
+	}
+	if len(account.Data) == 0 {
+		return stakeAccount, nil
+	}
+	if err := json.Unmarshal(account.Data, &stakeAccount.Data); err != nil {
+		// A malformed delegation of a stake account is reported as is.
+		var fieldErr *FieldError
+		if errors.As(err, &fieldErr) {
+			return nil, xerrors.Errorf("failed to unmarshal to StakeAccountInfoResponse: %w", err)
+		}
+		return nil, xerrors.Errorf("%w: failed to unmarshal to StakeAccountInfoResponse: %v", ErrNotStakeAccount, err)
+	}
+	return stakeAccount, nil
+}
+
+// encodedAccount is the `value` of getAccountInfo, whose data is decoded depending on the encoding.
+type encodedAccount struct {
+	Data       json.RawMessage `json:"data"`
+	Executable bool            `json:"executable"`
+	Lamports   uint64          `json:"lamports"`
+	Owner      string          `json:"owner"`
+	RentEpoch  uint64          `json:"rentEpoch"`
+	Space      uint64          `json:"space"`
+}
+
+// encodedData returns the data of an encoding other than jsonParsed, [data, encoding].
+func (a *encodedAccount) encodedData() (string, string, bool) {
+	if d := bytes.TrimLeft(a.Data, " \t\r\n"); len(d) == 0 || d[0] != '[' {
+		return "", "", false
+	}
+	var data []string
+	if err := json.Unmarshal(a.Data, &data); err != nil || len(data) != 2 {
+		return "", "", false
+	}
+	return data[0], data[1], true
+}
+
+// DecodeEncodedData decodes the account data of an RPC response, [data, encoding], into stakeAccount.Data.
+// Only base64 encoding is supported.
+func DecodeEncodedData(stakeAccount *StakeAccount, data string, encoding string) error {
+	if encoding != "base64" {
+		return fmt.Errorf("%w: unsupported encoding: %s", ErrNotStakeAccount, encoding)
+	}
+	b, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return xerrors.Errorf("%w: failed to decode base64: %v", ErrNotStakeAccount, err)
+	}
+	if err := stakeAccount.DecodeData(b); err != nil {
+		return xerrors.Errorf("%w: %v", ErrNotStakeAccount, err)
+	}
+	return nil
+}
+
+// DecodeStakeHistoryAccount decodes the `value` of getAccountInfo for the StakeHistory sysvar, with jsonParsed or base64 encoding.
+// It returns ErrAccountNotFound for null.
+func DecodeStakeHistoryAccount(raw []byte) (*StakeHistoryAccount, error) {
+	var account *encodedAccount
+	if err := json.Unmarshal(raw, &account); err != nil {
+		return nil, xerrors.Errorf("%w: failed to unmarshal to StakeHistoryAccountInfoResponse: %v", ErrNotStakeHistoryAccount, err)
+	}
+	if account == nil {
+		return nil, ErrAccountNotFound
+	}
+
+	stakeHistoryAccount := &StakeHistoryAccount{
+		Executable: account.Executable,
+		Lamports:   account.Lamports,
+		Owner:      account.Owner,
+		RentEpoch:  account.RentEpoch,
+		Space:      account.Space,
+	}
+	if data, encoding, ok := account.encodedData(); ok {
+		if encoding != "base64" {
+			return nil, fmt.Errorf("%w: unsupported encoding: %s", ErrNotStakeHistoryAccount, encoding)
+		}
+		b, err := base64.StdEncoding.DecodeString(data)
+		if err != nil {
+			return nil, xerrors.Errorf("%w: failed to decode base64: %v", ErrNotStakeHistoryAccount, err)
+		}
+		if err := stakeHistoryAccount.DecodeData(b); err != nil {
+			return nil, xerrors.Errorf("%w: %v", ErrNotStakeHistoryAccount, err)
+		}
+		return stakeHistoryAccount, nil
+	}
+
+	if len(account.Data) > 0 {
+		if err := json.Unmarshal(account.Data, &stakeHistoryAccount.Data); err != nil {
+			return nil, xerrors.Errorf("%w: failed to unmarshal to StakeHistoryAccountInfoResponse: %v", ErrNotStakeHistoryAccount, err)
+		}
+	}
+	if stakeHistoryAccount.Data.Parsed.Info == nil {
+		return nil, xerrors.Errorf("%w: no entries", ErrNotStakeHistoryAccount)
+	}
+
+	return stakeHistoryAccount, nil
+}
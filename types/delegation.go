@@ -0,0 +1,93 @@
+package types
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+)
+
+// MaxEpoch is u64::MAX, which the runtime uses as the activation epoch of bootstrap stakes
+// and as the deactivation epoch of stakes that are not deactivating.
+const MaxEpoch uint64 = math.MaxUint64
+
+// Delegation is the delegation of a stake account.
+// jsonParsed encodes u64 values as strings, which are parsed once when the account is decoded.
+type Delegation struct {
+	ActivationEpoch    uint64
+	DeactivationEpoch  uint64
+	Stake              uint64
+	Voter              string
+	WarmupCooldownRate float64
+}
+
+// IsDeactivating returns whether the stake was deactivated, including a stake which has already cooled down.
+func (d *Delegation) IsDeactivating() bool {
+	return d.DeactivationEpoch != MaxEpoch
+}
+
+// IsBootstrap returns whether the stake was delegated in the genesis config, and is effective from the first epoch.
+func (d *Delegation) IsBootstrap() bool {
+	return d.ActivationEpoch == MaxEpoch
+}
+
+type delegationJSON struct {
+	ActivationEpoch    json.RawMessage `json:"activationEpoch"`
+	DeactivationEpoch  json.RawMessage `json:"deactivationEpoch"`
+	Stake              json.RawMessage `json:"stake"`
+	Voter              string          `json:"voter"`
+	WarmupCooldownRate float64         `json:"warmupCooldownRate"`
+}
+
+func (d *Delegation) UnmarshalJSON(b []byte) error {
+	var v delegationJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+
+	var err error
+	if d.ActivationEpoch, err = parseUint64JSON("Delegation.ActivationEpoch", v.ActivationEpoch); err != nil {
+		return err
+	}
+	if d.DeactivationEpoch, err = parseUint64JSON("Delegation.DeactivationEpoch", v.DeactivationEpoch); err != nil {
+		return err
+	}
+	if d.Stake, err = parseUint64JSON("Delegation.Stake", v.Stake); err != nil {
+		return err
+	}
+	d.Voter = v.Voter
+	d.WarmupCooldownRate = v.WarmupCooldownRate
+	return nil
+}
+
+// MarshalJSON encodes u64 values as strings, as the jsonParsed encoding does.
+func (d Delegation) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		ActivationEpoch    string  `json:"activationEpoch"`
+		DeactivationEpoch  string  `json:"deactivationEpoch"`
+		Stake              string  `json:"stake"`
+		Voter              string  `json:"voter"`
+		WarmupCooldownRate float64 `json:"warmupCooldownRate"`
+	}{
+		ActivationEpoch:    strconv.FormatUint(d.ActivationEpoch, 10),
+		DeactivationEpoch:  strconv.FormatUint(d.DeactivationEpoch, 10),
+		Stake:              strconv.FormatUint(d.Stake, 10),
+		Voter:              d.Voter,
+		WarmupCooldownRate: d.WarmupCooldownRate,
+	})
+}
+
+// parseUint64JSON accepts a u64 value encoded either as a string or as a number.
+func parseUint64JSON(field string, raw json.RawMessage) (uint64, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return 0, &FieldError{Field: field, Err: ErrMissingField}
+	}
+	var s string
+	if raw[0] == '"' {
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return 0, &FieldError{Field: field, Value: string(raw), Err: ErrInvalidField}
+		}
+	} else {
+		s = string(raw)
+	}
+	return parseUint64Field(field, s)
+}
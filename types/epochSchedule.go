@@ -0,0 +1,88 @@
+package types
+
+import (
+	"math"
+	"math/bits"
+)
+
+// https://github.com/anza-xyz/agave/blob/v2.0.0/sdk/program/src/epoch_schedule.rs#L22
+const MinimumSlotsPerEpoch = 32
+
+// EpochScheduleAccount is the EpochSchedule sysvar with jsonParsed encoding.
+type EpochScheduleAccount struct {
+	Data struct {
+		Parsed struct {
+			Info EpochSchedule `json:"info"`
+			Type string        `json:"type"`
+		} `json:"parsed"`
+		Program string `json:"program"`
+		Space   uint64 `json:"space"`
+	} `json:"data"`
+
+	Executable bool   `json:"executable"`
+	Lamports   uint64 `json:"lamports"`
+	Owner      string `json:"owner"`
+	RentEpoch  uint64 `json:"rentEpoch"`
+}
+
+// EpochSchedule is the length of epochs, which double from MinimumSlotsPerEpoch up to SlotsPerEpoch during warmup.
+type EpochSchedule struct {
+	FirstNormalEpoch         uint64 `json:"firstNormalEpoch"`
+	FirstNormalSlot          uint64 `json:"firstNormalSlot"`
+	LeaderScheduleSlotOffset uint64 `json:"leaderScheduleSlotOffset"`
+	SlotsPerEpoch            uint64 `json:"slotsPerEpoch"`
+	Warmup                   bool   `json:"warmup"`
+}
+
+// GetSlotsInEpoch returns the number of slots in epoch.
+func (s *EpochSchedule) GetSlotsInEpoch(epoch uint64) uint64 {
+	if epoch < s.FirstNormalEpoch {
+		return saturatingPow2(epoch + uint64(bits.TrailingZeros64(MinimumSlotsPerEpoch)))
+	}
+	return s.SlotsPerEpoch
+}
+
+// GetFirstSlotInEpoch returns the first slot of epoch.
+func (s *EpochSchedule) GetFirstSlotInEpoch(epoch uint64) uint64 {
+	if epoch <= s.FirstNormalEpoch {
+		return saturatingMul(saturatingPow2(epoch)-1, MinimumSlotsPerEpoch)
+	}
+	return saturatingAdd(saturatingMul(epoch-s.FirstNormalEpoch, s.SlotsPerEpoch), s.FirstNormalSlot)
+}
+
+// GetEpochAndSlotIndex returns the epoch of slot and the index of slot in the epoch.
+func (s *EpochSchedule) GetEpochAndSlotIndex(slot uint64) (uint64, uint64) {
+	if slot < s.FirstNormalSlot {
+		// The smallest power of two above slot + MinimumSlotsPerEpoch
+		epoch := uint64(bits.Len64(slot+MinimumSlotsPerEpoch)) - uint64(bits.TrailingZeros64(MinimumSlotsPerEpoch)) - 1
+		epochLen := saturatingPow2(epoch + uint64(bits.TrailingZeros64(MinimumSlotsPerEpoch)))
+		return epoch, slot - (epochLen - MinimumSlotsPerEpoch)
+	}
+	if s.SlotsPerEpoch == 0 {
+		return s.FirstNormalEpoch, 0
+	}
+	normalSlotIndex := slot - s.FirstNormalSlot
+	return s.FirstNormalEpoch + normalSlotIndex/s.SlotsPerEpoch, normalSlotIndex % s.SlotsPerEpoch
+}
+
+func saturatingPow2(n uint64) uint64 {
+	if n >= 64 {
+		return math.MaxUint64
+	}
+	return 1 << n
+}
+
+func saturatingMul(x uint64, y uint64) uint64 {
+	hi, lo := bits.Mul64(x, y)
+	if hi != 0 {
+		return math.MaxUint64
+	}
+	return lo
+}
+
+func saturatingAdd(x uint64, y uint64) uint64 {
+	if z := x + y; z >= x {
+		return z
+	}
+	return math.MaxUint64
+}
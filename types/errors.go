@@ -0,0 +1,41 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrNotDelegated is returned by the delegation getters of a stake account which has no stake, e.g. an initialized account.
+	ErrNotDelegated = errors.New("stake account is not delegated")
+	// ErrNoMeta is returned by the Meta getters of an Uninitialized or RewardsPool stake account.
+	ErrNoMeta       = errors.New("stake account has no meta")
+	ErrMissingField = errors.New("missing field")
+	ErrInvalidField = errors.New("invalid field")
+
+	// ErrAccountNotFound, ErrNotStakeAccount and ErrNotStakeHistoryAccount are returned by the decoders.
+	ErrAccountNotFound = errors.New("account not found")
+	// ErrNotStakeAccount is returned for an account whose data is not a jsonParsed stake account.
+	ErrNotStakeAccount = errors.New("not a stake account")
+	// ErrNotStakeHistoryAccount is returned for an account which is not the jsonParsed StakeHistory sysvar.
+	ErrNotStakeHistoryAccount = errors.New("not a stake history account")
+)
+
+// FieldError is returned by the getters of StakeAccount when a field is missing or can't be parsed.
+type FieldError struct {
+	Field string
+	Value string
+	// Err is ErrMissingField or ErrInvalidField
+	Err error
+}
+
+func (e *FieldError) Error() string {
+	if e.Value == "" {
+		return fmt.Sprintf("%s: %v", e.Field, e.Err)
+	}
+	return fmt.Sprintf("%s: %v: %q", e.Field, e.Err, e.Value)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
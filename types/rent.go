@@ -0,0 +1,42 @@
+package types
+
+// https://github.com/anza-xyz/agave/blob/v2.0.0/sdk/program/src/rent.rs#L93
+const AccountStorageOverhead = 128
+
+// RentAccount is the Rent sysvar with jsonParsed encoding.
+type RentAccount struct {
+	Data struct {
+		Parsed struct {
+			Info RentAccountInfo `json:"info"`
+			Type string          `json:"type"`
+		} `json:"parsed"`
+		Program string `json:"program"`
+		Space   uint64 `json:"space"`
+	} `json:"data"`
+
+	Executable bool   `json:"executable"`
+	Lamports   uint64 `json:"lamports"`
+	Owner      string `json:"owner"`
+	RentEpoch  uint64 `json:"rentEpoch"`
+}
+
+type RentAccountInfo struct {
+	BurnPercent         uint8   `json:"burnPercent"`
+	ExemptionThreshold  float64 `json:"exemptionThreshold"`
+	LamportsPerByteYear string  `json:"lamportsPerByteYear"`
+}
+
+func (r *RentAccount) GetLamportsPerByteYear() (uint64, error) {
+	return parseUint64Field("LamportsPerByteYear", r.Data.Parsed.Info.LamportsPerByteYear)
+}
+
+// MinimumBalance returns the minimum balance of an account with dataLen bytes of data to be rent-exempt,
+// e.g. the rent-exempt reserve of a stake account for StakeStateV2Size.
+func (r *RentAccount) MinimumBalance(dataLen uint64) (uint64, error) {
+	lamportsPerByteYear, err := r.GetLamportsPerByteYear()
+	if err != nil {
+		return 0, err
+	}
+	bytes := AccountStorageOverhead + dataLen
+	return uint64(float64(bytes*lamportsPerByteYear) * r.Data.Parsed.Info.ExemptionThreshold), nil
+}
@@ -0,0 +1,38 @@
+package types
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Layout of the StakeHistory sysvar in the account data (bincode), a Vec of (Epoch, StakeHistoryEntry) from the newest epoch.
+// https://github.com/anza-xyz/agave/blob/v2.0.0/sdk/program/src/stake_history.rs
+const (
+	stakeHistoryLengthSize = 8
+	stakeHistoryEntrySize  = 32
+)
+
+// DecodeData decodes the binary data of the StakeHistory sysvar, as returned with base64 encoding, into r.Data.
+func (r *StakeHistoryAccount) DecodeData(data []byte) error {
+	if len(data) < stakeHistoryLengthSize {
+		return &FieldError{Field: "Data", Value: fmt.Sprintf("%d bytes", len(data)), Err: ErrInvalidField}
+	}
+	n := binary.LittleEndian.Uint64(data)
+	if n > uint64(len(data)-stakeHistoryLengthSize)/stakeHistoryEntrySize {
+		return &FieldError{Field: "Data", Value: fmt.Sprintf("%d entries in %d bytes", n, len(data)), Err: ErrInvalidField}
+	}
+
+	r.Data.Program = "sysvar"
+	r.Data.Space = len(data)
+	r.Data.Parsed.Type = "stakeHistory"
+	r.Data.Parsed.Info = make([]StakeHistoryAccountInfo, 0, n)
+	for i := uint64(0); i < n; i++ {
+		e := data[stakeHistoryLengthSize+i*stakeHistoryEntrySize:]
+		entry := StakeHistoryAccountInfo{Epoch: int(binary.LittleEndian.Uint64(e))}
+		entry.StakeHistory.Effective = binary.LittleEndian.Uint64(e[8:])
+		entry.StakeHistory.Activating = binary.LittleEndian.Uint64(e[16:])
+		entry.StakeHistory.Deactivating = binary.LittleEndian.Uint64(e[24:])
+		r.Data.Parsed.Info = append(r.Data.Parsed.Info, entry)
+	}
+	return nil
+}